@@ -0,0 +1,31 @@
+package core
+
+// abort is the value panicked by Abort, that Recover converts into a fail response without logging a stack trace.
+type abort struct {
+	err error
+}
+
+// Abort stops the serving of the request from any depth of the handler call stack, responding fail with err.
+// If err is nil, nothing more is responded, which is useful when the response has already been written.
+//
+// It panics, so the handlers stack must use Recover, as the default one does.
+//
+//	func loadUser(id string) *User {
+//		user, err := users.Find(id)
+//		if err != nil {
+//			core.Abort((&core.NotFoundError{}).New("user not found"))
+//		}
+//		return user
+//	}
+func Abort(err error) {
+	panic(abort{err: err})
+}
+
+// Must calls Abort with err if it isn't nil.
+//
+//	core.Must(db.Save(&user))
+func Must(err error) {
+	if err != nil {
+		Abort(err)
+	}
+}
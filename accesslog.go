@@ -0,0 +1,195 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// AccessLogEntry is the access log entry of a request.
+type AccessLogEntry struct {
+	Time      time.Time     `json:"time"`
+	RemoteIP  string        `json:"remoteIp"`
+	User      string        `json:"user,omitempty"` // The principal ID.
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int64         `json:"bytes"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"userAgent,omitempty"`
+	Latency   time.Duration `json:"latency"` // In nanoseconds in JSON.
+	RequestID string        `json:"requestId,omitempty"`
+	Handler   string        `json:"handler,omitempty"`   // The route handler, see Context.HandlerName.
+	Responder string        `json:"responder,omitempty"` // The handler writing the response, see Context.ResponderName.
+	Country   string        `json:"country,omitempty"`   // The country of the client, see Context.Geo.
+}
+
+// AccessLogFormatter formats an access log entry as a line, without the trailing newline.
+type AccessLogFormatter func(e *AccessLogEntry) []byte
+
+// CombinedLog formats the entries in the Apache Combined Log Format, followed by the latency in seconds and the request ID:
+//
+//	127.0.0.1 - bob [10/Oct/2000:13:55:36 -0700] "GET /users HTTP/1.1" 200 2326 "-" "curl/7.64.1" 0.012 "4bf92f35"
+func CombinedLog(e *AccessLogEntry) []byte {
+	b := make([]byte, 0, 256)
+	b = append(b, dash(e.RemoteIP)...)
+	b = append(b, " - "...)
+	b = append(b, dash(e.User)...)
+	b = append(b, " ["...)
+	b = e.Time.AppendFormat(b, "02/Jan/2006:15:04:05 -0700")
+	b = append(b, `] "`...)
+	b = append(b, e.Method+" "+e.URI+" "+e.Proto...)
+	b = append(b, `" `...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, ' ')
+	if e.Bytes > 0 {
+		b = strconv.AppendInt(b, e.Bytes, 10)
+	} else {
+		b = append(b, '-')
+	}
+	b = append(b, ' ')
+	b = strconv.AppendQuote(b, dash(e.Referer))
+	b = append(b, ' ')
+	b = strconv.AppendQuote(b, dash(e.UserAgent))
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, e.Latency.Seconds(), 'f', 3, 64)
+	b = append(b, ' ')
+	return strconv.AppendQuote(b, dash(e.RequestID))
+}
+
+// JSONLog formats the entries as JSON objects.
+func JSONLog(e *AccessLogEntry) []byte {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// LogfmtLog formats the entries as logfmt lines, with the latency in milliseconds:
+//
+//	time=2000-10-10T13:55:36-07:00 ip=127.0.0.1 method=GET uri=/users status=200 bytes=2326 latency_ms=12.3 request_id=4bf92f35
+func LogfmtLog(e *AccessLogEntry) []byte {
+	b := make([]byte, 0, 256)
+	b = append(b, "time="...)
+	b = e.Time.AppendFormat(b, time.RFC3339)
+	b = appendLogfmt(b, "ip", e.RemoteIP)
+	b = appendLogfmt(b, "user", e.User)
+	b = appendLogfmt(b, "method", e.Method)
+	b = appendLogfmt(b, "uri", e.URI)
+	b = appendLogfmt(b, "proto", e.Proto)
+	b = append(b, " status="...)
+	b = strconv.AppendInt(b, int64(e.Status), 10)
+	b = append(b, " bytes="...)
+	b = strconv.AppendInt(b, e.Bytes, 10)
+	b = appendLogfmt(b, "referer", e.Referer)
+	b = appendLogfmt(b, "user_agent", e.UserAgent)
+	b = append(b, " latency_ms="...)
+	b = strconv.AppendFloat(b, float64(e.Latency)/float64(time.Millisecond), 'f', 1, 64)
+	b = appendLogfmt(b, "request_id", e.RequestID)
+	b = appendLogfmt(b, "handler", e.Handler)
+	b = appendLogfmt(b, "responder", e.Responder)
+	return appendLogfmt(b, "country", e.Country)
+}
+
+// appendLogfmt appends the pair key=value to b, quoting the value if needed. Empty values are omitted.
+func appendLogfmt(b []byte, key, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = append(b, ' ')
+	b = append(b, key...)
+	b = append(b, '=')
+	if strings.ContainsAny(value, " \"=\\") || strconv.CanBackquote(value) == false {
+		return strconv.AppendQuote(b, value)
+	}
+	return append(b, value...)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// AccessLog is the middleware writing a line per request to Output, formatted by Format.
+//
+//	core.Use(core.NewAccessLog(os.Stdout, core.JSONLog).Handler)
+type AccessLog struct {
+	Output io.Writer
+	Format AccessLogFormatter
+
+	// SlowThreshold also logs the requests slower than it as warnings, with their handler and responder. Default is 0, disabled.
+	SlowThreshold time.Duration
+
+	mu sync.Mutex
+}
+
+// NewAccessLog returns a new AccessLog writing to w, default is os.Stdout, with format, default is CombinedLog.
+func NewAccessLog(w io.Writer, format AccessLogFormatter) *AccessLog {
+	if w == nil {
+		w = os.Stdout
+	}
+	if format == nil {
+		format = CombinedLog
+	}
+	return &AccessLog{Output: w, Format: format}
+}
+
+// Handler logs the request once the next handlers are done. A request whose handler panics is logged with a 500 status,
+// before the panic goes on to Recover.
+func (l *AccessLog) Handler(ctx *Context) {
+	start := time.Now()
+	panicked := true
+	defer func() {
+		l.log(ctx, start, panicked)
+	}()
+	ctx.Next()
+	panicked = false
+}
+
+// log writes the entry of the request started at start.
+func (l *AccessLog) log(ctx *Context, start time.Time, panicked bool) {
+	e := &AccessLogEntry{
+		Time:      start,
+		RemoteIP:  ctx.ClientIP(),
+		Method:    ctx.Request.Method,
+		URI:       ctx.Request.URL.RequestURI(),
+		Proto:     ctx.Request.Proto,
+		Status:    ctx.Status(),
+		Bytes:     ctx.Size(),
+		Referer:   ctx.Request.Referer(),
+		UserAgent: ctx.Request.UserAgent(),
+		Latency:   time.Since(start),
+		RequestID: ctx.Request.Header.Get("X-Request-Id"),
+		Handler:   ctx.HandlerName(),
+		Responder: ctx.ResponderName(),
+	}
+	if e.Status == 0 && panicked == true {
+		// Recover writes the response once the panic has unwound the handlers.
+		e.Status = http.StatusInternalServerError
+	}
+	if e.RequestID == "" {
+		e.RequestID = ctx.ResponseWriter.Header().Get("X-Request-Id")
+	}
+	if p := ctx.Principal(); p != nil {
+		e.User = p.ID
+	}
+	if loc := ctx.Geo(); loc != nil {
+		e.Country = loc.Country
+	}
+	if l.SlowThreshold > 0 && e.Latency > l.SlowThreshold {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path, "latency": e.Latency, "handler": e.Handler, "responder": e.Responder}).Warnln("AccessLog: slow request")
+	}
+	line := append(l.Format(e), '\n')
+	l.mu.Lock()
+	l.Output.Write(line)
+	l.mu.Unlock()
+}
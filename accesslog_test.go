@@ -0,0 +1,74 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogFormats(t *testing.T) {
+	e := &AccessLogEntry{
+		Time:      time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		RemoteIP:  "127.0.0.1",
+		User:      "bob",
+		Method:    "GET",
+		URI:       "/users?page=2",
+		Proto:     "HTTP/1.1",
+		Status:    200,
+		Bytes:     2326,
+		UserAgent: "curl/7.64.1",
+		Latency:   12300 * time.Microsecond,
+		RequestID: "4bf92f35",
+	}
+	tests := []struct {
+		name   string
+		format AccessLogFormatter
+		want   string
+	}{
+		{"combined", CombinedLog, `127.0.0.1 - bob [10/Oct/2000:13:55:36 -0700] "GET /users?page=2 HTTP/1.1" 200 2326 "-" "curl/7.64.1" 0.012 "4bf92f35"`},
+		{"logfmt", LogfmtLog, `time=2000-10-10T13:55:36-07:00 ip=127.0.0.1 user=bob method=GET uri="/users?page=2" proto=HTTP/1.1 status=200 bytes=2326 user_agent=curl/7.64.1 latency_ms=12.3 request_id=4bf92f35`},
+		{"json", JSONLog, `{"time":"2000-10-10T13:55:36-07:00","remoteIp":"127.0.0.1","user":"bob","method":"GET","uri":"/users?page=2","proto":"HTTP/1.1","status":200,"bytes":2326,"userAgent":"curl/7.64.1","latency":12300000,"requestId":"4bf92f35"}`},
+	}
+	for _, tt := range tests {
+		if got := string(tt.format(e)); got != tt.want {
+			t.Errorf("%s:\nwant %s\ngot  %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	hs := NewHandlersStack()
+	hs.Use(NewAccessLog(&out, LogfmtLog).Handler)
+	hs.Use(func(c *Context) { c.Text(http.StatusCreated, "done") })
+	r, _ := http.NewRequest("POST", "/orders", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("User-Agent", "go test")
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := out.String()
+	for _, want := range []string{" ip=10.0.0.1 ", " method=POST ", " status=201 bytes=4 ", ` user_agent="go test" `} {
+		if strings.Contains(line, want) == false {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	var out bytes.Buffer
+	hs := NewHandlersStack()
+	hs.Use(NewAccessLog(&out, LogfmtLog).Handler)
+	hs.Use(func(c *Context) { panic("boom") })
+	r, _ := http.NewRequest("GET", "/orders", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("want the 500 of Recover, got %d", w.Code)
+	}
+	if strings.Contains(out.String(), " uri=/orders ") == false || strings.Contains(out.String(), " status=500 ") == false {
+		t.Errorf("want the panic logged with status 500, got %q", out.String())
+	}
+}
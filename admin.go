@@ -0,0 +1,143 @@
+package core
+
+import (
+	"net/http"
+	"regexp"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AdminConfig are the extra values dumped by the admin config endpoint, with the framework configuration.
+// The values of the keys looking like secrets (password, secret, token, key) are redacted.
+var AdminConfig = map[string]interface{}{}
+
+// adminPath is the path the admin group is mounted on, not affected by the maintenance mode.
+var adminPath string
+
+// maintenance is 1 when the maintenance mode is on.
+var maintenance int32
+
+// secretKey matches the configuration keys whose values must be redacted.
+var secretKey = regexp.MustCompile(`(?i)passw|secret|token|key|credential`)
+
+// MountAdmin mounts the admin endpoints on the path of the router, protected by the guards handlers (at least one is required):
+//
+//	GET  /routes        the registered routes
+//	GET  /config        the configuration, redacted
+//	GET  /metrics       the Metrics, including the rate-limit counters and connection stats
+//	GET  /connections   the connection stats
+//	GET  /concurrency   the in-flight requests and dispatch wait times by route
+//	GET  /log-level     the log level
+//	PUT  /log-level     changes the log level, from the level parameter
+//	GET  /maintenance   the maintenance mode
+//	PUT  /maintenance   turns the maintenance mode on or off, from the on parameter
+func MountAdmin(router *Engine, path string, guards ...RouterHandler) *RouterGroup {
+	assert1(len(guards) > 0, "the admin endpoints must be protected by a guard handler")
+	admin := router.Group(path, guards...)
+	adminPath = admin.BasePath()
+
+	admin.GET("/routes", func(ctx *Context) {
+		ctx.Ok(router.Routes())
+	})
+	admin.GET("/config", func(ctx *Context) {
+		ctx.Ok(adminConfig())
+	})
+	admin.GET("/metrics", func(ctx *Context) {
+		ctx.ResponseWriter.Write([]byte(Metrics.String()))
+	})
+	admin.GET("/connections", func(ctx *Context) {
+		ctx.ResponseWriter.Write([]byte(connStats.String()))
+	})
+	admin.GET("/concurrency", func(ctx *Context) {
+		ctx.Ok(RoutesStats())
+	})
+	admin.GET("/log-level", func(ctx *Context) {
+		ctx.Ok(log.GetLevel().String())
+	})
+	admin.PUT("/log-level", func(ctx *Context) {
+		level, err := log.ParseLevel(ctx.Request.FormValue("level"))
+		if err != nil {
+			ctx.Fail((&ValidationError{}).New(err.Error()))
+			return
+		}
+		log.SetLevel(level)
+		if Log != nil {
+			Log.SetLevel(level)
+		}
+		log.Warnln("Admin: log level set to", level)
+		ctx.Ok(level.String())
+	})
+	admin.GET("/maintenance", func(ctx *Context) {
+		ctx.Ok(InMaintenance())
+	})
+	admin.PUT("/maintenance", func(ctx *Context) {
+		on := ctx.Request.FormValue("on")
+		if on != "true" && on != "false" {
+			ctx.Fail((&ValidationError{}).New("on must be true or false"))
+			return
+		}
+		SetMaintenance(on == "true")
+		log.Warnln("Admin: maintenance set to", on)
+		ctx.Ok(InMaintenance())
+	})
+	return admin
+}
+
+// SetMaintenance turns the maintenance mode on or off.
+func SetMaintenance(on bool) {
+	if on {
+		atomic.StoreInt32(&maintenance, 1)
+	} else {
+		atomic.StoreInt32(&maintenance, 0)
+	}
+}
+
+// InMaintenance tells if the maintenance mode is on.
+func InMaintenance() bool {
+	return atomic.LoadInt32(&maintenance) == 1
+}
+
+// Maintenance is a handler responding 503 Service Unavailable to all the requests but the admin ones when the maintenance mode is on.
+//
+//	core.Use(core.Maintenance)
+func Maintenance(ctx *Context) {
+	if InMaintenance() && (adminPath == "" || !pathHasPrefix(ctx.Request.URL.Path, adminPath)) {
+		err := (&ServerError{}).New("Service under maintenance")
+		err.Details = &ErrorDetails{RetryAfter: 60}
+		ctx.FailWithStatus(http.StatusServiceUnavailable, err)
+		return
+	}
+	ctx.Next()
+}
+
+// adminConfig returns the framework configuration and the AdminConfig, redacted.
+func adminConfig() map[string]interface{} {
+	config := map[string]interface{}{
+		"Address":         Address,
+		"Production":      Production,
+		"Timeout":         Timeout.String(),
+		"ListenLimit":     ListenLimit,
+		"ReadTimeout":     ReadTimeout.String(),
+		"WriteTimeout":    WriteTimeout.String(),
+		"IdleTimeout":     IdleTimeout.String(),
+		"MaxHeaderBytes":  MaxHeaderBytes,
+		"GracefulRestart": GracefulRestart,
+		"FailPath":        FailPath,
+	}
+	for k, v := range AdminConfig {
+		if secretKey.MatchString(k) {
+			v = "[redacted]"
+		}
+		config[k] = v
+	}
+	return config
+}
+
+// pathHasPrefix tells if path is prefix or under it.
+func pathHasPrefix(path, prefix string) bool {
+	if len(path) < len(prefix) || path[:len(prefix)] != prefix {
+		return false
+	}
+	return len(path) == len(prefix) || prefix[len(prefix)-1] == '/' || path[len(prefix)] == '/'
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMountAdmin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MountAdmin without guard: want a panic")
+		}
+	}()
+	MountAdmin(New(), "/admin")
+}
+
+func TestAdmin(t *testing.T) {
+	level := log.GetLevel()
+	defer func() {
+		adminPath = ""
+		SetMaintenance(false)
+		log.SetLevel(level)
+		delete(AdminConfig, "DatabasePassword")
+		delete(AdminConfig, "Region")
+	}()
+	AdminConfig["DatabasePassword"] = "hunter2"
+	AdminConfig["Region"] = "eu"
+
+	engine := New()
+	MountAdmin(engine, "/admin", func(c *Context) {
+		if c.Request.Header.Get("Authorization") != "Bearer admin" {
+			c.Fail((&UnauthorizedError{}).New("Unauthorized"))
+			return
+		}
+		c.Next()
+	})
+	engine.GET("/orders", func(c *Context) { c.Ok("orders") })
+	hs := NewHandlersStack()
+	hs.Use(Maintenance)
+	hs.Use(engine.Handler())
+	serve := func(method, target string, admin bool) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(method, target, nil)
+		if admin == true {
+			r.Header.Set("Authorization", "Bearer admin")
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("GET", "/admin/config", false); w.Code != http.StatusUnauthorized {
+		t.Errorf("unguarded: want 401, got %d", w.Code)
+	}
+	w := serve("GET", "/admin/config", true)
+	if body := w.Body.String(); strings.Contains(body, "hunter2") == true || strings.Contains(body, `"DatabasePassword":"[redacted]"`) == false ||
+		strings.Contains(body, `"Region":"eu"`) == false {
+		t.Errorf("config: want the password redacted, got %s", body)
+	}
+	if w := serve("GET", "/admin/routes", true); strings.Contains(w.Body.String(), "/orders") == false {
+		t.Errorf("routes: got %s", w.Body.String())
+	}
+
+	if w := serve("PUT", "/admin/log-level?level=debug", true); w.Code != http.StatusOK || log.GetLevel() != log.DebugLevel {
+		t.Errorf("log level: want debug, got %d %s", w.Code, log.GetLevel())
+	}
+	if w := serve("PUT", "/admin/log-level?level=loud", true); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid log level: want 400, got %d", w.Code)
+	}
+
+	if w := serve("PUT", "/admin/maintenance?on=true", true); w.Code != http.StatusOK || InMaintenance() == false {
+		t.Errorf("maintenance on: got %d %t", w.Code, InMaintenance())
+	}
+	if w := serve("GET", "/orders", false); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("in maintenance: want 503, got %d %v", w.Code, w.Header())
+	}
+	if w := serve("GET", "/admin/maintenance", true); w.Code != http.StatusOK {
+		t.Errorf("admin in maintenance: want 200, got %d", w.Code)
+	}
+	if w := serve("PUT", "/admin/maintenance?on=yes", true); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid maintenance: want 400, got %d", w.Code)
+	}
+	serve("PUT", "/admin/maintenance?on=false", true)
+	if w := serve("GET", "/orders", false); w.Code != http.StatusOK {
+		t.Errorf("after maintenance: want 200, got %d", w.Code)
+	}
+}
+
+func TestPathHasPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/admin", "/admin", true},
+		{"/admin/config", "/admin", true},
+		{"/administrator", "/admin", false},
+		{"/admin/config", "/admin/", true},
+		{"/ad", "/admin", false},
+	}
+	for _, tt := range tests {
+		if got := pathHasPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("%s %s: want %t, got %t", tt.path, tt.prefix, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// The errors of the API key validation.
+var (
+	ErrAPIKeyInvalid = errors.New("invalid API key")
+	ErrAPIKeyExpired = errors.New("expired API key")
+)
+
+// APIKey is an issued API key. Only the hash of its secret is stored.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`
+	Principal string    `json:"principal"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"` // Zero if the key doesn't expire.
+}
+
+// APIKeyStore stores the API keys.
+type APIKeyStore interface {
+	Save(key *APIKey) error
+	Get(id string) (*APIKey, error) // Returns nil and no error if the key doesn't exist.
+	Delete(id string) error
+}
+
+// APIKeys issues and validates API keys of the form <prefix>_<id>_<secret>.
+//
+//	keys := core.NewAPIKeys("sk", store)
+//	plain, key, err := keys.Issue("user-42", []string{"orders:read"}, 90*24*time.Hour)
+//	// plain is shown once to the user.
+//	api := core.Routers.Group("/api", keys.Handler)
+type APIKeys struct {
+	Prefix   string
+	Store    APIKeyStore
+	Header   string // The header of the key, default is X-API-Key. An Authorization Bearer key is also accepted.
+	Optional bool   // Lets the requests without a key through, without a principal.
+}
+
+// NewAPIKeys returns a new APIKeys issuing the keys with prefix, stored in store.
+func NewAPIKeys(prefix string, store APIKeyStore) *APIKeys {
+	return &APIKeys{Prefix: prefix, Store: store, Header: "X-API-Key"}
+}
+
+// Issue issues a new API key of principal with scopes, expiring after ttl if it isn't 0.
+// It returns the plain key to give to the client, which can't be retrieved later.
+func (k *APIKeys) Issue(principal string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	plainSecret := base64.RawURLEncoding.EncodeToString(secret)
+	key := &APIKey{
+		ID:        hex.EncodeToString(id),
+		Hash:      hashAPIKeySecret(plainSecret),
+		Principal: principal,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(ttl)
+	}
+	if err := k.Store.Save(key); err != nil {
+		return "", nil, err
+	}
+	return k.Prefix + "_" + key.ID + "_" + plainSecret, key, nil
+}
+
+// Validate returns the API key of plain, or ErrAPIKeyInvalid or ErrAPIKeyExpired.
+func (k *APIKeys) Validate(plain string) (*APIKey, error) {
+	if strings.HasPrefix(plain, k.Prefix+"_") == false {
+		return nil, ErrAPIKeyInvalid
+	}
+	parts := strings.SplitN(plain[len(k.Prefix)+1:], "_", 2)
+	if len(parts) != 2 {
+		return nil, ErrAPIKeyInvalid
+	}
+	key, err := k.Store.Get(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKeySecret(parts[1]))) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+	if key.ExpiresAt.IsZero() == false && time.Now().After(key.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// Revoke revokes the API key id.
+func (k *APIKeys) Revoke(id string) error {
+	return k.Store.Delete(id)
+}
+
+// Handler is the middleware resolving the API key of the request into its principal, with ctx.Principal.
+// The requests without a valid key fail with 401 Unauthorized, unless Optional is set and they have no key.
+func (k *APIKeys) Handler(ctx *Context) {
+	plain := ctx.Request.Header.Get(k.Header)
+	if auth := ctx.Request.Header.Get("Authorization"); plain == "" && strings.HasPrefix(auth, "Bearer "+k.Prefix+"_") {
+		plain = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if plain == "" {
+		if k.Optional == true {
+			ctx.Next()
+			return
+		}
+		ctx.Fail((&UnauthorizedError{}).New("API key required"))
+		return
+	}
+	key, err := k.Validate(plain)
+	if err == ErrAPIKeyInvalid || err == ErrAPIKeyExpired {
+		ctx.Fail((&UnauthorizedError{}).New(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.SetPrincipal(&Principal{ID: key.Principal, Scopes: key.Scopes, Claims: map[string]interface{}{"apiKey": key.ID}})
+	ctx.Next()
+}
+
+// hashAPIKeySecret returns the stored hash of an API key secret.
+// As the secrets are random, a plain SHA-256 is enough.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryAPIKeyStore is an APIKeyStore in memory, for the tests and the single instance deployments.
+type MemoryAPIKeyStore struct {
+	keys *Cache
+}
+
+// NewMemoryAPIKeyStore returns a new MemoryAPIKeyStore.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: NewCache("", 0, 0)}
+}
+
+// Save saves key.
+func (s *MemoryAPIKeyStore) Save(key *APIKey) error {
+	s.keys.Set(key.ID, key)
+	return nil
+}
+
+// Get returns the key id, or nil.
+func (s *MemoryAPIKeyStore) Get(id string) (*APIKey, error) {
+	if v, ok := s.keys.Get(id); ok == true {
+		return v.(*APIKey), nil
+	}
+	return nil, nil
+}
+
+// Delete deletes the key id.
+func (s *MemoryAPIKeyStore) Delete(id string) error {
+	s.keys.Delete(id)
+	return nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	keys := NewAPIKeys("sk", NewMemoryAPIKeyStore())
+	plain, key, err := keys.Issue("user-42", []string{"orders:read"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, _ := keys.Issue("user-42", nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	var principal *Principal
+	hs := NewHandlersStack()
+	hs.Use(keys.Handler)
+	hs.Use(func(c *Context) {
+		principal = c.Principal()
+		c.Ok(nil)
+	})
+	tests := []struct {
+		header, value string
+		code          int
+	}{
+		{"X-API-Key", plain, http.StatusOK},
+		{"Authorization", "Bearer " + plain, http.StatusOK},
+		{"X-API-Key", plain + "x", http.StatusUnauthorized},
+		{"X-API-Key", expired, http.StatusUnauthorized},
+		{"X-API-Key", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		principal = nil
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s %q: want %d, got %d", tt.header, tt.value, tt.code, w.Code)
+		}
+		if tt.code == http.StatusOK && (principal.ID != "user-42" || principal.HasScope("orders:read") == false) {
+			t.Errorf("%s: principal: got %+v", tt.header, principal)
+		}
+	}
+
+	keys.Revoke(key.ID)
+	if _, err := keys.Validate(plain); err != ErrAPIKeyInvalid {
+		t.Errorf("revoked: want ErrAPIKeyInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AssetMaxAge is the cache lifetime of the favicon and robots.txt responses.
+var AssetMaxAge = 7 * 24 * time.Hour
+
+// Favicon serves icon at /favicon.ico, so that the browsers hits don't end with a 404 in the access logs.
+// Its content type is detected, as image/x-icon or image/png.
+func (group *RouterGroup) Favicon(icon []byte) *Route {
+	return group.GET("/favicon.ico", assetHandler(http.DetectContentType(icon), icon))
+}
+
+// FaviconFS serves the file name of fsys at /favicon.ico. It panics if the file can't be read.
+//
+//	core.Routers.FaviconFS(http.Dir("public"), "favicon.ico")
+func (group *RouterGroup) FaviconFS(fsys http.FileSystem, name string) *Route {
+	f, err := fsys.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	icon, err := ioutil.ReadAll(f)
+	if err != nil {
+		panic(err)
+	}
+	return group.Favicon(icon)
+}
+
+// Robots serves content at /robots.txt.
+//
+//	core.Routers.Robots("User-agent: *\nDisallow: /api/\n")
+func (group *RouterGroup) Robots(content string) *Route {
+	return group.GET("/robots.txt", assetHandler("text/plain; charset=utf-8", []byte(content)))
+}
+
+// assetHandler responds with the static content b of the type contentType, cached for AssetMaxAge.
+func assetHandler(contentType string, b []byte) RouterHandler {
+	return func(ctx *Context) {
+		h := ctx.ResponseWriter.Header()
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(AssetMaxAge/time.Second)))
+		ctx.Blob(contentType, b)
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFaviconRobots(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	ico := []byte("\x00\x00\x01\x00\x01\x00\x10\x10")
+	fsys := http.FS(fstest.MapFS{"public/favicon.ico": {Data: ico}})
+	maxAge := "public, max-age=" + strconv.Itoa(int(AssetMaxAge/time.Second))
+
+	tests := []struct {
+		register    func(engine *Engine)
+		path        string
+		contentType string
+		body        []byte
+	}{
+		{func(e *Engine) { e.Favicon(png) }, "/favicon.ico", "image/png", png},
+		{func(e *Engine) { e.FaviconFS(fsys, "public/favicon.ico") }, "/favicon.ico", "image/x-icon", ico},
+		{func(e *Engine) { e.Robots("User-agent: *\nDisallow: /api/\n") }, "/robots.txt", "text/plain; charset=utf-8", []byte("User-agent: *\nDisallow: /api/\n")},
+	}
+	for _, tt := range tests {
+		engine := New()
+		tt.register(engine)
+		w := serveRouter(engine, "GET", tt.path)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != string(tt.body) {
+			t.Errorf("%s: want 200 %s %q, got %d %s %q", tt.path, tt.contentType, tt.body, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != maxAge {
+			t.Errorf("%s: want Cache-Control %q, got %q", tt.path, maxAge, got)
+		}
+	}
+
+	// A missing file panics on registration, the route isn't added.
+	engine := New()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("missing file: want a panic")
+			}
+		}()
+		engine.FaviconFS(fsys, "favicon.ico")
+	}()
+	if w := serveRouter(engine, "GET", "/favicon.ico"); w.Code != http.StatusNotFound {
+		t.Errorf("missing file: want 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,161 @@
+// Package auth implements the password hashing of package core: argon2id of golang.org/x/crypto with the OWASP defaults,
+// in the PHC string format, upgraded on verify when the parameters change or when the hash is of a legacy algorithm like bcrypt,
+// and the constant-time comparisons of the credentials.
+//
+//	hash, err := auth.Hash(password)
+//	...
+//	upgraded, err := auth.Verify(user.PasswordHash, password)
+//	if err != nil {
+//		ctx.Fail((&core.UnauthorizedError{}).New("invalid credentials"))
+//		return
+//	}
+//	if upgraded != "" {
+//		saveHash(user, upgraded)
+//	}
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrMismatch is the error of Verify when the password doesn't match the hash.
+	ErrMismatch = errors.New("auth: password mismatch")
+	// ErrInvalidHash is the error of Verify when the hash isn't an argon2id hash nor one of a Legacy algorithm.
+	ErrInvalidHash = errors.New("auth: invalid hash")
+)
+
+// The limits of the parameters of the hashes to verify, so that a tampered hash can't exhaust the server.
+const (
+	maxTime    = 16
+	maxMemory  = 256 * 1024 // 256 MiB.
+	maxThreads = 16
+	maxKeyLen  = 128
+)
+
+// Params are the parameters of argon2id.
+type Params struct {
+	Time    uint32 // The passes over the memory.
+	Memory  uint32 // The memory in KiB.
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// Hasher hashes and verifies the passwords with argon2id.
+type Hasher struct {
+	Params Params
+	// Legacy verifies the hashes of the other algorithms by prefix, so that they are upgraded to argon2id on verify.
+	// The verifiers return nil if password matches hash, like bcrypt.CompareHashAndPassword, the default of the bcrypt hashes:
+	//
+	//	auth.DefaultHasher.Legacy["$sha256$"] = verifySHA256
+	Legacy map[string]func(hash, password []byte) error
+}
+
+// NewHasher returns a new Hasher with the OWASP parameters: 19 MiB, 2 passes and 1 thread, upgrading the bcrypt hashes.
+func NewHasher() *Hasher {
+	return &Hasher{
+		Params: Params{Time: 2, Memory: 19 * 1024, Threads: 1, SaltLen: 16, KeyLen: 32},
+		Legacy: map[string]func(hash, password []byte) error{
+			"$2a$": bcrypt.CompareHashAndPassword,
+			"$2b$": bcrypt.CompareHashAndPassword,
+			"$2y$": bcrypt.CompareHashAndPassword,
+		},
+	}
+}
+
+// DefaultHasher is the Hasher of Hash and Verify.
+var DefaultHasher = NewHasher()
+
+// Hash returns the hash of password with DefaultHasher.
+func Hash(password string) (string, error) {
+	return DefaultHasher.Hash(password)
+}
+
+// Verify verifies password against hash with DefaultHasher, see Hasher.Verify.
+func Verify(hash, password string) (string, error) {
+	return DefaultHasher.Verify(hash, password)
+}
+
+// Hash returns the argon2id hash of password with a random salt, like "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>".
+func (h *Hasher) Hash(password string) (string, error) {
+	p := h.Params
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify verifies password against hash in constant time. It returns ErrMismatch if it doesn't match.
+// If it matches but hash is of a Legacy algorithm or of other Params, it returns the new hash of password
+// to store in place of hash, otherwise "".
+// An empty hash, of an unknown user, takes the time of a verification and returns ErrMismatch,
+// so that the response time doesn't reveal whether the user exists.
+func (h *Hasher) Verify(hash, password string) (string, error) {
+	if hash == "" {
+		h.Hash(password)
+		return "", ErrMismatch
+	}
+	if strings.HasPrefix(hash, "$argon2id$") == false {
+		for prefix, verify := range h.Legacy {
+			if strings.HasPrefix(hash, prefix) == true {
+				if verify([]byte(hash), []byte(password)) != nil {
+					return "", ErrMismatch
+				}
+				return h.Hash(password)
+			}
+		}
+		return "", ErrInvalidHash
+	}
+	p, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return "", err
+	}
+	derived := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	if subtle.ConstantTimeCompare(derived, key) != 1 {
+		return "", ErrMismatch
+	}
+	if p != h.Params {
+		return h.Hash(password)
+	}
+	return "", nil
+}
+
+// decodeHash returns the parameters, the salt and the key of an argon2id hash, within the limits of the parameters.
+func decodeHash(hash string) (Params, []byte, []byte, error) {
+	var p Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil ||
+		p.Time < 1 || p.Time > maxTime || p.Memory > maxMemory || p.Threads < 1 || p.Threads > maxThreads {
+		return p, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > maxKeyLen {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLen, p.KeyLen = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
+
+// Equal tells if the credentials a and b are equal, in a constant time, like an API key and its expected value.
+func Equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDecodeHashLimits(t *testing.T) {
+	key := "$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	for _, params := range []string{"m=64,t=2,p=1", "m=64,t=1,p=16"} {
+		if _, _, _, err := decodeHash("$argon2id$v=19$" + params + key); err != nil {
+			t.Errorf("%s: want a valid hash, got %v", params, err)
+		}
+	}
+	for _, params := range []string{"m=4194304,t=2,p=1", "m=64,t=1000,p=1", "m=64,t=2,p=255", "m=64,t=0,p=1"} {
+		if _, _, _, err := decodeHash("$argon2id$v=19$" + params + key); errors.Is(err, ErrInvalidHash) == false {
+			t.Errorf("%s: want ErrInvalidHash, got %v", params, err)
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	h := NewHasher()
+	h.Params.Memory = 64
+	hash, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=2,p=1$") == false {
+		t.Errorf("hash: got %s", hash)
+	}
+	if upgraded, err := h.Verify(hash, "correct horse"); err != nil || upgraded != "" {
+		t.Errorf("verify: want no upgrade, got %q, %v", upgraded, err)
+	}
+	if _, err := h.Verify(hash, "wrong horse"); errors.Is(err, ErrMismatch) == false {
+		t.Errorf("wrong password: want ErrMismatch, got %v", err)
+	}
+	if _, err := h.Verify("", "correct horse"); errors.Is(err, ErrMismatch) == false {
+		t.Errorf("unknown user: want ErrMismatch, got %v", err)
+	}
+	if _, err := h.Verify("$argon2id$v=19$m=64$bad", "correct horse"); errors.Is(err, ErrInvalidHash) == false {
+		t.Errorf("invalid hash: want ErrInvalidHash, got %v", err)
+	}
+
+	h.Params.Time = 3
+	upgraded, err := h.Verify(hash, "correct horse")
+	if err != nil || strings.HasPrefix(upgraded, "$argon2id$v=19$m=64,t=3,p=1$") == false {
+		t.Errorf("new params: want an upgrade, got %q, %v", upgraded, err)
+	}
+
+	h.Legacy["$plain$"] = func(hash, password []byte) error {
+		if bytes.Equal(hash[len("$plain$"):], password) == false {
+			return errors.New("mismatch")
+		}
+		return nil
+	}
+	if upgraded, err := h.Verify("$plain$secret", "secret"); err != nil || strings.HasPrefix(upgraded, "$argon2id$") == false {
+		t.Errorf("legacy: want an upgrade, got %q, %v", upgraded, err)
+	}
+	if _, err := h.Verify("$plain$secret", "other"); errors.Is(err, ErrMismatch) == false {
+		t.Errorf("legacy mismatch: want ErrMismatch, got %v", err)
+	}
+	if _, err := h.Verify("$scrypt$ln=15,r=8,p=1$unknown", "secret"); errors.Is(err, ErrInvalidHash) == false {
+		t.Errorf("unknown algorithm: want ErrInvalidHash, got %v", err)
+	}
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if upgraded, err := h.Verify(string(legacy), "secret"); err != nil || strings.HasPrefix(upgraded, "$argon2id$") == false {
+		t.Errorf("bcrypt: want an upgrade, got %q, %v", upgraded, err)
+	}
+	if _, err := h.Verify(string(legacy), "other"); errors.Is(err, ErrMismatch) == false {
+		t.Errorf("bcrypt mismatch: want ErrMismatch, got %v", err)
+	}
+
+	if Equal("sk_live_1", "sk_live_1") == false || Equal("sk_live_1", "sk_live_2") == true {
+		t.Error("Equal")
+	}
+}
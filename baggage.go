@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// BaggageKeys is the allowlist of the W3C baggage entries of the inbound requests, e.g. "tenant.id" or "user.id",
+// propagated to the outbound requests by HTTPClient.DoContext. Default is nil, the inbound baggage isn't propagated.
+// The entries set with ctx.SetBaggage are always propagated.
+var BaggageKeys []string
+
+// Baggage returns the baggage of the request: the allowed entries of its baggage header and the ones set with SetBaggage.
+func (ctx *Context) Baggage() map[string]string {
+	if b, ok := ctx.Data["baggage"].(map[string]string); ok == true {
+		return b
+	}
+	b := make(map[string]string)
+	if len(BaggageKeys) > 0 {
+		inbound := parseBaggage(ctx.Request.Header.Get("Baggage"))
+		for _, k := range BaggageKeys {
+			if v, ok := inbound[k]; ok == true {
+				b[k] = v
+			}
+		}
+	}
+	ctx.Data["baggage"] = b
+	return b
+}
+
+// SetBaggage sets the baggage entry key, propagated to the outbound requests.
+func (ctx *Context) SetBaggage(key, value string) {
+	ctx.Baggage()[key] = value
+}
+
+// propagateBaggage adds the baggage of ctx to the outbound request req, the entries of req first.
+func (ctx *Context) propagateBaggage(req *http.Request) {
+	b := ctx.Baggage()
+	if len(b) == 0 {
+		return
+	}
+	header := req.Header.Get("Baggage")
+	own := parseBaggage(header)
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		if _, ok := own[k]; ok == false {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	members := make([]string, 0, len(keys)+1)
+	if header != "" {
+		members = append(members, header)
+	}
+	for _, k := range keys {
+		members = append(members, k+"="+url.PathEscape(b[k]))
+	}
+	req.Header.Set("Baggage", strings.Join(members, ","))
+}
+
+// parseBaggage parses a W3C baggage header, without the entries properties.
+func parseBaggage(s string) map[string]string {
+	b := make(map[string]string)
+	for _, member := range strings.Split(s, ",") {
+		if i := strings.Index(member, ";"); i >= 0 {
+			member = member[:i]
+		}
+		i := strings.Index(member, "=")
+		if i <= 0 {
+			continue
+		}
+		k := strings.TrimSpace(member[:i])
+		v, err := url.PathUnescape(strings.TrimSpace(member[i+1:]))
+		if k == "" || err != nil {
+			continue
+		}
+		b[k] = v
+	}
+	return b
+}
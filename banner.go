@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var (
+	// Banner is the format of the startup banner printed by Run when not in Production: "text", "json" for the tools, or "off".
+	Banner = "text"
+
+	// BannerOutput is the output of the startup banner, default is os.Stdout.
+	BannerOutput io.Writer = os.Stdout
+)
+
+// bannerInfo is the content of the startup banner.
+type bannerInfo struct {
+	Address    string      `json:"address"`
+	Pid        int         `json:"pid"`
+	Features   []string    `json:"features"`
+	Plugins    []string    `json:"plugins,omitempty"`
+	Middleware []string    `json:"middleware"`
+	Routes     []RouteInfo `json:"routes"`
+}
+
+// newBannerInfo returns the banner of the server of hs and engine.
+func newBannerInfo(hs *HandlersStack, engine *Engine) *bannerInfo {
+	b := &bannerInfo{
+		Address:    Address,
+		Pid:        os.Getpid(),
+		Middleware: handlerNames(hs.Handlers),
+		Routes:     engine.Routes(),
+	}
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"tls", TLSCertFile != "" || TLSConfig != nil},
+		{"mtls", ClientCAFile != ""},
+		{"graceful-restart", GracefulRestart},
+		{"trust-proxy", TrustProxy},
+		{"auto-head", engine.AutoHEAD},
+		{"method-not-allowed", engine.HandleMethodNotAllowed},
+		{"strict-writes", StrictWrites},
+		{"disable-pool", DisablePool},
+		{"leak-check", LeakCheck},
+	}
+	for _, f := range features {
+		if f.enabled == true {
+			b.Features = append(b.Features, f.name)
+		}
+	}
+	for _, p := range hs.plugins {
+		b.Plugins = append(b.Plugins, p.Name())
+	}
+	return b
+}
+
+// printBanner prints the banner b to w in format.
+func printBanner(w io.Writer, format string, b *bannerInfo) error {
+	switch format {
+	case "off", "":
+		return nil
+	case "json":
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+		enc := json.NewEncoder(w)
+		return enc.Encode(b)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Listening\t%s (pid %d)\n", b.Address, b.Pid)
+	fmt.Fprintf(tw, "Features\t%s\n", strings.Join(b.Features, ", "))
+	if len(b.Plugins) > 0 {
+		fmt.Fprintf(tw, "Plugins\t%s\n", strings.Join(b.Plugins, ", "))
+	}
+	for i, m := range b.Middleware {
+		label := ""
+		if i == 0 {
+			label = "Middleware"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", label, m)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tSCOPES")
+	for _, r := range b.Routes {
+		handler := ""
+		if len(r.HandlerNames) > 0 {
+			handler = r.HandlerNames[len(r.HandlerNames)-1]
+		}
+		path := r.Path
+		if r.Deprecated == true {
+			path += " (deprecated)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Method, path, handler, strings.Join(r.Scopes, " "))
+	}
+	return tw.Flush()
+}
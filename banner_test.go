@@ -0,0 +1,36 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBanner(t *testing.T) {
+	engine := New()
+	engine.GET("/users", listUsers).Options(RouteOptions{Scopes: []string{"users:read"}})
+	hs := NewHandlersStack()
+	hs.Use(NewAccessLog(nil, nil).Handler)
+	b := newBannerInfo(hs, engine)
+
+	var text bytes.Buffer
+	if err := printBanner(&text, "text", b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Features    auto-head\n", "Middleware  github.com/HiLittleCat/core.(*AccessLog).Handler\n",
+		"GET     /users  github.com/HiLittleCat/core.listUsers  users:read\n"} {
+		if strings.Contains(text.String(), want) == false {
+			t.Errorf("text: want %q in\n%s", want, text.String())
+		}
+	}
+
+	var json bytes.Buffer
+	printBanner(&json, "json", b)
+	if strings.Contains(json.String(), `"features":["auto-head"]`) == false {
+		t.Errorf("json: got %s", json.String())
+	}
+	var off bytes.Buffer
+	if printBanner(&off, "off", b); off.Len() != 0 {
+		t.Errorf("off: got %s", off.String())
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// BatchMaxRequests is the maximum number of sub-requests of a batch request.
+var BatchMaxRequests = 20
+
+// BatchSharedHeaders are the headers of the batch request given to the sub-requests, so that they share its authentication.
+var BatchSharedHeaders = []string{"Authorization", "Cookie", "X-Request-Id", "Accept-Language"}
+
+// BatchRequest is a sub-request of a batch request.
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the response of a sub-request.
+type BatchResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Batch returns a handler serving a batch request: its body is an array of BatchRequest,
+// each one is served in order by hs (the default handlers stack if nil), and the success response data is the array of BatchResponse.
+//
+//	core.Routers.POST("/batch", core.Batch(nil))
+//
+// The sub-requests get the BatchSharedHeaders of the batch request, and can't be batch requests themselves.
+func Batch(hs *HandlersStack) RouterHandler {
+	if hs == nil {
+		hs = defaultHandlersStack
+	}
+	return func(ctx *Context) {
+		if ctx.Request.Header.Get("X-Batch-Request") != "" {
+			ctx.Fail((&ValidationError{}).New("batch requests can't be nested"))
+			return
+		}
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+		var reqs []BatchRequest
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&reqs); err != nil {
+			ctx.Fail((&ValidationError{}).New("invalid batch: " + err.Error()))
+			return
+		}
+		if len(reqs) > BatchMaxRequests {
+			ctx.Fail((&ValidationError{}).New("too many requests in the batch"))
+			return
+		}
+
+		responses := make([]BatchResponse, len(reqs))
+		for i, br := range reqs {
+			responses[i] = serveBatchRequest(ctx, hs, br)
+		}
+		ctx.Ok(responses)
+	}
+}
+
+// serveBatchRequest serves a sub-request with hs.
+func serveBatchRequest(ctx *Context, hs *HandlersStack, br BatchRequest) BatchResponse {
+	method := strings.ToUpper(br.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !strings.HasPrefix(br.Path, "/") {
+		return BatchResponse{Status: http.StatusBadRequest, Body: json.RawMessage(`"invalid path"`)}
+	}
+	r, err := http.NewRequest(method, br.Path, bytes.NewReader(br.Body))
+	if err != nil {
+		return BatchResponse{Status: http.StatusBadRequest, Body: json.RawMessage(`"invalid request"`)}
+	}
+	r = r.WithContext(ctx.Request.Context())
+	r.RemoteAddr = ctx.Request.RemoteAddr
+	r.Host = ctx.Request.Host
+	for _, k := range BatchSharedHeaders {
+		if v := ctx.Request.Header.Get(k); v != "" {
+			r.Header.Set(k, v)
+		}
+	}
+	for k, v := range br.Headers {
+		r.Header.Set(k, v)
+	}
+	if len(br.Body) > 0 && r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("X-Batch-Request", "1")
+
+	w := &batchWriter{header: make(http.Header)}
+	hs.ServeHTTP(w, r)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	res := BatchResponse{Status: w.status}
+	if ct := w.header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+		res.Headers = map[string]string{"Content-Type": ct}
+	}
+	body := w.body.Bytes()
+	if len(body) > 0 {
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+		if json.Valid(body) {
+			res.Body = body
+		} else {
+			res.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return res
+}
+
+// batchWriter records the response of a sub-request.
+type batchWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchWriter) Header() http.Header { return w.header }
+
+func (w *batchWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+func (w *batchWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
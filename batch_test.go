@@ -0,0 +1,83 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	hs := NewHandlersStack()
+	engine := New()
+	engine.POST("/batch", Batch(hs))
+	engine.GET("/users/:id", func(c *Context) {
+		c.Ok(map[string]string{"id": c.Params.ByName("id"), "auth": c.Request.Header.Get("Authorization")})
+	})
+	engine.POST("/echo", func(c *Context) {
+		var v map[string]interface{}
+		json.NewDecoder(c.Request.Body).Decode(&v)
+		c.Ok(v)
+	})
+	engine.GET("/text", func(c *Context) { c.Text(http.StatusAccepted, "plain") })
+	hs.Use(engine.Handler())
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/batch", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer t")
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(`[{"path":"/users/1"},{"method":"post","path":"/echo","body":{"a":1}},{"path":"/text"},{"path":"users"},{"method":"POST","path":"/batch","body":[]}]`)
+	var res struct{ Data []BatchResponse }
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK || len(res.Data) != 5 {
+		t.Fatalf("want 5 responses, got %d %s", w.Code, w.Body.String())
+	}
+	tests := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `"auth":"Bearer t"`},
+		{http.StatusOK, `"a":1`},
+		{http.StatusAccepted, `"plain"`},
+		{http.StatusBadRequest, `"invalid path"`},
+		{http.StatusBadRequest, `can't be nested`},
+	}
+	for i, tt := range tests {
+		if res.Data[i].Status != tt.status || strings.Contains(string(res.Data[i].Body), tt.body) == false {
+			t.Errorf("request %d: want %d with %s, got %d %s", i, tt.status, tt.body, res.Data[i].Status, res.Data[i].Body)
+		}
+	}
+	if ct := res.Data[2].Headers["Content-Type"]; strings.HasPrefix(ct, "text/plain") == false {
+		t.Errorf("text: want its Content-Type, got %q", ct)
+	}
+
+	if w := serve(`{"path":"/users/1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid batch: want 400, got %d", w.Code)
+	}
+	if w := serve("[" + strings.Repeat(`{"path":"/text"},`, BatchMaxRequests) + `{"path":"/text"}]`); w.Code != http.StatusBadRequest {
+		t.Errorf("too many requests: want 400, got %d", w.Code)
+	}
+}
+
+func TestBatchDefaultStack(t *testing.T) {
+	// The default handlers stack is resolved once: the concurrent batches don't race on it.
+	h := Batch(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hs := NewHandlersStack()
+			hs.Use(h)
+			r := httptest.NewRequest("POST", "/batch", strings.NewReader("[]"))
+			r.Header.Set("X-Batch-Request", "1")
+			hs.ServeHTTP(httptest.NewRecorder(), r)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,149 @@
+package benchmarks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/HiLittleCat/core"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// The framework warnings would be measured, and flood the output.
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+// discardWriter is a response writer that doesn't allocate, so that only the framework is measured.
+type discardWriter struct {
+	header http.Header
+}
+
+func newDiscardWriter() *discardWriter {
+	return &discardWriter{header: make(http.Header)}
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func serve(b *testing.B, hs *core.HandlersStack, r *http.Request) {
+	w := newDiscardWriter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hs.ServeHTTP(w, r)
+	}
+}
+
+func routerStack() *core.HandlersStack {
+	router := core.New()
+	router.GET("/", func(c *core.Context) { c.ResStatus(http.StatusOK) })
+	router.GET("/users/:id", func(c *core.Context) { c.ResStatus(http.StatusOK) })
+	router.GET("/users/:id/posts/:post", func(c *core.Context) { c.ResStatus(http.StatusOK) })
+	router.POST("/users", func(c *core.Context) { c.ResStatus(http.StatusCreated) })
+	hs := core.NewHandlersStack()
+	hs.Use(router.Handler())
+	return hs
+}
+
+func BenchmarkContextPool(b *testing.B) {
+	hs := core.NewHandlersStack()
+	hs.Use(func(c *core.Context) {})
+	r, _ := http.NewRequest("GET", "/", nil)
+	serve(b, hs, r)
+}
+
+func BenchmarkRouteStatic(b *testing.B) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	serve(b, routerStack(), r)
+}
+
+func BenchmarkRouteParam(b *testing.B) {
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	serve(b, routerStack(), r)
+}
+
+func BenchmarkRouteParams(b *testing.B) {
+	r, _ := http.NewRequest("GET", "/users/42/posts/7", nil)
+	serve(b, routerStack(), r)
+}
+
+func BenchmarkRouteNotFound(b *testing.B) {
+	r, _ := http.NewRequest("GET", "/nowhere", nil)
+	serve(b, routerStack(), r)
+}
+
+func BenchmarkOk(b *testing.B) {
+	hs := core.NewHandlersStack()
+	hs.Use(func(c *core.Context) {
+		c.Ok(core.H{"id": 42, "name": "core", "tags": []string{"a", "b"}})
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	serve(b, hs, r)
+}
+
+func BenchmarkFail(b *testing.B) {
+	hs := core.NewHandlersStack()
+	err := (&core.BusinessError{}).New(1001, "invalid")
+	hs.Use(func(c *core.Context) { c.Fail(err) })
+	r, _ := http.NewRequest("GET", "/", nil)
+	serve(b, hs, r)
+}
+
+func BenchmarkMiddlewareChain(b *testing.B) {
+	hs := core.NewHandlersStack()
+	for i := 0; i < 5; i++ {
+		hs.Use(func(c *core.Context) { c.Next() })
+	}
+	hs.Use(func(c *core.Context) { c.ResStatus(http.StatusOK) })
+	r, _ := http.NewRequest("GET", "/", nil)
+	serve(b, hs, r)
+}
+
+func BenchmarkParallel(b *testing.B) {
+	hs := routerStack()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		w := newDiscardWriter()
+		r, _ := http.NewRequest("GET", "/users/42", nil)
+		for pb.Next() {
+			hs.ServeHTTP(w, r)
+		}
+	})
+}
+
+// TestRegressionGate compares the benchmark outputs given by CORE_BENCH_BASELINE and CORE_BENCH_CURRENT.
+// CORE_BENCH_TOLERANCE sets the accepted slowdown, default is 0.1 (10%).
+func TestRegressionGate(t *testing.T) {
+	basePath, curPath := os.Getenv("CORE_BENCH_BASELINE"), os.Getenv("CORE_BENCH_CURRENT")
+	if basePath == "" || curPath == "" {
+		t.Skip("CORE_BENCH_BASELINE and CORE_BENCH_CURRENT are not set")
+	}
+	tolerance := 0.1
+	if v := os.Getenv("CORE_BENCH_TOLERANCE"); v != "" {
+		if _, err := fmt.Sscanf(v, "%g", &tolerance); err != nil {
+			t.Fatalf("CORE_BENCH_TOLERANCE: %v", err)
+		}
+	}
+	baseline, current := parseFile(t, basePath), parseFile(t, curPath)
+	for _, r := range Compare(baseline, current, tolerance) {
+		t.Error(r)
+	}
+}
+
+func parseFile(t *testing.T, path string) map[string]Result {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	results, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
@@ -0,0 +1,116 @@
+// Package benchmarks contains the performance benchmarks of core and a helper to compare them against a baseline.
+//
+// Record a baseline before a performance-motivated change:
+//
+//	go test -run=NONE -bench=. -benchmem ./benchmarks > bench_baseline.txt
+//
+// Then gate the change on it:
+//
+//	go test -run=NONE -bench=. -benchmem ./benchmarks > bench_output.txt
+//	CORE_BENCH_BASELINE=bench_baseline.txt CORE_BENCH_CURRENT=bench_output.txt go test -run=TestRegressionGate ./benchmarks
+package benchmarks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the measure of a benchmark.
+type Result struct {
+	Name        string
+	NsPerOp     float64
+	BytesPerOp  float64
+	AllocsPerOp float64
+}
+
+// Regression describes a benchmark that became slower or allocates more than the baseline.
+type Regression struct {
+	Name     string
+	Metric   string
+	Baseline float64
+	Current  float64
+}
+
+// String formats the regression for reports.
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s %.2f -> %.2f (%+.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, (r.Current-r.Baseline)/r.Baseline*100)
+}
+
+// Parse reads the output of "go test -bench" and returns the results by benchmark name.
+// The GOMAXPROCS suffix is removed from names so that outputs of different machines can be compared.
+// When a benchmark appears several times (-count), the best result is kept.
+func Parse(r io.Reader) (map[string]Result, error) {
+	results := make(map[string]Result)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		res := Result{Name: trimProcs(fields[0])}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("benchmarks: parse %q: %v", scanner.Text(), err)
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				res.NsPerOp = v
+			case "B/op":
+				res.BytesPerOp = v
+			case "allocs/op":
+				res.AllocsPerOp = v
+			}
+		}
+		if old, ok := results[res.Name]; ok == true && old.NsPerOp <= res.NsPerOp {
+			continue
+		}
+		results[res.Name] = res
+	}
+	return results, scanner.Err()
+}
+
+// Compare returns the benchmarks of current that are slower than baseline by more than tolerance (0.1 is 10%),
+// or that allocate more memory.
+// Benchmarks missing from one of the sides are ignored.
+func Compare(baseline, current map[string]Result, tolerance float64) []Regression {
+	var regressions []Regression
+	for name, cur := range current {
+		base, ok := baseline[name]
+		if ok == false {
+			continue
+		}
+		if base.NsPerOp > 0 && cur.NsPerOp > base.NsPerOp*(1+tolerance) {
+			regressions = append(regressions, Regression{name, "ns/op", base.NsPerOp, cur.NsPerOp})
+		}
+		if cur.AllocsPerOp > base.AllocsPerOp {
+			regressions = append(regressions, Regression{name, "allocs/op", base.AllocsPerOp, cur.AllocsPerOp})
+		}
+		if cur.BytesPerOp > base.BytesPerOp*(1+tolerance) {
+			regressions = append(regressions, Regression{name, "B/op", base.BytesPerOp, cur.BytesPerOp})
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool {
+		if regressions[i].Name != regressions[j].Name {
+			return regressions[i].Name < regressions[j].Name
+		}
+		return regressions[i].Metric < regressions[j].Metric
+	})
+	return regressions
+}
+
+// trimProcs removes the "-8" GOMAXPROCS suffix of a benchmark name.
+func trimProcs(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
@@ -0,0 +1,46 @@
+package benchmarks
+
+import (
+	"strings"
+	"testing"
+)
+
+const baselineOutput = `goos: linux
+BenchmarkRouteStatic-8   	 5000000	       300 ns/op	      64 B/op	       2 allocs/op
+BenchmarkRouteParam-8    	 3000000	       400 ns/op	      96 B/op	       3 allocs/op
+BenchmarkOk-8            	 1000000	      1000 ns/op	     512 B/op	       8 allocs/op
+PASS
+`
+
+const currentOutput = `BenchmarkRouteStatic-4   	 5000000	       320 ns/op	      64 B/op	       2 allocs/op
+BenchmarkRouteParam-4    	 3000000	       500 ns/op	      96 B/op	       3 allocs/op
+BenchmarkOk-4            	 1000000	      1000 ns/op	     512 B/op	       9 allocs/op
+BenchmarkOk-4            	 1000000	       900 ns/op	     512 B/op	       9 allocs/op
+BenchmarkNew-4           	 1000000	      1000 ns/op	     512 B/op	       8 allocs/op
+`
+
+func TestCompare(t *testing.T) {
+	baseline, err := Parse(strings.NewReader(baselineOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := Parse(strings.NewReader(currentOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := current["BenchmarkOk"].NsPerOp; got != 900 {
+		t.Errorf("best of runs: want 900, got %v", got)
+	}
+
+	regressions := Compare(baseline, current, 0.1)
+	want := []string{"BenchmarkOk allocs/op", "BenchmarkRouteParam ns/op"}
+	if len(regressions) != len(want) {
+		t.Fatalf("regressions: want %v, got %v", want, regressions)
+	}
+	for i, r := range regressions {
+		if got := r.Name + " " + r.Metric; got != want[i] {
+			t.Errorf("regression %d: want %q, got %q", i, want[i], got)
+		}
+	}
+}
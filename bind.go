@@ -0,0 +1,312 @@
+package core
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ShouldBind binds the request to the struct pointed by dst, then validates it with its validate tags.
+//
+// The fields are bound from the body by default, decoded by its Content-Type: JSON, or a form by the field names.
+// The body without a Content-Type is sniffed, JSON if it starts with { or [.
+// The fields with an in tag are bound from its sources instead, the first one with a value in the tag order:
+// path, query, header or body. A value of the body doesn't override them.
+// The name of the value is the name tag, or the json tag, or the field name.
+// A header tag is a shorthand for in:"header" with the header name, like with BindHeader:
+//
+//	type updateUser struct {
+//		ID      string `in:"path" name:"id" validate:"required"`
+//		DryRun  bool   `in:"query|header" name:"dry_run"`
+//		TraceID string `header:"X-Trace-Id"`
+//		Name    string `json:"name" validate:"required"`
+//	}
+//
+// The returned error is an ICoreError to use with Fail, with the malformed fields in the details.
+func (ctx *Context) ShouldBind(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return (&ServerError{}).New("ShouldBind: dst must be a non-nil pointer to a struct")
+	}
+	form, err := ctx.bindBody(dst)
+	if err != nil {
+		return err
+	}
+	var fields []FieldError
+	bindFields(rv.Elem(), func(f reflect.StructField, v reflect.Value) {
+		sources := strings.Split(f.Tag.Get("in"), "|")
+		name := fieldName(f)
+		if header := f.Tag.Get("header"); header != "" {
+			sources, name = []string{"header"}, header
+		} else if f.Tag.Get("in") == "" {
+			sources = []string{"body"}
+		}
+		if stringIn("body", sources) == false {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		for _, source := range sources {
+			values := ctx.bindValues(source, name, form)
+			if len(values) == 0 {
+				continue
+			}
+			if err := setField(v, values); err != nil {
+				fields = append(fields, FieldError{Field: name, Message: name + ": " + err.Error()})
+			}
+			return
+		}
+	})
+	if len(fields) > 0 {
+		return (&ValidationError{}).NewFields(fields...)
+	}
+	if err := structValidate.Struct(dst); err != nil {
+		return validatorError(err)
+	}
+	return nil
+}
+
+// bindBody decodes the JSON body into dst, or returns the values of the form body.
+func (ctx *Context) bindBody(dst interface{}) (url.Values, error) {
+	contentType := strings.TrimSpace(strings.Split(ctx.Request.Header.Get("Content-Type"), ";")[0])
+	if contentType == "multipart/form-data" {
+		if err := ctx.Request.ParseMultipartForm(int64(MultipartMaxmemoryMb) << 20); err != nil {
+			return nil, (&ValidationError{}).New("invalid form: " + err.Error())
+		}
+		return ctx.Request.MultipartForm.Value, nil
+	}
+	body, err := ctx.BodyBytes()
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil, err
+	}
+	if contentType == "" {
+		if b := bytes.TrimSpace(body); b[0] == '{' || b[0] == '[' {
+			contentType = "application/json"
+		}
+	}
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		var json = jsoniter.ConfigCompatibleWithStandardLibrary
+		if err := json.Unmarshal(body, dst); err != nil {
+			return nil, (&ValidationError{}).New("invalid JSON body: " + err.Error())
+		}
+		return nil, nil
+	case contentType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, (&ValidationError{}).New("invalid form: " + err.Error())
+		}
+		return form, nil
+	}
+	e := (&ValidationError{}).New("unsupported body Content-Type")
+	e.HTTPCode = http.StatusUnsupportedMediaType
+	return nil, e
+}
+
+// bindValues returns the values of name in source.
+func (ctx *Context) bindValues(source, name string, form url.Values) []string {
+	switch source {
+	case "path":
+		if v, ok := ctx.Params.Get(name); ok == true {
+			return []string{v}
+		}
+	case "query":
+		return ctx.Request.URL.Query()[name]
+	case "header":
+		return ctx.Request.Header.Values(name)
+	case "body":
+		return form[name]
+	}
+	return nil
+}
+
+// bindFields calls bind with the settable fields of the struct v, and the fields of its embedded structs.
+func bindFields(v reflect.Value, bind func(f reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous == true && f.Type.Kind() == reflect.Struct {
+			bindFields(v.Field(i), bind)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		bind(f, v.Field(i))
+	}
+}
+
+// fieldName returns the name of the value of the field f: its name tag, or its json tag, or its name.
+func fieldName(f reflect.StructField) string {
+	if name := f.Tag.Get("name"); name != "" {
+		return name
+	}
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// setField sets v from values, converted to its type: the slices take all the values, the others the first one.
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+// setValue sets v from the string s, converted to its type.
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("must be a duration")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return errors.New("unsupported type " + v.Type().String())
+	}
+	return nil
+}
+
+// BindHeader binds the request headers to the fields with a header tag of the struct pointed by dst, then validates it
+// with its validate tags, e.g. validate:"required" for a required header:
+//
+//	type signed struct {
+//		Signature string    `header:"X-Signature" validate:"required"`
+//		Timestamp time.Time `header:"X-Timestamp"`
+//		Retries   int       `header:"X-Retry-Count"`
+//	}
+//
+// The returned error is an ICoreError to use with Fail, with the malformed headers in the details.
+func (ctx *Context) BindHeader(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return (&ServerError{}).New("BindHeader: dst must be a non-nil pointer to a struct")
+	}
+	var fields []FieldError
+	bindFields(rv.Elem(), func(f reflect.StructField, v reflect.Value) {
+		name := f.Tag.Get("header")
+		if name == "" {
+			return
+		}
+		if values := ctx.Request.Header.Values(name); len(values) > 0 {
+			if err := setField(v, values); err != nil {
+				fields = append(fields, FieldError{Field: name, Message: name + ": " + err.Error()})
+			}
+		}
+	})
+	if len(fields) > 0 {
+		return (&ValidationError{}).NewFields(fields...)
+	}
+	if err := structValidate.Struct(dst); err != nil {
+		return validatorError(err)
+	}
+	return nil
+}
+
+// HeaderInt returns the request header name as an int, or def if it is missing.
+// A malformed header returns a ValidationError to use with Fail.
+func (ctx *Context) HeaderInt(name string, def int) (int, error) {
+	s := ctx.Request.Header.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return def, (&ValidationError{}).NewFields(FieldError{Field: name, Message: name + ": must be an integer"})
+	}
+	return n, nil
+}
+
+// HeaderTime returns the request header name as a time, in the HTTP date format like If-Modified-Since, or RFC 3339.
+// It returns the zero time if it is missing, and a ValidationError to use with Fail if it is malformed.
+func (ctx *Context) HeaderTime(name string) (time.Time, error) {
+	s := strings.TrimSpace(ctx.Request.Header.Get(name))
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := parseTime(s)
+	if err != nil {
+		return time.Time{}, (&ValidationError{}).NewFields(FieldError{Field: name, Message: name + ": " + err.Error()})
+	}
+	return t, nil
+}
+
+// parseTime parses s in the HTTP date format, or RFC 3339.
+func parseTime(s string) (time.Time, error) {
+	if t, err := http.ParseTime(s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("must be an HTTP date")
+}
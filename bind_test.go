@@ -0,0 +1,110 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindUser struct {
+	ID      int           `in:"path" name:"id" validate:"required"`
+	DryRun  bool          `in:"query|header" name:"dry_run"`
+	TraceID string        `in:"header" name:"X-Trace-Id"`
+	Tags    []string      `in:"query" name:"tag"`
+	Timeout time.Duration `in:"query" name:"timeout"`
+	Name    string        `json:"name" validate:"required"`
+}
+
+func serveBind(dst interface{}, contentType, path, body string, header http.Header) error {
+	var err error
+	engine := New()
+	engine.POST("/users/:id", func(c *Context) {
+		err = c.ShouldBind(dst)
+		c.Ok(nil)
+	})
+	hs := NewHandlersStack()
+	hs.Use(engine.Handler())
+	r, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestShouldBind(t *testing.T) {
+	var u bindUser
+	err := serveBind(&u, "", "/users/42?tag=a&tag=b&timeout=2s", `{"name":"bob","ID":7,"TraceID":"body"}`, http.Header{"X-Trace-Id": {"t1"}, "Dry_run": {"true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bindUser{ID: 42, DryRun: true, TraceID: "t1", Tags: []string{"a", "b"}, Timeout: 2 * time.Second, Name: "bob"}
+	if u.ID != want.ID || u.DryRun != want.DryRun || u.TraceID != want.TraceID || strings.Join(u.Tags, ",") != "a,b" || u.Timeout != want.Timeout || u.Name != want.Name {
+		t.Errorf("bound: want %+v, got %+v", want, u)
+	}
+
+	var f bindUser
+	if err := serveBind(&f, "application/x-www-form-urlencoded", "/users/42", "name=alice", nil); err != nil || f.Name != "alice" {
+		t.Errorf("form: want alice, got %q %v", f.Name, err)
+	}
+}
+
+func TestShouldBindErrors(t *testing.T) {
+	var u bindUser
+	err := serveBind(&u, "application/json", "/users/x?timeout=soon", `{}`, nil)
+	details, _ := err.(*ValidationError).GetDetails().(*ErrorDetails)
+	if details == nil || len(details.Fields) != 2 || details.Fields[0].Message != "id: must be an integer" {
+		t.Errorf("malformed: want the field errors, got %v", err)
+	}
+	if err := serveBind(&u, "application/json", "/users/42", `{}`, nil); err == nil || !strings.Contains(err.Error(), "Name fails the required validation") {
+		t.Errorf("invalid: want the validation error, got %v", err)
+	}
+	if err := serveBind(&u, "text/csv", "/users/42", "a,b", nil); err == nil || err.(ICoreError).GetHTTPCode() != http.StatusUnsupportedMediaType {
+		t.Errorf("unsupported: want 415, got %v", err)
+	}
+}
+
+func TestBindHeader(t *testing.T) {
+	var h struct {
+		Signature string    `header:"X-Signature" validate:"required"`
+		Timestamp time.Time `header:"X-Timestamp"`
+		Retries   int       `header:"X-Retry-Count"`
+	}
+	hs := NewHandlersStack()
+	var errs []error
+	hs.Use(func(c *Context) {
+		if errs = append(errs, c.BindHeader(&h)); len(errs) > 1 {
+			c.Ok(nil)
+			return
+		}
+		n, err := c.HeaderInt("X-Retry-Count", 0)
+		if n != 3 || err != nil {
+			t.Errorf("HeaderInt: want 3, got %d %v", n, err)
+		}
+		if _, err := c.HeaderTime("X-Signature"); err == nil {
+			t.Error("HeaderTime: want an error for a malformed date")
+		}
+		c.Ok(nil)
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Signature", "abc")
+	r.Header.Set("X-Timestamp", "Sun, 06 Nov 1994 08:49:37 GMT")
+	r.Header.Set("X-Retry-Count", "3")
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+	if errs[0] != nil || h.Signature != "abc" || h.Timestamp.Year() != 1994 || h.Retries != 3 {
+		t.Errorf("bound: want the headers, got %+v %v", h, errs[0])
+	}
+
+	h.Signature = ""
+	r.Header.Del("X-Signature")
+	r.Header.Set("X-Retry-Count", "three")
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+	if errs[1] == nil || errs[1].Error() != "X-Retry-Count: must be an integer" {
+		t.Errorf("malformed: want the header error, got %v", errs[1])
+	}
+}
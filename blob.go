@@ -0,0 +1,47 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Blob responds with the raw bytes data of the type contentType, outside of the JSON envelope.
+// If a filename is given, the response is an attachment downloaded with this name.
+//
+//	ctx.Blob("image/png", avatar)
+//	ctx.Blob("application/pdf", invoice, "invoice-42.pdf")
+func (ctx *Context) Blob(contentType string, data []byte, filename ...string) {
+	ctx.DataWithContentType(contentType, bytes.NewReader(data), int64(len(data)), filename...)
+}
+
+// DataWithContentType responds with the content of r of the type contentType, outside of the JSON envelope.
+// If length isn't negative, it is the Content-Length of the response.
+// If a filename is given, the response is an attachment downloaded with this name.
+func (ctx *Context) DataWithContentType(contentType string, r io.Reader, length int64, filename ...string) {
+	if ctx.written == true {
+		ctx.doubleWrite("Context.DataWithContentType")
+		return
+	}
+	ctx.written = true
+	if ctx.ClientGone() == true {
+		ctx.logGone()
+		return
+	}
+
+	h := ctx.ResponseWriter.Header()
+	h.Set("Content-Type", contentType)
+	if len(filename) > 0 && filename[0] != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename[0]}))
+	}
+	ctx.finalizeHeader(http.StatusOK, length)
+	ctx.ResponseWriter.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(ctx.ResponseWriter, r); err != nil {
+		if ctx.gone == true {
+			ctx.logGone()
+			return
+		}
+		ctx.warn("write_error", err.Error())
+	}
+}
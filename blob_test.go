@@ -0,0 +1,57 @@
+package core
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDataWithContentType(t *testing.T) {
+	// Larger than the response buffer of the server, which would set the Content-Length of a smaller one.
+	content := strings.Repeat("a,b\n", 4096)
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		// A stale Content-Length, like the one of a proxied response.
+		c.ResponseWriter.Header().Set("Content-Length", "1")
+		parts := io.MultiReader(strings.NewReader(content[:10]), strings.NewReader(content[10:]))
+		switch c.Request.URL.Path {
+		case "/stream":
+			c.DataWithContentType("text/csv", parts, -1)
+		case "/sized":
+			c.DataWithContentType("text/csv", parts, int64(len(content)), "export.csv")
+		}
+	})
+	server := httptest.NewServer(hs)
+	defer server.Close()
+
+	tests := []struct {
+		path          string
+		contentLength int64
+		chunked       bool
+		disposition   string
+	}{
+		{"/stream", -1, true, ""},
+		{"/sized", int64(len(content)), false, `attachment; filename=export.csv`},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != content || resp.Header.Get("Content-Type") != "text/csv" {
+			t.Errorf("%s: want the text/csv content, got %s %.20q", tt.path, resp.Header.Get("Content-Type"), body)
+		}
+		chunked := len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+		if resp.ContentLength != tt.contentLength || chunked != tt.chunked {
+			t.Errorf("%s: want Content-Length %d, chunked %t, got %d, %v", tt.path, tt.contentLength, tt.chunked, resp.ContentLength, resp.TransferEncoding)
+		}
+		if got := resp.Header.Get("Content-Disposition"); got != tt.disposition {
+			t.Errorf("%s: want Content-Disposition %q, got %q", tt.path, tt.disposition, got)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BlobInfo describes a blob of a BlobStore.
+type BlobInfo struct {
+	Key         string
+	Size        int64 // -1 if it is unknown.
+	ContentType string
+	ModTime     time.Time
+}
+
+// BlobStore stores the files of the framework features: the uploads, the served files and the images.
+// The keys are slash-separated paths like "avatars/bob.jpg".
+// FSBlobStore stores them in a directory, the object storages like S3 or GCS are plugged with adapters.
+// The methods return an error matching fs.ErrNotExist for the unknown keys.
+type BlobStore interface {
+	// Put stores the content of r under key, replacing the previous one. An empty contentType is derived from the key extension.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get opens the blob key. The reader may be an io.ReadSeeker for the range requests.
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	// SignedURL returns a URL downloading the blob key until expiry, without credentials.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Delete deletes the blob key. Deleting an unknown key isn't an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// blobContentType returns contentType, or the type of the extension of key.
+func blobContentType(key, contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// FSBlobStore is the BlobStore of the files of a directory. The content types are kept in the .meta subdirectory.
+// Its signed URLs are served by its Handler:
+//
+//	blobs, err := core.NewFSBlobStore("/var/lib/myapp/blobs", "https://example.com/blobs/", secret)
+//	core.Routers.GET("/blobs/*filepath", blobs.Handler)
+type FSBlobStore struct {
+	Dir     string
+	BaseURL string // The URL of the Handler route, like "https://example.com/blobs/".
+	Key     []byte // The key of the HMAC-SHA256 signatures of the URLs, default is SignedURLKey.
+}
+
+// NewFSBlobStore returns a new FSBlobStore in dir, created if it doesn't exist, with its URLs under baseURL signed by key,
+// of MinKeyLen bytes at least, or nil for SignedURLKey.
+func NewFSBlobStore(dir, baseURL string, key []byte) (*FSBlobStore, error) {
+	if key != nil && len(key) < MinKeyLen {
+		return nil, errors.New("core.NewFSBlobStore: " + errShortKey)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".meta"), 0755); err != nil {
+		return nil, err
+	}
+	return &FSBlobStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/") + "/", Key: key}, nil
+}
+
+// path returns the file path of key, and of its metadata. The keys can't escape Dir: the backslashes,
+// separators on Windows, and the volume names are rejected, and the metadata directory is matched case-insensitively.
+func (s *FSBlobStore) path(key string) (string, string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	meta := strings.ToLower(strings.SplitN(key, "/", 2)[0])
+	if key == "" || meta == ".meta" || strings.ContainsAny(key, "\\\x00") == true {
+		return "", "", (&ValidationError{}).New("invalid blob key")
+	}
+	name := filepath.FromSlash(key)
+	if filepath.VolumeName(name) != "" {
+		return "", "", (&ValidationError{}).New("invalid blob key")
+	}
+	return filepath.Join(s.Dir, name), filepath.Join(s.Dir, ".meta", name), nil
+}
+
+// Put writes the file of key atomically.
+func (s *FSBlobStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, meta, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), ".put-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(meta), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(meta, []byte(blobContentType(key, contentType)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Get opens the file of key.
+func (s *FSBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error) {
+	name, meta, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err == nil && stat.IsDir() == true {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	contentType, _ := ioutil.ReadFile(meta)
+	info := &BlobInfo{Key: key, Size: stat.Size(), ContentType: blobContentType(key, string(contentType)), ModTime: stat.ModTime()}
+	return f, info, nil
+}
+
+// SignedURL returns the URL of key under BaseURL, with its expires and signature query parameters like SignURL.
+func (s *FSBlobStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, _, err := s.path(key); err != nil {
+		return "", err
+	}
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	q := signQuery(s.signingKey(), key, nil, expiry)
+	return s.BaseURL + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+func (s *FSBlobStore) signingKey() []byte {
+	if s.Key != nil {
+		return s.Key
+	}
+	return SignedURLKey
+}
+
+// Delete removes the file of key.
+func (s *FSBlobStore) Delete(ctx context.Context, key string) error {
+	name, meta, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	os.Remove(meta)
+	if err := os.Remove(name); err != nil && errors.Is(err, fs.ErrNotExist) == false {
+		return err
+	}
+	return nil
+}
+
+// Handler serves the blob of a signed URL, the filepath param of the route. The requests with a missing, invalid or expired
+// signature fail with 403 Forbidden.
+func (s *FSBlobStore) Handler(ctx *Context) {
+	key := strings.TrimPrefix(path.Clean("/"+ctx.Param("filepath")), "/")
+	if err := verifyQuery(s.signingKey(), key, ctx.Request.URL.Query()); err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.ServeBlob(s, key)
+}
+
+// InlineBlobTypes are the content types of the blobs displayed by the browsers, the others are served as attachments:
+// the blobs uploaded with an HTML or SVG type can't run scripts on the site.
+var InlineBlobTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/mp4", "video/webm", "audio/mpeg", "audio/ogg", "audio/wav",
+	"application/pdf", "text/plain", "text/css", "text/csv", "application/json",
+	"application/javascript", "text/javascript", "font/woff", "font/woff2",
+}
+
+// inlineBlobType tells if the content type contentType is in InlineBlobTypes.
+func inlineBlobType(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, inline := range InlineBlobTypes {
+		if t == inline {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeBlob responds with the blob key of store, honoring the range requests when the store supports them.
+// The blobs are served with X-Content-Type-Options: nosniff, and as attachments unless their type is in InlineBlobTypes.
+func (ctx *Context) ServeBlob(store BlobStore, key string) {
+	r, info, err := store.Get(ctx.Request.Context(), key)
+	if errors.Is(err, fs.ErrNotExist) {
+		ctx.Fail((&NotFoundError{}).New("Blob not found"))
+		return
+	}
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	defer r.Close()
+	h := ctx.ResponseWriter.Header()
+	h.Set("X-Content-Type-Options", "nosniff")
+	if inlineBlobType(info.ContentType) == false {
+		h.Set("Content-Disposition", "attachment")
+	}
+	if rs, ok := r.(io.ReadSeeker); ok == true && info.Size >= 0 {
+		ctx.ResponseWriter.Header().Set("Content-Type", info.ContentType)
+		ctx.ServeRange(rs, info.Size, info.ModTime)
+		return
+	}
+	if info.ModTime.IsZero() == false {
+		ctx.ResponseWriter.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	ctx.DataWithContentType(info.ContentType, r, info.Size)
+}
+
+// Blobs serves the blobs of store under relativePath, publicly: the files of a directory, or of a bucket.
+//
+//	core.Routers.Blobs("/static", blobs)
+func (group *RouterGroup) Blobs(relativePath string, store BlobStore) *Route {
+	return group.GET(strings.TrimSuffix(relativePath, "/")+"/*filepath", func(ctx *Context) {
+		ctx.ServeBlob(store, strings.TrimPrefix(ctx.Param("filepath"), "/"))
+	})
+}
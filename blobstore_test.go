@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFSBlobStore(t *testing.T) {
+	store, err := NewFSBlobStore(t.TempDir(), "http://example.com/blobs", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := context.Background()
+	if err := store.Put(c, "docs/a.txt", strings.NewReader("hello world"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(c, "docs/b", strings.NewReader("{}"), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+	r, info, err := store.Get(c, "/docs/../docs/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(b) != "{}" || info.ContentType != "application/json" || info.Size != 2 {
+		t.Errorf("Get: got %q %+v", b, info)
+	}
+	if _, _, err := store.Get(c, "docs/c"); errors.Is(err, fs.ErrNotExist) == false {
+		t.Errorf("Get unknown: want fs.ErrNotExist, got %v", err)
+	}
+	for _, key := range []string{".meta/docs/b", ".META/docs/b", `..\..\secret`, `docs\..\..\secret`, "docs/a.txt\x00"} {
+		if _, _, err := store.Get(c, key); err == nil || errors.Is(err, fs.ErrNotExist) == true {
+			t.Errorf("Get %q: want an invalid key error, got %v", key, err)
+		}
+	}
+
+	engine := New()
+	engine.Blobs("/public", store)
+	engine.GET("/blobs/*filepath", store.Handler)
+	serve := func(target string, header http.Header) *httptest.ResponseRecorder {
+		hs := NewHandlersStack()
+		hs.Use(engine.Handler())
+		r, _ := http.NewRequest("GET", target, nil)
+		if header != nil {
+			r.Header = header
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/public/docs/a.txt", http.Header{"Range": {"bytes=6-"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "world" || strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") == false {
+		t.Errorf("range: got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("inline: got %v", w.Header())
+	}
+	store.Put(c, "docs/x", strings.NewReader("<script>alert(1)</script>"), "text/html")
+	if w = serve("/public/docs/x", nil); w.Header().Get("Content-Disposition") != "attachment" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("html: want an attachment, got %v", w.Header())
+	}
+	if w = serve("/public/docs/c", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown: want 404, got %d", w.Code)
+	}
+
+	signed, err := store.SignedURL(c, "docs/a.txt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(signed, "http://example.com/blobs/docs/a.txt?expires=") == false {
+		t.Fatalf("SignedURL: got %s", signed)
+	}
+	signed = strings.TrimPrefix(signed, "http://example.com")
+	if w = serve(signed, nil); w.Code != http.StatusOK || w.Body.String() != "hello world" {
+		t.Errorf("signed: got %d %q", w.Code, w.Body.String())
+	}
+	if w = serve(strings.Replace(signed, "a.txt", "b", 1), nil); w.Code != http.StatusForbidden {
+		t.Errorf("tampered: want 403, got %d", w.Code)
+	}
+	expired, _ := store.SignedURL(c, "docs/a.txt", -time.Minute)
+	if w = serve(strings.TrimPrefix(expired, "http://example.com"), nil); w.Code != http.StatusForbidden {
+		t.Errorf("expired: want 403, got %d", w.Code)
+	}
+
+	if err := store.Delete(c, "docs/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(c, "docs/a.txt"); err != nil {
+		t.Errorf("Delete unknown: %v", err)
+	}
+	if w = serve("/public/docs/a.txt", nil); w.Code != http.StatusNotFound {
+		t.Errorf("deleted: want 404, got %d", w.Code)
+	}
+}
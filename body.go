@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// BodyCacheLimit is the maximum size of the request body read and cached by BodyBytes, default is 4MB.
+var BodyCacheLimit int64 = 4 << 20
+
+// ErrBodyStreamed is returned by BodyBytes for the routes with the StreamBody option, whose body is read by the handler only.
+var ErrBodyStreamed = errors.New("core: the request body is streamed")
+
+// BodyBytes reads the request body and caches it, for the middleware and the handlers needing it all, like the signature
+// verification, the audit and the binding. Request.Body is restored to the start of the body, so the next readers still work.
+// A body larger than BodyCacheLimit fails with 413 Request Entity Too Large: the returned error is an ICoreError to use with Fail.
+func (ctx *Context) BodyBytes() ([]byte, error) {
+	return ctx.readBody(BodyCacheLimit)
+}
+
+// readBody reads and caches the request body of at most limit bytes. The limit applies to a body already cached with a higher one too.
+func (ctx *Context) readBody(limit int64) ([]byte, error) {
+	if body, ok := ctx.Data["body"].([]byte); ok == true {
+		ctx.setBody(body)
+		if int64(len(body)) > limit {
+			return nil, errBodyTooLarge()
+		}
+		return body, nil
+	}
+	if stream, _ := ctx.Data["bodyStream"].(bool); stream == true {
+		return nil, ErrBodyStreamed
+	}
+	if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, limit+1))
+	if err != nil {
+		// Give the read part back to the next readers.
+		ctx.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), ctx.Request.Body), ctx.Request.Body}
+		return nil, (&ValidationError{}).New("invalid body: " + err.Error())
+	}
+	if int64(len(body)) > limit {
+		ctx.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), ctx.Request.Body), ctx.Request.Body}
+		return nil, errBodyTooLarge()
+	}
+	ctx.Request.Body.Close()
+	ctx.setBody(body)
+	return body, nil
+}
+
+// errBodyTooLarge returns the error of a body beyond its limit, failing with 413 Request Entity Too Large.
+func errBodyTooLarge() error {
+	e := (&ValidationError{}).New("body too large")
+	e.HTTPCode = http.StatusRequestEntityTooLarge
+	return e
+}
+
+// setBody caches body, and restores Request.Body to its start.
+func (ctx *Context) setBody(body []byte) {
+	ctx.Data["body"] = body
+	ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+}
+
+// CloneRequest returns a replayable copy of the request, for the shadow traffic, the retries to another backend,
+// or an asynchronous audit: its body is the cached body of BodyBytes, with GetBody to read it again,
+// its headers are copied without the hop-by-hop headers, and its context is a new one, not canceled when the request ends.
+// Its URL is the request URL: set its scheme and host to send it to a backend.
+func (ctx *Context) CloneRequest() (*http.Request, error) {
+	body, err := ctx.BodyBytes()
+	if err != nil {
+		return nil, err
+	}
+	r := ctx.Request.Clone(context.Background())
+	r.RequestURI = ""
+	for _, h := range hopHeaders {
+		r.Header.Del(h)
+	}
+	if body == nil {
+		r.Body = http.NoBody
+		return r, nil
+	}
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.Body, _ = r.GetBody()
+	return r, nil
+}
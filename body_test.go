@@ -0,0 +1,89 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveBody(h RouterHandler, body string) *httptest.ResponseRecorder {
+	hs := NewHandlersStack()
+	hs.Use(h)
+	r, _ := http.NewRequest("POST", "/upload", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	return w
+}
+
+func TestBodyBytes(t *testing.T) {
+	var first, second, read []byte
+	serveBody(func(c *Context) {
+		first, _ = c.BodyBytes()
+		second, _ = c.BodyBytes()
+		read, _ = ioutil.ReadAll(c.Request.Body)
+		c.Ok(nil)
+	}, `{"name":"bob"}`)
+	for _, got := range [][]byte{first, second, read} {
+		if string(got) != `{"name":"bob"}` {
+			t.Errorf("body: want the request body, got %q", got)
+		}
+	}
+
+	BodyCacheLimit = 4
+	defer func() { BodyCacheLimit = 4 << 20 }()
+	w := serveBody(func(c *Context) {
+		if _, err := c.BodyBytes(); err != nil {
+			c.Fail(err)
+			return
+		}
+		c.Ok(nil)
+	}, `{"name":"bob"}`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status code: want %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestStreamBody(t *testing.T) {
+	var err error
+	var read []byte
+	engine := New()
+	engine.POST("/upload", func(c *Context) {
+		_, err = c.BodyBytes()
+		read, _ = ioutil.ReadAll(c.Request.Body)
+		c.Ok(nil)
+	}).Options(RouteOptions{StreamBody: true})
+	hs := NewHandlersStack()
+	hs.Use(engine.Handler())
+	r, _ := http.NewRequest("POST", "/upload", strings.NewReader("chunk"))
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	if err != ErrBodyStreamed || string(read) != "chunk" {
+		t.Errorf("streamed body: want ErrBodyStreamed and the body unread, got %v %q", err, read)
+	}
+}
+
+func TestCloneRequest(t *testing.T) {
+	var clone *http.Request
+	serveBody(func(c *Context) {
+		c.Request.Header.Set("Connection", "keep-alive")
+		c.Request.Header.Set("X-Request-Id", "42")
+		clone, _ = c.CloneRequest()
+		clone.Header.Set("X-Request-Id", "43")
+		if c.Request.Header.Get("X-Request-Id") != "42" {
+			t.Error("headers: want a copy")
+		}
+		c.Ok(nil)
+	}, "payload")
+
+	if clone.Context().Err() != nil || clone.Header.Get("Connection") != "" || clone.ContentLength != 7 {
+		t.Errorf("clone: want a live context without hop headers, got %v %v", clone.Context().Err(), clone.Header)
+	}
+	for i := 0; i < 2; i++ {
+		body, _ := clone.GetBody()
+		if b, _ := ioutil.ReadAll(body); string(b) != "payload" {
+			t.Errorf("replay %d: want the body, got %q", i, b)
+		}
+	}
+}
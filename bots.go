@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// Bot is a known bot, recognized by its user agent.
+type Bot struct {
+	Name      string
+	UserAgent string   // The token of the user agent, matched case-insensitively, like "googlebot".
+	Domains   []string // The domains of the reverse DNS of the bot IPs, like ".googlebot.com". Empty if the bot can't be verified.
+}
+
+// KnownBots are the bots recognized by default by NewBotDetector.
+var KnownBots = []Bot{
+	{Name: "Googlebot", UserAgent: "googlebot", Domains: []string{".googlebot.com", ".google.com", ".googleusercontent.com"}},
+	{Name: "Bingbot", UserAgent: "bingbot", Domains: []string{".search.msn.com"}},
+	{Name: "Applebot", UserAgent: "applebot", Domains: []string{".applebot.apple.com"}},
+	{Name: "YandexBot", UserAgent: "yandexbot", Domains: []string{".yandex.ru", ".yandex.net", ".yandex.com"}},
+	{Name: "Baiduspider", UserAgent: "baiduspider", Domains: []string{".crawl.baidu.com", ".crawl.baidu.jp"}},
+	{Name: "DuckDuckBot", UserAgent: "duckduckbot"},
+	{Name: "facebookexternalhit", UserAgent: "facebookexternalhit"},
+	{Name: "Twitterbot", UserAgent: "twitterbot"},
+	{Name: "LinkedInBot", UserAgent: "linkedinbot"},
+	{Name: "Slackbot", UserAgent: "slackbot"},
+}
+
+// DNSResolver resolves the reverse and forward DNS of the bot IPs, like net.DefaultResolver.
+type DNSResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// BotDetector is the middleware recognizing the bots by their user agent, see Context.IsBot and Context.BotName.
+// The bots with domains are verified by the reverse DNS of the client IP, confirmed by its forward DNS:
+// the impostors using their user agent are counted in Metrics under "bots.impostors", and handled as the other clients.
+// The bots are counted in Metrics under "bots.<name>", the verifications failing on a DNS error under "bots.errors".
+//
+//	core.Use(core.NewBotDetector().Handler)
+type BotDetector struct {
+	Bots          []Bot
+	Resolver      DNSResolver
+	VerifyTimeout time.Duration // The timeout of the DNS verification, default is 2 seconds.
+	Cache         *Cache        // The verifications by IP and bot, default is 10000 for a day.
+
+	// Serve serves the bot requests instead of the next handlers, e.g. with the cached or prerendered pages.
+	// Default is nil, the bots are served by the next handlers.
+	Serve RouterHandler
+}
+
+// NewBotDetector returns a new BotDetector of the KnownBots, verified with net.DefaultResolver.
+func NewBotDetector() *BotDetector {
+	return &BotDetector{
+		Bots:          KnownBots,
+		Resolver:      net.DefaultResolver,
+		VerifyTimeout: 2 * time.Second,
+		Cache:         NewCache("bots", 10000, 24*time.Hour),
+	}
+}
+
+// Handler recognizes the bot of the request, then calls the next handlers, or Serve for the bots.
+func (d *BotDetector) Handler(ctx *Context) {
+	if bot := d.detect(ctx); bot != nil {
+		ctx.Data["bot"] = bot.Name
+		Metrics.Add("bots."+bot.Name, 1)
+		if d.Serve != nil {
+			d.Serve(ctx)
+			return
+		}
+	}
+	ctx.Next()
+}
+
+// detect returns the verified bot of the request, or nil.
+func (d *BotDetector) detect(ctx *Context) *Bot {
+	ua := strings.ToLower(ctx.Request.UserAgent())
+	if ua == "" {
+		return nil
+	}
+	for i := range d.Bots {
+		bot := &d.Bots[i]
+		if strings.Contains(ua, bot.UserAgent) == false {
+			continue
+		}
+		if len(bot.Domains) == 0 || d.verified(ctx, bot) == true {
+			return bot
+		}
+		Metrics.Add("bots.impostors", 1)
+		return nil
+	}
+	return nil
+}
+
+// verified tells if the client IP of the request belongs to the domains of bot, by its reverse DNS confirmed by the forward DNS.
+// The verifications failing on a DNS error or timeout are not cached, so that the bot is verified again by its next requests.
+func (d *BotDetector) verified(ctx *Context, bot *Bot) bool {
+	ip := ctx.ClientIP()
+	key := bot.Name + " " + ip
+	if d.Cache != nil {
+		if ok, found := d.Cache.Get(key); found == true {
+			return ok.(bool)
+		}
+	}
+	ok, err := d.verify(ctx.Request.Context(), ip, bot.Domains)
+	if err != nil {
+		Metrics.Add("bots.errors", 1)
+		return false
+	}
+	if d.Cache != nil {
+		d.Cache.Set(key, ok)
+	}
+	return ok
+}
+
+// verify verifies ip by DNS against domains. The error is the one of a DNS lookup failing otherwise than by a not found name.
+func (d *BotDetector) verify(ctx context.Context, ip string, domains []string) (bool, error) {
+	timeout := d.VerifyTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false, nil
+	}
+	names, err := d.Resolver.LookupAddr(ctx, ip)
+	if err != nil {
+		return false, lookupError(ctx, err)
+	}
+	var lookupErr error
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		matched := false
+		for _, domain := range domains {
+			if strings.HasSuffix(name, domain) {
+				matched = true
+				break
+			}
+		}
+		if matched == false {
+			continue
+		}
+		addrs, err := d.Resolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			lookupErr = lookupError(ctx, err)
+			continue
+		}
+		for _, a := range addrs {
+			if a.IP.Equal(parsed) {
+				return true, nil
+			}
+		}
+	}
+	return false, lookupErr
+}
+
+// lookupError returns err of a DNS lookup with ctx, or nil if the name is not found: the IP is then not verified for sure.
+func lookupError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if dnsErr, ok := err.(*net.DNSError); ok == true && dnsErr.IsNotFound == true {
+		return nil
+	}
+	return err
+}
+
+// IsBot tells if the request is from a bot recognized by the BotDetector middleware.
+func (ctx *Context) IsBot() bool {
+	return ctx.BotName() != ""
+}
+
+// BotName returns the name of the bot recognized by the BotDetector middleware, or "".
+func (ctx *Context) BotName() string {
+	name, _ := ctx.Data["bot"].(string)
+	return name
+}
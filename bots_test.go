@@ -0,0 +1,216 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDNS struct {
+	reverse map[string][]string
+	forward map[string][]string
+	fails   *int // The number of the next reverse lookups failing.
+}
+
+func (f fakeDNS) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if f.fails != nil && *f.fails > 0 {
+		*f.fails--
+		return nil, &net.DNSError{Err: "i/o timeout", Name: addr, IsTimeout: true}
+	}
+	if names, ok := f.reverse[addr]; ok == true {
+		return names, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func (f fakeDNS) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	for _, ip := range f.forward[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestBotDetector(t *testing.T) {
+	d := NewBotDetector()
+	d.Resolver = fakeDNS{
+		reverse: map[string][]string{"66.249.66.1": {"crawl-66-249-66-1.googlebot.com."}, "203.0.113.5": {"fake.googlebot.com."}},
+		forward: map[string][]string{"crawl-66-249-66-1.googlebot.com": {"66.249.66.1"}, "fake.googlebot.com": {"198.51.100.1"}},
+	}
+	var bot string
+	hs := NewHandlersStack()
+	hs.Use(d.Handler)
+	hs.Use(func(c *Context) {
+		bot = c.BotName()
+		c.Ok(nil)
+	})
+	googlebot := "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	tests := []struct {
+		remote, ua, bot string
+	}{
+		{"66.249.66.1:1", googlebot, "Googlebot"},
+		{"66.249.66.1:2", googlebot, "Googlebot"},
+		{"203.0.113.5:1", googlebot, ""},
+		{"192.0.2.1:1", googlebot, ""},
+		{"192.0.2.1:1", "Slackbot-LinkExpanding 1.0", "Slackbot"},
+		{"192.0.2.1:1", "Mozilla/5.0 (X11; Linux x86_64)", ""},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		r.Header.Set("User-Agent", tt.ua)
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if bot != tt.bot {
+			t.Errorf("%s %q: want bot %q, got %q", tt.remote, tt.ua, tt.bot, bot)
+		}
+	}
+}
+
+func TestBotDetectorErrors(t *testing.T) {
+	fails := 1
+	d := NewBotDetector()
+	d.Resolver = fakeDNS{
+		reverse: map[string][]string{"66.249.66.1": {"crawl-66-249-66-1.googlebot.com."}},
+		forward: map[string][]string{"crawl-66-249-66-1.googlebot.com": {"66.249.66.1"}},
+		fails:   &fails,
+	}
+	var bot string
+	hs := NewHandlersStack()
+	hs.Use(d.Handler)
+	hs.Use(func(c *Context) {
+		bot = c.BotName()
+		c.Ok(nil)
+	})
+	for i, want := range []string{"", "Googlebot", "Googlebot"} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "66.249.66.1:1"
+		r.Header.Set("User-Agent", "Googlebot/2.1")
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if bot != want {
+			t.Errorf("request %d: want bot %q, got %q", i, want, bot)
+		}
+	}
+	if d.Cache.Len() != 1 {
+		t.Errorf("want the verification cached once the DNS answers, got %d entries", d.Cache.Len())
+	}
+}
+
+type prerenderFunc func(ctx context.Context, pageURL string) ([]byte, error)
+
+func (f prerenderFunc) Render(ctx context.Context, pageURL string) ([]byte, error) {
+	return f(ctx, pageURL)
+}
+
+func TestPrerender(t *testing.T) {
+	var rendered []string
+	p := NewPrerender("https://www.example.com/", prerenderFunc(func(ctx context.Context, pageURL string) ([]byte, error) {
+		rendered = append(rendered, pageURL)
+		if strings.Contains(pageURL, "broken") {
+			return nil, errors.New("timeout")
+		}
+		return []byte("<h1>rendered</h1>"), nil
+	}))
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		if strings.Contains(c.Request.UserAgent(), "Slackbot") {
+			c.Data["bot"] = "Slackbot"
+		}
+		c.Next()
+	})
+	hs.Use(p.Handler)
+	hs.Use(func(c *Context) { c.HTML(http.StatusOK, "<div id=app></div>") })
+	serve := func(target, ua string) string {
+		r, _ := http.NewRequest("GET", target, nil)
+		r.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	tests := []struct {
+		target, ua, body string
+	}{
+		{"http://example.com/products/1", "Slackbot 1.0", "<h1>rendered</h1>"},
+		{"http://example.com/products/1", "Slackbot 1.0", "<h1>rendered</h1>"},
+		{"http://example.com/products/1?_escaped_fragment_=", "Mozilla/5.0", "<div id=app></div>"},
+		{"http://evil.example/products/2", "Slackbot 1.0", "<h1>rendered</h1>"},
+		{"http://example.com/products/1", "Mozilla/5.0", "<div id=app></div>"},
+		{"http://example.com/app.js", "Slackbot 1.0", "<div id=app></div>"},
+		{"http://example.com/broken", "Slackbot 1.0", "<div id=app></div>"},
+	}
+	for _, tt := range tests {
+		if got := serve(tt.target, tt.ua); got != tt.body {
+			t.Errorf("%s %q: want %q, got %q", tt.target, tt.ua, tt.body, got)
+		}
+	}
+	if len(rendered) != 3 || rendered[0] != "https://www.example.com/products/1" || rendered[1] != "https://www.example.com/products/2" {
+		t.Errorf("rendered: want the cached pages rendered once at the base URL, got %v", rendered)
+	}
+}
+
+func TestPrerenderLimits(t *testing.T) {
+	var rendered []string
+	release := make(chan struct{})
+	p := NewPrerender("https://www.example.com", prerenderFunc(func(ctx context.Context, pageURL string) ([]byte, error) {
+		rendered = append(rendered, pageURL)
+		if strings.Contains(pageURL, "slow") {
+			<-release
+		}
+		return []byte("<h1>rendered</h1>"), nil
+	}))
+	p.QueryParams = []string{"page"}
+	p.MaxConcurrent = 1
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.Data["bot"] = "Slackbot"
+		c.Next()
+	})
+	hs.Use(p.Handler)
+	hs.Use(func(c *Context) { c.HTML(http.StatusOK, "<div id=app></div>") })
+	serve := func(target string) string {
+		r, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	for _, target := range []string{"/products?a=1", "/products?b=2&page=2", "/products?page=2&c=3"} {
+		serve(target)
+	}
+	if len(rendered) != 2 || rendered[0] != "https://www.example.com/products" || rendered[1] != "https://www.example.com/products?page=2" {
+		t.Errorf("rendered: want the pages rendered once by kept query params, got %v", rendered)
+	}
+
+	done := make(chan string)
+	go func() { done <- serve("/slow") }()
+	for len(p.sem) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := serve("/other"); got != "<div id=app></div>" {
+		t.Errorf("beyond MaxConcurrent: want the shell, got %q", got)
+	}
+	close(release)
+	if got := <-done; got != "<h1>rendered</h1>" {
+		t.Errorf("want the slow page rendered, got %q", got)
+	}
+}
+
+func TestPrerenderService(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer service.Close()
+	s := &PrerenderService{URL: service.URL, MaxBody: 100}
+	if html, err := s.Render(context.Background(), "https://www.example.com/"); err != nil || len(html) != 100 {
+		t.Errorf("want the page, got %d bytes, %v", len(html), err)
+	}
+	s.MaxBody = 99
+	if _, err := s.Render(context.Background(), "https://www.example.com/"); err == nil {
+		t.Error("page beyond MaxBody: want an error")
+	}
+}
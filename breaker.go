@@ -0,0 +1,199 @@
+package core
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned by a Breaker refusing a call.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+// The states of a Breaker.
+const (
+	BreakerClosed   BreakerState = iota // Calls are allowed, failures are counted.
+	BreakerOpen                         // Calls are refused until OpenTimeout elapsed.
+	BreakerHalfOpen                     // A few trial calls are allowed, to decide to close or open again.
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Breaker is a circuit breaker protecting an upstream: when the failure rate of the calls reaches FailureRate,
+// it opens and refuses the calls for OpenTimeout, then lets HalfOpenRequests trial calls go through to decide to close again.
+//
+// It can be used as a middleware of the proxy routes with Handler, or for outbound calls with Do or Context.Breaker.
+// The state is published in Metrics under "breaker.<name>", the refused calls under "breaker.<name>.rejected".
+type Breaker struct {
+	Name             string
+	FailureRate      float64       // The failure rate opening the breaker, default is 0.5.
+	MinRequests      int           // The minimum number of calls in Window before evaluating the failure rate, default is 20.
+	Window           time.Duration // The duration of the failures counting, default is 10s.
+	OpenTimeout      time.Duration // The duration of the open state, default is 30s.
+	HalfOpenRequests int           // The number of trial calls in half-open state, default is 1.
+
+	// Fallback is the handler called by Handler when the breaker is open.
+	// Default is nil, a 503 Service Unavailable fail response is written.
+	Fallback RouterHandler
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trials      int
+}
+
+// NewBreaker returns a new closed Breaker with the default settings, and publishes its state.
+func NewBreaker(name string) *Breaker {
+	b := &Breaker{
+		Name:             name,
+		FailureRate:      0.5,
+		MinRequests:      20,
+		Window:           10 * time.Second,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+	Metrics.Set("breaker."+name, expvar.Func(func() interface{} { return b.State().String() }))
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Allow asks the breaker for a call. If it is allowed, done must be called with the outcome of the call,
+// otherwise ErrBreakerOpen is returned.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	switch b.state {
+	case BreakerOpen:
+		Metrics.Add("breaker."+b.Name+".rejected", 1)
+		return nil, ErrBreakerOpen
+	case BreakerHalfOpen:
+		if b.trials >= b.HalfOpenRequests {
+			Metrics.Add("breaker."+b.Name+".rejected", 1)
+			return nil, ErrBreakerOpen
+		}
+		b.trials++
+	}
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.record(success) })
+	}, nil
+}
+
+// Do executes fn if the breaker allows it, a non-nil error being a failure.
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			done(false)
+			panic(e)
+		}
+	}()
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+// Handler is a middleware protecting the next handlers: a panic or a 5xx response is a failure.
+func (b *Breaker) Handler(ctx *Context) {
+	done, err := b.Allow()
+	if err != nil {
+		if b.Fallback != nil {
+			b.Fallback(ctx)
+			return
+		}
+		ctx.FailWithStatus(http.StatusServiceUnavailable, (&ServerError{}).New(err.Error()))
+		return
+	}
+	success := false
+	defer func() { done(success) }()
+	ctx.Next()
+	success = ctx.Status() < http.StatusInternalServerError
+}
+
+// Breaker executes fn through the Breaker b.
+func (ctx *Context) Breaker(b *Breaker, fn func() error) error {
+	return b.Do(fn)
+}
+
+// record counts the outcome of a call.
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	switch b.state {
+	case BreakerHalfOpen:
+		if success == false {
+			b.open(now)
+			return
+		}
+		if b.trials >= b.HalfOpenRequests {
+			b.close(now)
+		}
+	case BreakerClosed:
+		b.requests++
+		if success == false {
+			b.failures++
+		}
+		if b.requests >= b.MinRequests && float64(b.failures)/float64(b.requests) >= b.FailureRate {
+			b.open(now)
+		}
+	}
+}
+
+// advance moves the breaker to half-open when the open timeout elapsed, and resets the counting window.
+func (b *Breaker) advance(now time.Time) {
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.OpenTimeout {
+			b.state = BreakerHalfOpen
+			b.trials = 0
+		}
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= b.Window {
+			b.windowStart = now
+			b.requests = 0
+			b.failures = 0
+		}
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+}
+
+func (b *Breaker) close(now time.Time) {
+	b.state = BreakerClosed
+	b.windowStart = now
+	b.requests = 0
+	b.failures = 0
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker("test")
+	b.MinRequests = 4
+	b.OpenTimeout = 20 * time.Millisecond
+	fail := func() error { return errors.New("upstream down") }
+	ok := func() error { return nil }
+
+	b.Do(ok)
+	b.Do(fail)
+	b.Do(ok)
+	if b.State() != BreakerClosed {
+		t.Fatalf("under MinRequests: want closed, got %s", b.State())
+	}
+	b.Do(fail)
+	if b.State() != BreakerOpen {
+		t.Fatalf("failure rate reached: want open, got %s", b.State())
+	}
+	if err := b.Do(ok); err != ErrBreakerOpen {
+		t.Errorf("open: want %v, got %v", ErrBreakerOpen, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("after OpenTimeout: want half-open, got %s", b.State())
+	}
+	b.Do(fail)
+	if b.State() != BreakerOpen {
+		t.Fatalf("failed trial: want open, got %s", b.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	b.Do(ok)
+	if b.State() != BreakerClosed {
+		t.Fatalf("successful trial: want closed, got %s", b.State())
+	}
+	if got := Metrics.Get("breaker.test").String(); got != `"closed"` {
+		t.Errorf("metrics: want %q, got %s", `"closed"`, got)
+	}
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AttemptRecord is the failed authentication attempts of a key.
+type AttemptRecord struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// AttemptStore stores the failed authentication attempts.
+// Incr and Lock are atomic, so that the concurrent failures of a key are all counted.
+type AttemptStore interface {
+	Get(key string) (AttemptRecord, error) // Returns a zero record if the key has none.
+	// Incr increments the failures of key, kept for ttl, and returns their new count.
+	Incr(key string, ttl time.Duration) (int, error)
+	// Lock locks key out until until, keeping its failures for ttl.
+	Lock(key string, until time.Time, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// AttemptEvent is an event of the brute force protection: "failure", "lockout" or "success".
+type AttemptEvent struct {
+	Type        string
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+}
+
+// BruteForce tracks the failed authentication attempts per key, like the client IP or the username,
+// and locks a key out after Threshold failures, for an exponential duration.
+//
+//	bf := core.NewBruteForce("login")
+//	core.Routers.POST("/login", bf.Handler, func(ctx *core.Context) {
+//		if ctx.AuthLocked("user:" + username) {
+//			return
+//		}
+//		if !checkPassword(username, password) {
+//			ctx.AuthFailed("user:" + username)
+//			ctx.Fail((&core.UnauthorizedError{}).New("invalid credentials"))
+//			return
+//		}
+//		ctx.AuthSucceeded("user:" + username)
+//	})
+//
+// The client IP is always tracked, as "ip:<ip>". The locked out requests fail with 429 Too Many Requests and a Retry-After header.
+type BruteForce struct {
+	Name        string
+	Store       AttemptStore  // Default is a MemoryAttemptStore, local to the instance.
+	Threshold   int           // The failures before the first lockout, default is 5.
+	BaseLockout time.Duration // The first lockout, doubled on each next failure, default is 1 minute.
+	MaxLockout  time.Duration // Default is 1 hour.
+	Window      time.Duration // The failures are forgotten after Window without failure, default is 24 hours.
+	OnEvent     func(ctx *Context, e AttemptEvent)
+}
+
+// NewBruteForce returns a new BruteForce with the default policy.
+func NewBruteForce(name string) *BruteForce {
+	return &BruteForce{
+		Name:        name,
+		Store:       NewMemoryAttemptStore(),
+		Threshold:   5,
+		BaseLockout: time.Minute,
+		MaxLockout:  time.Hour,
+		Window:      24 * time.Hour,
+	}
+}
+
+// Handler is the middleware rejecting the locked out client IPs, and enabling the ctx helpers AuthFailed, AuthSucceeded and AuthLocked.
+func (b *BruteForce) Handler(ctx *Context) {
+	ctx.Data["bruteforce"] = b
+	if ctx.AuthLocked() == true {
+		return
+	}
+	ctx.Next()
+}
+
+// Locked returns the remaining lockout of key, or 0.
+func (b *BruteForce) Locked(key string) (time.Duration, error) {
+	record, err := b.Store.Get(b.Name + ":" + key)
+	if err != nil {
+		return 0, err
+	}
+	if wait := time.Until(record.LockedUntil); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failure of key, and returns its new record.
+func (b *BruteForce) Fail(ctx *Context, key string) (AttemptRecord, error) {
+	var record AttemptRecord
+	failures, err := b.Store.Incr(b.Name+":"+key, b.Window)
+	if err != nil {
+		return record, err
+	}
+	record.Failures = failures
+	event := "failure"
+	if record.Failures >= b.Threshold {
+		lockout := b.BaseLockout << uint(record.Failures-b.Threshold)
+		if lockout > b.MaxLockout || lockout <= 0 {
+			lockout = b.MaxLockout
+		}
+		record.LockedUntil = time.Now().Add(lockout)
+		ttl := b.Window
+		if lockout > ttl {
+			ttl = lockout
+		}
+		if err := b.Store.Lock(b.Name+":"+key, record.LockedUntil, ttl); err != nil {
+			return record, err
+		}
+		event = "lockout"
+		Metrics.Add("bruteforce."+b.Name+".lockouts", 1)
+	}
+	b.emit(ctx, AttemptEvent{Type: event, Key: key, Failures: record.Failures, LockedUntil: record.LockedUntil})
+	return record, nil
+}
+
+// Reset forgets the failures of key.
+func (b *BruteForce) Reset(ctx *Context, key string) error {
+	if err := b.Store.Delete(b.Name + ":" + key); err != nil {
+		return err
+	}
+	b.emit(ctx, AttemptEvent{Type: "success", Key: key})
+	return nil
+}
+
+func (b *BruteForce) emit(ctx *Context, e AttemptEvent) {
+	if b.OnEvent != nil {
+		b.OnEvent(ctx, e)
+	}
+}
+
+// bruteForce returns the BruteForce of the request, and its tracked keys: the client IP then keys.
+func (ctx *Context) bruteForce(keys []string) (*BruteForce, []string) {
+	b, _ := ctx.Data["bruteforce"].(*BruteForce)
+	if b == nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context: BruteForce.Handler isn't used")
+	}
+	return b, append([]string{"ip:" + ctx.ClientIP()}, keys...)
+}
+
+// AuthLocked tells if the client IP or one of keys is locked out by the BruteForce of the route.
+// If so, the request fails with 429 Too Many Requests and a Retry-After header.
+func (ctx *Context) AuthLocked(keys ...string) bool {
+	b, keys := ctx.bruteForce(keys)
+	if b == nil {
+		return false
+	}
+	for _, key := range keys {
+		wait, err := b.Locked(key)
+		if err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("BruteForce: " + err.Error())
+			continue
+		}
+		if wait > 0 {
+			err := (&BusinessError{}).New(0, "too many failed attempts")
+			err.Details = &ErrorDetails{RetryAfter: int(wait/time.Second) + 1}
+			ctx.FailWithStatus(http.StatusTooManyRequests, err)
+			return true
+		}
+	}
+	return false
+}
+
+// AuthFailed records a failed authentication of the client IP and keys.
+func (ctx *Context) AuthFailed(keys ...string) {
+	b, keys := ctx.bruteForce(keys)
+	if b == nil {
+		return
+	}
+	for _, key := range keys {
+		if _, err := b.Fail(ctx, key); err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("BruteForce: " + err.Error())
+		}
+	}
+}
+
+// AuthSucceeded forgets the failed authentications of keys.
+// The failures of the client IP are kept until Window, so that an attacker can't reset them with its own account.
+func (ctx *Context) AuthSucceeded(keys ...string) {
+	b, _ := ctx.bruteForce(nil)
+	if b == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := b.Reset(ctx, key); err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("BruteForce: " + err.Error())
+		}
+	}
+}
+
+// MemoryAttemptStore is an AttemptStore in memory, local to the instance.
+type MemoryAttemptStore struct {
+	mu      sync.Mutex // Makes Incr and Lock atomic.
+	records *Cache
+}
+
+// NewMemoryAttemptStore returns a new MemoryAttemptStore.
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{records: NewCache("", 0, 0)}
+}
+
+// Get returns the record of key.
+func (s *MemoryAttemptStore) Get(key string) (AttemptRecord, error) {
+	record, _ := s.records.Get(key)
+	r, _ := record.(AttemptRecord)
+	return r, nil
+}
+
+// Incr increments the failures of key, kept for ttl or until the end of its lockout.
+func (s *MemoryAttemptStore) Incr(key string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, _ := s.records.Get(key)
+	r, _ := record.(AttemptRecord)
+	r.Failures++
+	if wait := time.Until(r.LockedUntil); wait > ttl {
+		ttl = wait
+	}
+	s.records.SetTTL(key, r, ttl)
+	return r.Failures, nil
+}
+
+// Lock locks key out until until, keeping its failures for ttl.
+func (s *MemoryAttemptStore) Lock(key string, until time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, _ := s.records.Get(key)
+	r, _ := record.(AttemptRecord)
+	r.LockedUntil = until
+	s.records.SetTTL(key, r, ttl)
+	return nil
+}
+
+// Delete deletes the record of key.
+func (s *MemoryAttemptStore) Delete(key string) error {
+	s.records.Delete(key)
+	return nil
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBruteForce(t *testing.T) {
+	bf := NewBruteForce("login")
+	bf.Threshold = 2
+	var events []string
+	bf.OnEvent = func(c *Context, e AttemptEvent) { events = append(events, e.Type+" "+e.Key) }
+	hs := NewHandlersStack()
+	hs.Use(bf.Handler)
+	hs.Use(func(c *Context) {
+		user := "user:" + c.Request.URL.Query().Get("user")
+		if c.AuthLocked(user) {
+			return
+		}
+		if c.Request.URL.Query().Get("password") != "good" {
+			c.AuthFailed(user)
+			c.Fail((&UnauthorizedError{}).New("invalid credentials"))
+			return
+		}
+		c.AuthSucceeded(user)
+		c.Ok(nil)
+	})
+	serve := func(query, ip string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/login?"+query, nil)
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, code := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if w := serve("user=bob&password=bad", "10.0.0.1"); w.Code != code {
+			t.Errorf("want %d, got %d", code, w.Code)
+		}
+	}
+	w := serve("user=bob&password=good", "10.0.0.2")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("locked user from another IP: want 429 with Retry-After, got %d", w.Code)
+	}
+	if w := serve("user=alice&password=good", "10.0.0.3"); w.Code != http.StatusOK {
+		t.Errorf("other user: want 200, got %d", w.Code)
+	}
+	if wait, _ := bf.Locked("user:bob"); wait <= 0 || wait > time.Minute {
+		t.Errorf("lockout: want up to 1 minute, got %v", wait)
+	}
+	if len(events) != 5 || events[3] != "lockout user:bob" || events[4] != "success user:alice" {
+		t.Errorf("events: got %v", events)
+	}
+}
+
+func TestBruteForceConcurrentFailures(t *testing.T) {
+	bf := NewBruteForce("concurrent")
+	bf.Threshold = 1000
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bf.Fail(nil, "user:bob")
+		}()
+	}
+	wg.Wait()
+	if record, _ := bf.Store.Get("concurrent:user:bob"); record.Failures != 50 {
+		t.Errorf("want 50 failures, got %d", record.Failures)
+	}
+	bf.Threshold = 51
+	if record, _ := bf.Fail(nil, "user:bob"); record.Failures != 51 || record.LockedUntil.IsZero() == true {
+		t.Errorf("lockout: got %+v", record)
+	}
+	if wait, _ := bf.Locked("user:bob"); wait <= 0 {
+		t.Errorf("want locked, got %v", wait)
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"expvar"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bulkhead caps the concurrent in-flight requests of the next handlers, so that one slow endpoint can't exhaust all the goroutines.
+// When MaxConcurrent requests are in flight, up to MaxQueue requests wait for QueueTimeout, the others are rejected
+// with Status and a Retry-After header. The slots are allocated by the first request from MaxConcurrent.
+//
+// Use it globally with core.Use(b.Handler), or per route with router.GET(path, b.Handler, handler).
+// The in-flight requests are published in Metrics under "bulkhead.<name>.inflight", the rejected ones under "bulkhead.<name>.rejected".
+type Bulkhead struct {
+	Name          string
+	MaxConcurrent int
+	MaxQueue      int
+	QueueTimeout  time.Duration // Default is 1s.
+	RetryAfter    int           // The Retry-After seconds of the rejected requests, default is 1.
+	Status        int           // The status of the rejected requests, default is 503 Service Unavailable, 429 is the other common choice.
+
+	once     sync.Once
+	sem      chan struct{}
+	queued   int32
+	inflight int32
+}
+
+// NewBulkhead returns a new Bulkhead allowing maxConcurrent in-flight requests and maxQueue waiting ones.
+func NewBulkhead(name string, maxConcurrent, maxQueue int) *Bulkhead {
+	b := &Bulkhead{
+		Name:          name,
+		MaxConcurrent: maxConcurrent,
+		MaxQueue:      maxQueue,
+		QueueTimeout:  time.Second,
+		RetryAfter:    1,
+		Status:        http.StatusServiceUnavailable,
+	}
+	Metrics.Set("bulkhead."+name+".inflight", expvar.Func(func() interface{} { return atomic.LoadInt32(&b.inflight) }))
+	return b
+}
+
+// Handler is the middleware limiting the next handlers.
+func (b *Bulkhead) Handler(ctx *Context) {
+	b.once.Do(func() { b.sem = make(chan struct{}, b.MaxConcurrent) })
+	if b.acquire(ctx) == false {
+		Metrics.Add("bulkhead."+b.Name+".rejected", 1)
+		status, retryAfter := b.Status, b.RetryAfter
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		if retryAfter <= 0 {
+			retryAfter = 1
+		}
+		err := (&ServerError{}).New(http.StatusText(status))
+		err.Details = &ErrorDetails{RetryAfter: retryAfter}
+		ctx.FailWithStatus(status, err)
+		return
+	}
+	defer b.release()
+	ctx.Next()
+}
+
+// acquire takes a slot, waiting in the queue if there is room.
+func (b *Bulkhead) acquire(ctx *Context) bool {
+	select {
+	case b.sem <- struct{}{}:
+		atomic.AddInt32(&b.inflight, 1)
+		return true
+	default:
+	}
+	if int(atomic.AddInt32(&b.queued, 1)) > b.MaxQueue {
+		atomic.AddInt32(&b.queued, -1)
+		return false
+	}
+	defer atomic.AddInt32(&b.queued, -1)
+	timeout := b.QueueTimeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case b.sem <- struct{}{}:
+		atomic.AddInt32(&b.inflight, 1)
+		return true
+	case <-t.C:
+		return false
+	case <-ctx.Request.Context().Done():
+		return false
+	}
+}
+
+func (b *Bulkhead) release() {
+	atomic.AddInt32(&b.inflight, -1)
+	<-b.sem
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	b := NewBulkhead("test", 1, 1)
+	b.QueueTimeout = 50 * time.Millisecond
+	b.Status = http.StatusTooManyRequests
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	canceled := false
+	hs := NewHandlersStack()
+	hs.Use(b.Handler)
+	hs.Use(func(c *Context) {
+		if c.Request.URL.Path == "/slow" {
+			entered <- struct{}{}
+			<-release
+		}
+		if c.Request.URL.Path == "/canceled" {
+			canceled = true
+		}
+		c.Ok(nil)
+	})
+	serve := func(c context.Context, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil).WithContext(c)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	done := make(chan int)
+	go func() { done <- serve(context.Background(), "/slow").Code }()
+	<-entered
+
+	// The slot is taken: the request waits in the queue, and times out.
+	start := time.Now()
+	w := serve(context.Background(), "/")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" || time.Since(start) < b.QueueTimeout {
+		t.Errorf("queue timeout: want 429 after %s, got %d %v in %s", b.QueueTimeout, w.Code, w.Header(), time.Since(start))
+	}
+
+	// The queue is full: the request is rejected at once.
+	queued := make(chan int)
+	go func() { queued <- serve(context.Background(), "/").Code }()
+	for i := 0; i < 100 && atomic.LoadInt32(&b.queued) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	start = time.Now()
+	if code := serve(context.Background(), "/").Code; code != http.StatusTooManyRequests || time.Since(start) >= b.QueueTimeout {
+		t.Errorf("full queue: want an immediate 429, got %d in %s", code, time.Since(start))
+	}
+	<-queued
+
+	// A canceled request leaves the queue, without response.
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	if serve(c, "/canceled"); canceled == true {
+		t.Error("canceled: want the request rejected")
+	}
+
+	// The queued request gets the slot once it is released.
+	go func() { queued <- serve(context.Background(), "/").Code }()
+	for i := 0; i < 100 && atomic.LoadInt32(&b.queued) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("slow: want 200, got %d", code)
+	}
+	if code := <-queued; code != http.StatusOK {
+		t.Errorf("queued: want 200 after the release, got %d", code)
+	}
+	if n := len(b.sem); n != 0 || atomic.LoadInt32(&b.inflight) != 0 {
+		t.Errorf("want the slots released, got %d in flight", n)
+	}
+}
+
+func TestBulkheadLiteral(t *testing.T) {
+	literal := &Bulkhead{Name: "literal", MaxConcurrent: 2}
+	changed := NewBulkhead("changed", 1, 0)
+	changed.MaxConcurrent = 2
+	for _, b := range []*Bulkhead{literal, changed} {
+		release := make(chan struct{})
+		entered := make(chan struct{}, 2)
+		hs := NewHandlersStack()
+		hs.Use(b.Handler)
+		hs.Use(func(c *Context) {
+			entered <- struct{}{}
+			<-release
+			c.Ok(nil)
+		})
+		codes := make(chan int, 3)
+		for i := 0; i < 2; i++ {
+			go func() {
+				w := httptest.NewRecorder()
+				hs.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+				codes <- w.Code
+			}()
+			<-entered
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+			t.Errorf("%s: want the third request rejected with 503, got %d %v", b.Name, w.Code, w.Header())
+		}
+		close(release)
+		for i := 0; i < 2; i++ {
+			if code := <-codes; code != http.StatusOK {
+				t.Errorf("%s: want the %d in-flight requests served, got %d", b.Name, b.MaxConcurrent, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+)
+
+// DefaultAssets is the bundle of the Asset helper of the context. It is set by the first Assets route.
+var DefaultAssets *Assets
+
+// Assets is a bundle of static files, typically an embed.FS, served at fingerprinted URLs for cache-busting:
+// "app.css" is served at /assets/app.9f2c61a0.css, cached forever, and at /assets/app.css, always revalidated.
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	assets, err := core.NewAssets(public, "/assets/")
+//	core.Routers.Assets(assets)
+type Assets struct {
+	fsys     fs.FS
+	prefix   string
+	manifest map[string]string // The fingerprinted names by name.
+	names    map[string]string // The names by fingerprinted name.
+}
+
+// NewAssets returns the bundle of the files of fsys, served under prefix. The files are fingerprinted with their content hash.
+func NewAssets(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		fsys:     fsys,
+		prefix:   "/" + strings.Trim(prefix, "/") + "/",
+		manifest: make(map[string]string),
+		names:    make(map[string]string),
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() == true {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		ext := path.Ext(name)
+		fingerprinted := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		a.manifest[name] = fingerprinted
+		a.names[fingerprinted] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Manifest returns the fingerprinted names of the files by name.
+func (a *Assets) Manifest() map[string]string {
+	return a.manifest
+}
+
+// URL returns the fingerprinted URL of the file name, or its plain URL if it isn't in the bundle.
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if fingerprinted, ok := a.manifest[name]; ok == true {
+		return a.prefix + fingerprinted
+	}
+	return a.prefix + name
+}
+
+// Templates parses the templates of the bundle matching patterns, with the "asset" function returning the URL of a file:
+//
+//	<link rel="stylesheet" href="{{asset "app.css"}}">
+func (a *Assets) Templates(patterns ...string) (*template.Template, error) {
+	return template.New("").Funcs(template.FuncMap{"asset": a.URL}).ParseFS(a.fsys, patterns...)
+}
+
+// Assets serves the files of the bundle a. It sets DefaultAssets if it isn't set.
+func (group *RouterGroup) Assets(a *Assets) *Route {
+	if DefaultAssets == nil {
+		DefaultAssets = a
+	}
+	return group.GET(a.prefix+"*filepath", a.serve)
+}
+
+// serve responds with the file of the request URL.
+func (a *Assets) serve(ctx *Context) {
+	name := strings.TrimPrefix(ctx.Param("filepath"), "/")
+	cacheControl := "no-cache"
+	if plain, ok := a.names[name]; ok == true {
+		name = plain
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	b, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		ctx.Fail((&NotFoundError{}).New("Asset not found"))
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ctx.ResponseWriter.Header().Set("Cache-Control", cacheControl)
+	ctx.Blob(contentType, b)
+}
+
+// Asset returns the fingerprinted URL of the file name of DefaultAssets.
+func (ctx *Context) Asset(name string) string {
+	if DefaultAssets == nil {
+		return name
+	}
+	return DefaultAssets.URL(name)
+}
+
+// Render responds with the status code and the template name of t executed with data.
+func (ctx *Context) Render(code int, t *template.Template, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		ctx.Fail((&ServerError{}).New(err.Error()))
+		return
+	}
+	ctx.HTML(code, buf.String())
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.css":    {Data: []byte("body{}")},
+		"index.html": {Data: []byte(`<link href="{{asset "app.css"}}">`)},
+	}
+	assets, err := NewAssets(fsys, "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := assets.URL("app.css")
+	if strings.HasPrefix(url, "/assets/app.") == false || strings.HasSuffix(url, ".css") == false || url == "/assets/app.css" {
+		t.Fatalf("URL: want a fingerprinted /assets/app.*.css, got %s", url)
+	}
+
+	engine := New()
+	engine.Assets(assets)
+	tmpl, err := assets.Templates("*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.GET("/", func(c *Context) { c.Render(http.StatusOK, tmpl, "index.html", nil) })
+
+	tests := []struct {
+		path, cacheControl string
+	}{
+		{url, "public, max-age=31536000, immutable"},
+		{"/assets/app.css", "no-cache"},
+	}
+	for _, tt := range tests {
+		w := serveRouter(engine, "GET", tt.path)
+		if w.Code != http.StatusOK || w.Body.String() != "body{}" {
+			t.Errorf("%s: want 200 body{}, got %d %s", tt.path, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: Cache-Control: want %q, got %q", tt.path, tt.cacheControl, got)
+		}
+		if got := w.Header().Get("Content-Type"); strings.HasPrefix(got, "text/css") == false {
+			t.Errorf("%s: Content-Type: got %q", tt.path, got)
+		}
+	}
+	if w := serveRouter(engine, "GET", "/"); w.Body.String() != `<link href="`+url+`">` {
+		t.Errorf("render: got %s", w.Body.String())
+	}
+	if w := serveRouter(engine, "GET", "/assets/missing.js"); w.Code != http.StatusNotFound {
+		t.Errorf("missing: want 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"container/list"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Cache is a concurrency-safe in-memory cache, with a TTL per entry and a least recently used eviction beyond its size.
+// It is the cache shared by the middleware and the memory stores, local to the instance.
+//
+//	users := core.NewCache("users", 10000, time.Minute)
+//	users.Set(id, user)
+//	v, ok := users.Get(id)
+//
+// A named cache publishes its size, hits, misses and evictions in Metrics under "cache.<name>".
+type Cache struct {
+	size int           // The max number of entries, 0 is no limit.
+	ttl  time.Duration // The default TTL of the entries, 0 is no expiry.
+
+	mu        sync.Mutex
+	ll        *list.List // The entries, the most recently used first.
+	items     map[string]*list.Element
+	lastSweep time.Time
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type cacheEntry struct {
+	key    string
+	value  interface{}
+	expiry time.Time // Zero if the entry doesn't expire.
+}
+
+// NewCache returns a new Cache of size entries at most, expiring after ttl by default. The zero size or ttl are no limit.
+func NewCache(name string, size int, ttl time.Duration) *Cache {
+	c := &Cache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+	if name != "" {
+		Metrics.Set("cache."+name, expvar.Func(c.stats))
+	}
+	return c
+}
+
+// stats returns the metrics of the cache.
+func (c *Cache) stats() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return map[string]int64{"size": int64(c.ll.Len()), "hits": c.hits, "misses": c.misses, "evictions": c.evictions}
+}
+
+// Get returns the value of key, and tells if it is cached.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if ok == false || c.expired(e, time.Now()) == true {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+// Set caches value for key with the default TTL.
+func (c *Cache) Set(key string, value interface{}) {
+	c.SetTTL(key, value, c.ttl)
+}
+
+// SetTTL caches value for key for ttl, 0 is no expiry.
+func (c *Cache) SetTTL(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	c.set(key, value, ttl, time.Now())
+	c.mu.Unlock()
+}
+
+// Add caches value for key for ttl only if key isn't cached, and tells if it was added.
+func (c *Cache) Add(key string, value interface{}, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if e, ok := c.items[key]; ok == true && c.expired(e, now) == false {
+		return false
+	}
+	c.set(key, value, ttl, now)
+	return true
+}
+
+// Delete removes key.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	if e, ok := c.items[key]; ok == true {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+}
+
+// Len returns the number of entries, including the expired ones not removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache) set(key string, value interface{}, ttl time.Duration, now time.Time) {
+	if now.Sub(c.lastSweep) > time.Minute {
+		c.sweep(now)
+	}
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = now.Add(ttl)
+	}
+	if e, ok := c.items[key]; ok == true {
+		entry := e.Value.(*cacheEntry)
+		entry.value, entry.expiry = value, expiry
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, value: value, expiry: expiry})
+	for c.size > 0 && c.ll.Len() > c.size {
+		c.evictions++
+		c.remove(c.ll.Back())
+	}
+}
+
+// expired tells if the entry e is expired, and removes it.
+func (c *Cache) expired(e *list.Element, now time.Time) bool {
+	expiry := e.Value.(*cacheEntry).expiry
+	if expiry.IsZero() == true || now.Before(expiry) {
+		return false
+	}
+	c.remove(e)
+	return true
+}
+
+// sweep removes the expired entries.
+func (c *Cache) sweep(now time.Time) {
+	c.lastSweep = now
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		c.expired(e, now)
+		e = next
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache("test", 2, 0)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3) // Evicts b, the least recently used.
+	if _, ok := c.Get("b"); ok == true {
+		t.Errorf("b: want evicted")
+	}
+	if v, ok := c.Get("a"); ok == false || v != 1 {
+		t.Errorf("a: want 1, got %v", v)
+	}
+
+	c.SetTTL("d", 4, time.Millisecond)
+	if c.Add("d", 5, time.Minute) == true {
+		t.Errorf("add d: want cached")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok := c.Get("d"); ok == true {
+		t.Errorf("d: want expired")
+	}
+	if c.Add("d", 5, time.Minute) == false {
+		t.Errorf("add expired d: want added")
+	}
+	if got := Metrics.Get("cache.test").String(); got != `{"evictions":2,"hits":2,"misses":2,"size":2}` {
+		t.Errorf("metrics: got %s", got)
+	}
+}
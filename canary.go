@@ -0,0 +1,87 @@
+package core
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// CanaryOptions tell which requests a Canary handler sends to the canary handler.
+type CanaryOptions struct {
+	// Header and HeaderValue send the requests with this header value to the canary, whatever the weight.
+	// HeaderValue is required with Header: the requests without the header don't match it.
+	Header      string
+	HeaderValue string
+
+	// Cookie and CookieValue send the requests with this cookie value to the canary, whatever the weight.
+	// CookieValue is required with Cookie.
+	Cookie      string
+	CookieValue string
+
+	// Weight is the percentage of the other requests sent to the canary, from 0 to 100.
+	Weight float64
+
+	// StickyKey returns the key of the client, hashed so that a client always gets the same handler.
+	// Default uses the session cookie, or the client IP. An empty key gets a random handler.
+	StickyKey func(ctx *Context) string
+}
+
+// Canary returns a handler splitting a route between the stable and canary handlers, by header or cookie match, or by weight.
+// The requests served by the canary have Context.Data["canary"] set to true.
+//
+//	router.GET("/users/:id", core.Canary(getUser, getUserV2, core.CanaryOptions{Weight: 5, Header: "X-Canary", HeaderValue: "1"}))
+func Canary(stable, canary RouterHandler, opts CanaryOptions) RouterHandler {
+	assert1(opts.Header == "" || opts.HeaderValue != "", "the canary Header "+opts.Header+" has no HeaderValue")
+	assert1(opts.Cookie == "" || opts.CookieValue != "", "the canary Cookie "+opts.Cookie+" has no CookieValue")
+	return func(ctx *Context) {
+		if opts.isCanary(ctx) {
+			ctx.Data["canary"] = true
+			canary(ctx)
+			return
+		}
+		stable(ctx)
+	}
+}
+
+func (opts CanaryOptions) isCanary(ctx *Context) bool {
+	if opts.Header != "" && ctx.Request.Header.Get(opts.Header) == opts.HeaderValue {
+		return true
+	}
+	if opts.Cookie != "" {
+		if c, err := ctx.Request.Cookie(opts.Cookie); err == nil && c.Value == opts.CookieValue {
+			return true
+		}
+	}
+	if opts.Weight <= 0 {
+		return false
+	}
+	if opts.Weight >= 100 {
+		return true
+	}
+	var key string
+	if opts.StickyKey != nil {
+		key = opts.StickyKey(ctx)
+	} else {
+		key = defaultStickyKey(ctx)
+	}
+	if key == "" {
+		return rand.Float64()*100 < opts.Weight
+	}
+	return float64(hashBucket(key, 10000))/100 < opts.Weight
+}
+
+// defaultStickyKey returns the session cookie value if any, or the client IP.
+func defaultStickyKey(ctx *Context) string {
+	if httpCookie.Name != "" {
+		if c, err := ctx.Request.Cookie(httpCookie.Name); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return ctx.ClientIP()
+}
+
+// hashBucket returns the bucket of key, in [0, n).
+func hashBucket(key string, n uint32) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32() % n
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCanary(t *testing.T) {
+	var canaried bool
+	stable := func(c *Context) { c.Text(http.StatusOK, "stable") }
+	canary := func(c *Context) {
+		canaried, _ = c.Data["canary"].(bool)
+		c.Text(http.StatusOK, "canary")
+	}
+	serve := func(h RouterHandler, setup func(r *http.Request)) string {
+		hs := NewHandlersStack()
+		hs.Use(h)
+		r, _ := http.NewRequest("GET", "/users/1", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		if setup != nil {
+			setup(r)
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	forced := Canary(stable, canary, CanaryOptions{Header: "X-Canary", HeaderValue: "1", Cookie: "canary", CookieValue: "yes"})
+	if got := serve(forced, nil); got != "stable" {
+		t.Errorf("without header: want stable, got %s", got)
+	}
+	if got := serve(forced, func(r *http.Request) { r.Header.Set("X-Canary", "1") }); got != "canary" || canaried == false {
+		t.Errorf("header: want canary, got %s %t", got, canaried)
+	}
+	if got := serve(forced, func(r *http.Request) { r.Header.Set("X-Canary", "0") }); got != "stable" {
+		t.Errorf("other header value: want stable, got %s", got)
+	}
+	if got := serve(forced, func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "canary", Value: "yes"}) }); got != "canary" {
+		t.Errorf("cookie: want canary, got %s", got)
+	}
+
+	weighted := Canary(stable, canary, CanaryOptions{Weight: 20, StickyKey: func(c *Context) string { return c.Request.Header.Get("X-User") }})
+	n := 0
+	for i := 0; i < 1000; i++ {
+		user := "user" + strconv.Itoa(i)
+		got := serve(weighted, func(r *http.Request) { r.Header.Set("X-User", user) })
+		if again := serve(weighted, func(r *http.Request) { r.Header.Set("X-User", user) }); again != got {
+			t.Fatalf("%s: want a sticky handler, got %s and %s", user, got, again)
+		}
+		if got == "canary" {
+			n++
+		}
+	}
+	if n < 150 || n > 250 {
+		t.Errorf("weight: want about 200 canaries in 1000, got %d", n)
+	}
+	if got := serve(Canary(stable, canary, CanaryOptions{Weight: 100}), nil); got != "canary" {
+		t.Errorf("weight 100: want canary, got %s", got)
+	}
+	if got := serve(Canary(stable, canary, CanaryOptions{}), nil); got != "stable" {
+		t.Errorf("weight 0: want stable, got %s", got)
+	}
+
+	for _, opts := range []CanaryOptions{{Header: "X-Canary"}, {Cookie: "canary"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%+v: want a panic without value", opts)
+				}
+			}()
+			Canary(stable, canary, opts)
+		}()
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChallengeCode is the application code of the fail responses of the requests without a solved challenge.
+const ChallengeCode = "challenge_required"
+
+// ChallengeHeader is the request header of the challenge token, for the API clients.
+var ChallengeHeader = "X-Challenge-Token"
+
+// ChallengeVerifier verifies the token of a solved anti-automation challenge, like a captcha.
+type ChallengeVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// SiteVerify is the ChallengeVerifier of the captcha services with a siteverify API: hCaptcha, reCAPTCHA and Turnstile.
+type SiteVerify struct {
+	URL      string
+	Secret   string
+	Field    string       // The form field of the token sent by the widget.
+	MinScore float64      // The minimum score of the services scoring the clients, like reCAPTCHA v3.
+	Client   *http.Client // Default has a 5s timeout.
+}
+
+// NewHCaptcha returns the SiteVerify of hCaptcha.
+func NewHCaptcha(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://api.hcaptcha.com/siteverify", Secret: secret, Field: "h-captcha-response"}
+}
+
+// NewReCAPTCHA returns the SiteVerify of reCAPTCHA. For reCAPTCHA v3, set its MinScore, 0.5 being the recommended one.
+func NewReCAPTCHA(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://www.google.com/recaptcha/api/siteverify", Secret: secret, Field: "g-recaptcha-response"}
+}
+
+// NewTurnstile returns the SiteVerify of Cloudflare Turnstile.
+func NewTurnstile(secret string) *SiteVerify {
+	return &SiteVerify{URL: "https://challenges.cloudflare.com/turnstile/v0/siteverify", Secret: secret, Field: "cf-turnstile-response"}
+}
+
+var challengeClient = &http.Client{Timeout: 5 * time.Second}
+
+// Verify verifies token with the siteverify API.
+func (v *SiteVerify) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {v.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequest("POST", v.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := v.Client
+	if client == nil {
+		client = challengeClient
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	var result struct {
+		Success bool     `json:"success"`
+		Score   *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	if result.Success == true && v.MinScore > 0 && result.Score != nil && *result.Score < v.MinScore {
+		return false, nil
+	}
+	return result.Success, nil
+}
+
+// Challenge returns the middleware requiring a solved challenge on the next handlers, verified by v.
+// The token is read from the ChallengeHeader, or from the form field of v if it is a SiteVerify.
+// The requests without a valid token fail with 403 Forbidden and the ChallengeCode code.
+//
+//	core.Routers.POST("/signup", core.Challenge(core.NewTurnstile(secret)), signup)
+func Challenge(v ChallengeVerifier) RouterHandler {
+	return func(ctx *Context) {
+		token := ctx.Request.Header.Get(ChallengeHeader)
+		if sv, ok := v.(*SiteVerify); token == "" && ok == true && sv.Field != "" {
+			token = ctx.Request.PostFormValue(sv.Field)
+		}
+		if token == "" {
+			ctx.FailWithCode(http.StatusForbidden, ChallengeCode, "challenge required")
+			return
+		}
+		valid, err := v.Verify(ctx.Request.Context(), token, ctx.ClientIP())
+		if err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Challenge: " + err.Error())
+			ctx.Fail((&ServerError{}).New("challenge verification failed"))
+			return
+		}
+		if valid == false {
+			ctx.FailWithCode(http.StatusForbidden, ChallengeCode, "challenge failed")
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestChallenge(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("secret") == "s3cret" && r.PostFormValue("response") == "solved" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false}`))
+	}))
+	defer api.Close()
+
+	v := NewTurnstile("s3cret")
+	v.URL = api.URL
+	hs := NewHandlersStack()
+	hs.Use(Challenge(v))
+	hs.Use(func(c *Context) { c.Ok(nil) })
+
+	for token, code := range map[string]int{"solved": http.StatusOK, "forged": http.StatusForbidden, "": http.StatusForbidden} {
+		form := url.Values{"cf-turnstile-response": {token}}
+		r, _ := http.NewRequest("POST", "/signup", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("token %q: want %d, got %d", token, code, w.Code)
+		}
+		if code == http.StatusForbidden && strings.Contains(w.Body.String(), `"code":"`+ChallengeCode+`"`) == false {
+			t.Errorf("token %q: want the %s code, got %s", token, ChallengeCode, w.Body.String())
+		}
+	}
+}
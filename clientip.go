@@ -0,0 +1,60 @@
+package core
+
+import (
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientIP returns the IP of the client, from the X-Forwarded-For and X-Real-Ip headers when TrustProxy is set
+// and the request comes from one of the TrustedProxies, or from the connection.
+// X-Forwarded-For is read from the right, the entries appended by the trusted proxies skipped.
+func (ctx *Context) ClientIP() string {
+	ctx.checkLive("Context.ClientIP")
+	host, _, err := net.SplitHostPort(strings.TrimSpace(ctx.Request.RemoteAddr))
+	if err != nil {
+		host = ctx.Request.RemoteAddr
+	}
+	if TrustProxy == false || trustedProxy(host) == false {
+		return host
+	}
+	if xff := ctx.Request.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		ip := host
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			ip = hop
+			if trustedProxy(hop) == false {
+				break
+			}
+		}
+		return ip
+	}
+	if ip := strings.TrimSpace(ctx.Request.Header.Get("X-Real-Ip")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return host
+}
+
+// trustedProxy tells if ip is in the TrustedProxies.
+func trustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range TrustedProxies {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.WithField("cidr", cidr).Warnln("TrustedProxies: invalid CIDR")
+			continue
+		}
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	defer func(trust bool) { TrustProxy = trust }(TrustProxy)
+	tests := []struct {
+		name   string
+		trust  bool
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"connection", false, "203.0.113.7:1234", "1.2.3.4", "", "203.0.113.7"},
+		{"untrusted peer", true, "203.0.113.7:1234", "1.2.3.4", "", "203.0.113.7"},
+		{"one proxy", true, "10.0.0.2:1234", "198.51.100.9", "", "198.51.100.9"},
+		{"spoofed left-most", true, "10.0.0.2:1234", "1.2.3.4, 198.51.100.9", "", "198.51.100.9"},
+		{"proxies chain", true, "10.0.0.2:1234", "1.2.3.4, 198.51.100.9, 10.0.0.3", "", "198.51.100.9"},
+		{"malformed hop", true, "10.0.0.2:1234", "198.51.100.9, junk, 10.0.0.3", "", "10.0.0.3"},
+		{"real ip", true, "127.0.0.1:1234", "", "198.51.100.9", "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		TrustProxy = tt.trust
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-Ip", tt.realIP)
+		}
+		if got := (&Context{Request: r}).ClientIP(); got != tt.want {
+			t.Errorf("%s: want %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the middleware compressing the responses with the encodings accepted by the client,
+// among the ones configured for their content type, in the server preference order.
+//
+//	c := core.NewCompression()
+//	c.Types["text/csv"] = []string{"gzip"}
+//	core.Use(c.Handler)
+//
+// The responses smaller than MinSize, already encoded, partial or without body aren't compressed.
+type Compression struct {
+	// Types maps the media types, or the type prefixes like "text/", to their encodings in preference order: "zstd" and "gzip".
+	// Default prefers zstd then gzip for JSON, JavaScript, XML, SVG, CSV and the text types.
+	Types map[string][]string
+
+	MinSize   int               // Default is 1KB.
+	GzipLevel int               // Default is gzip.DefaultCompression.
+	ZstdLevel zstd.EncoderLevel // Default is zstd.SpeedDefault.
+
+	gzipPool sync.Pool
+	zstdPool sync.Pool
+}
+
+// NewCompression returns a new Compression with the default types.
+func NewCompression() *Compression {
+	both := []string{"zstd", "gzip"}
+	return &Compression{
+		Types: map[string][]string{
+			"application/json":       both,
+			"application/javascript": both,
+			"application/xml":        both,
+			"image/svg+xml":          both,
+			"text/":                  both,
+		},
+		MinSize:   1024,
+		GzipLevel: gzip.DefaultCompression,
+		ZstdLevel: zstd.SpeedDefault,
+	}
+}
+
+// Handler compresses the responses of the next handlers.
+func (c *Compression) Handler(ctx *Context) {
+	accepted := ctx.Request.Header.Get("Accept-Encoding")
+	if accepted == "" || ctx.Request.Method == "HEAD" {
+		ctx.Next()
+		return
+	}
+	w := &compressWriter{c: c, ctx: ctx, ResponseWriter: ctx.ResponseWriter, accepted: accepted}
+	ctx.ResponseWriter = w
+	defer w.close()
+	ctx.Next()
+}
+
+// encoding returns the encoding of a response of contentType for a client accepting accepted, or an empty string.
+func (c *Compression) encoding(contentType, accepted string) string {
+	t, _, _ := mime.ParseMediaType(contentType)
+	encodings, ok := c.Types[t]
+	if ok == false {
+		if i := strings.Index(t, "/"); i >= 0 {
+			encodings = c.Types[t[:i+1]]
+		}
+	}
+	if len(encodings) == 0 {
+		return ""
+	}
+	return negotiate(accepted, encodings, matchToken)
+}
+
+// encoder returns a pooled encoder of encoding writing to w.
+func (c *Compression) encoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == "zstd" {
+		if e, ok := c.zstdPool.Get().(*zstd.Encoder); ok == true {
+			e.Reset(w)
+			return e
+		}
+		e, _ := zstd.NewWriter(w, zstd.WithEncoderLevel(c.ZstdLevel), zstd.WithEncoderConcurrency(1))
+		return e
+	}
+	if gz, ok := c.gzipPool.Get().(*gzip.Writer); ok == true {
+		gz.Reset(w)
+		return gz
+	}
+	gz, err := gzip.NewWriterLevel(w, c.GzipLevel)
+	if err != nil {
+		gz = gzip.NewWriter(w)
+	}
+	return gz
+}
+
+func (c *Compression) release(encoder io.WriteCloser) {
+	switch e := encoder.(type) {
+	case *zstd.Encoder:
+		c.zstdPool.Put(e)
+	case *gzip.Writer:
+		c.gzipPool.Put(e)
+	}
+}
+
+// compressWriter buffers the start of a response until MinSize, to decide if it is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *Compression
+	ctx      *Context
+	accepted string
+	status   int
+	buf      bytes.Buffer
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ctx.writer.record(code)
+}
+
+// Write buffers or compresses p. The size of the response is the one written by the handlers, not the encoded one.
+func (w *compressWriter) Write(p []byte) (n int, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ctx.writer.record(w.status)
+	w.uncounted(func() { n, err = w.write(p) })
+	w.ctx.writer.size += int64(n)
+	return n, err
+}
+
+func (w *compressWriter) write(p []byte) (int, error) {
+	if w.decided == false {
+		w.buf.Write(p)
+		if w.buf.Len() < w.c.MinSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// uncounted runs write, without counting its bytes in the size of the response: they are counted by Write.
+func (w *compressWriter) uncounted(write func()) {
+	size := w.ctx.writer.size
+	write()
+	w.ctx.writer.size = size
+}
+
+// Flush decides on the buffered start of the response, and flushes it to the client.
+func (w *compressWriter) Flush() {
+	w.uncounted(func() {
+		if w.decided == false {
+			w.decide()
+		}
+		if f, ok := w.encoder.(interface{ Flush() error }); ok == true {
+			f.Flush()
+		}
+	})
+	if f, ok := w.ResponseWriter.(http.Flusher); ok == true {
+		f.Flush()
+	}
+}
+
+// decide chooses the encoding of the response, writes its header, and the buffered start of its body.
+// It is called once MinSize is buffered, or on a flush of a streamed response, which is compressed whatever its size.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.ResponseWriter.Header()
+	encoding := w.c.encoding(h.Get("Content-Type"), w.accepted)
+	if encoding != "" && varies(h, "Accept-Encoding") == false {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if encoding != "" && h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" &&
+		w.status != http.StatusPartialContent && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		h.Set("Content-Encoding", encoding)
+		h.Del("Content-Length")
+		w.encoder = w.c.encoder(encoding, w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// close writes the rest of the response, and restores the ResponseWriter of the context.
+func (w *compressWriter) close() {
+	w.ctx.ResponseWriter = w.ResponseWriter
+	if w.status == 0 {
+		// Nothing has been written, like on a panic recovered later.
+		return
+	}
+	w.uncounted(func() {
+		if w.decided == false {
+			// The whole response is smaller than MinSize.
+			w.decided = true
+			w.ResponseWriter.WriteHeader(w.status)
+			w.ResponseWriter.Write(w.buf.Bytes())
+			return
+		}
+		if w.encoder != nil {
+			w.encoder.Close()
+			w.c.release(w.encoder)
+			w.encoder = nil
+		}
+	})
+}
+
+// varies tells if the Vary header h lists the request header name.
+func varies(h http.Header, name string) bool {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if f := strings.TrimSpace(field); f == "*" || strings.EqualFold(f, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
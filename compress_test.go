@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompression(t *testing.T) {
+	big := strings.Repeat("compressible ", 200)
+	c := NewCompression()
+	hs := NewHandlersStack()
+	hs.Use(c.Handler)
+	hs.Use(func(c *Context) {
+		switch c.Request.URL.Path {
+		case "/small":
+			c.Ok("tiny")
+		case "/png":
+			c.Blob("image/png", []byte(big))
+		default:
+			c.Ok(big)
+		}
+	})
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		path, accept, encoding string
+	}{
+		{"/big", "gzip, deflate, br, zstd", "zstd"},
+		{"/big", "gzip", "gzip"},
+		{"/big", "zstd;q=0, gzip", "gzip"},
+		{"/big", "br", ""},
+		{"/small", "gzip", ""},
+		{"/png", "gzip", ""},
+	}
+	for _, tt := range tests {
+		w := serve(tt.path, tt.accept)
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s %q: want encoding %q, got %q", tt.path, tt.accept, tt.encoding, got)
+			continue
+		}
+		var body []byte
+		switch tt.encoding {
+		case "gzip":
+			gz, _ := gzip.NewReader(w.Body)
+			body, _ = ioutil.ReadAll(gz)
+		case "zstd":
+			d, _ := zstd.NewReader(w.Body)
+			body, _ = ioutil.ReadAll(d)
+		default:
+			body = w.Body.Bytes()
+		}
+		if strings.Contains(string(body), "tiny") == false && strings.Contains(string(body), big) == false {
+			t.Errorf("%s %q: unexpected body %.40q", tt.path, tt.accept, body)
+		}
+	}
+}
+
+// TestCompressionStatus checks that the middleware behind Compression see the status and the size of the responses
+// when they are written, before the compression writes them.
+func TestCompressionStatus(t *testing.T) {
+	breaker := NewBreaker("compression")
+	breaker.MinRequests = 2
+	var out bytes.Buffer
+	hs := NewHandlersStack()
+	hs.Use(NewCompression().Handler)
+	hs.Use(NewAccessLog(&out, LogfmtLog).Handler)
+	hs.Use(breaker.Handler)
+	hs.Use(func(c *Context) {
+		if c.Request.URL.Path == "/big" {
+			c.Text(http.StatusOK, strings.Repeat("compressible ", 200))
+			return
+		}
+		c.Fail((&ServerError{}).New("down"))
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serve("/fail"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("want 500, got %d", w.Code)
+		}
+	}
+	if breaker.State() != BreakerOpen {
+		t.Errorf("breaker: want open after the 500s, got %s", breaker.State())
+	}
+	if strings.Contains(out.String(), "status=500") == false || strings.Contains(out.String(), "bytes=0") == true {
+		t.Errorf("access log: want the status and size of the small responses, got %s", out.String())
+	}
+
+	out.Reset()
+	breaker.close(time.Now())
+	w := serve("/big")
+	if w.Header().Get("Content-Encoding") != "gzip" || strings.Contains(out.String(), "status=200 bytes=2600") == false {
+		t.Errorf("access log: want the uncompressed size, got %v %s", w.Header(), out.String())
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding" {
+		t.Errorf("Vary: want one Accept-Encoding, got %q", vary)
+	}
+}
@@ -10,10 +10,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -24,6 +26,8 @@ import (
 // Context contains all the data needed during the serving flow, including the standard http.ResponseWriter and *http.Request.
 //
 // The Data field can be used to pass all kind of data through the handlers stack.
+//
+// A Context is reused by another request once the handlers stack returns: goroutines outliving the handler must use ctx.Copy().
 type Context struct {
 	ResponseWriter http.ResponseWriter
 	Request        *http.Request
@@ -33,6 +37,12 @@ type Context struct {
 	Params         Params                 // Path Value
 	Data           map[string]interface{} // Custom Data
 	BodyJSON       map[string]interface{} // body json data
+	writer         contextWriter          // Keeps the ResponseWriter wrapper, pooled with the context to avoid an allocation per request.
+	gone           bool                   // A flag to know if the client has gone away.
+	route          *Route                 // The route of the request, nil until it is routed.
+	start          time.Time              // The time the request entered the handlers stack.
+	copied         bool                   // A flag to know if the context is a copy, which can't write the response.
+	generation     uint32                 // Odd while serving a request, incremented atomically by the pool, for LeakCheck.
 }
 
 // ResFormat response data
@@ -41,8 +51,20 @@ type ResFormat struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message"`
 	Errno   int         `json:"errno"`
+	Code    string      `json:"code,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+	Path    string      `json:"path,omitempty"`
+	Page    *PageMeta   `json:"page,omitempty"`
 }
 
+var (
+	// FailPath adds the request path to the fail response, in the path field. Default is false, not to leak routing details.
+	FailPath bool
+
+	// FailMessageFormatter formats the message of the fail response. Default is nil, the message is the error message.
+	FailMessageFormatter func(ctx *Context, message string) string
+)
+
 // Redirect Redirect replies to the request with a redirect to url, which may be a path relative to the request path.
 func (ctx *Context) Redirect(url string, code int) {
 	http.Redirect(ctx.ResponseWriter, ctx.Request, url, code)
@@ -50,56 +72,114 @@ func (ctx *Context) Redirect(url string, code int) {
 
 // Ok Response json
 func (ctx *Context) Ok(data interface{}) {
+	ctx.ok(&ResFormat{Ok: true, Data: data})
+}
+
+// ok writes the success response res.
+func (ctx *Context) ok(res *ResFormat) {
+	ctx.checkLive("Context.Ok")
 	if ctx.written == true {
-		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.Success: request has been writed")
+		ctx.doubleWrite("Context.Ok")
 		return
 	}
 	ctx.written = true
+	ctx.intercept(res)
+	if tree, ok := ctx.Data["fields"].(fieldTree); ok == true {
+		res.Data = pruneFields(res.Data, tree)
+	}
+	if ctx.ClientGone() == true {
+		ctx.written = true
+		ctx.logGone()
+		return
+	}
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	b, _ := json.Marshal(&ResFormat{Ok: true, Data: data})
-	ctx.ResponseWriter.WriteHeader(http.StatusOK)
-	ctx.ResponseWriter.Write(b)
+	b, _ := json.Marshal(ctx.envelope(res))
+	ctx.write(http.StatusOK, b)
 }
 
+// ErrNil is the error of the fail responses of a nil error: they are 500 Internal Server Error.
+var ErrNil = errors.New(http.StatusText(http.StatusInternalServerError))
+
 // Fail Response fail
+//
+// The http status code is the one of err if it is an ICoreError, or wraps one, or is mapped with MapError or MapErrorType.
+// Otherwise it is 500 Internal Server Error. A nil err is a bug of the caller: it is logged with its location and fails with ErrNil.
 func (ctx *Context) Fail(err error) {
+	httpCode := http.StatusInternalServerError
+	if coreErr := mapError(err); coreErr != nil {
+		httpCode = coreErr.GetHTTPCode()
+		err = coreErr
+	}
+	ctx.fail(httpCode, "", err)
+}
+
+// FailWithStatus Response fail with the http status code httpCode, whatever the type of err.
+// The errno of err is kept if it is an ICoreError.
+func (ctx *Context) FailWithStatus(httpCode int, err error) {
+	ctx.fail(httpCode, "", err)
+}
+
+// FailWithCode Response fail with the http status code httpCode and the application code appCode.
+func (ctx *Context) FailWithCode(httpCode int, appCode string, msg string) {
+	ctx.fail(httpCode, appCode, errors.New(msg))
+}
+
+// fail writes the fail response.
+func (ctx *Context) fail(httpCode int, appCode string, err error) {
+	ctx.checkLive("Context.Fail")
 	if err == nil {
-		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.Fail: err is nil")
-		ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
-		ctx.ResponseWriter.Write(nil)
-		return
+		ctx.warn("nil_error", "Context.Fail: err is nil, by "+callerLocation())
+		httpCode, appCode, err = http.StatusInternalServerError, "", ErrNil
 	}
 
 	if ctx.written == true {
-		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.Fail: request has been writed")
+		ctx.doubleWrite("Context.Fail")
 		return
 	}
 
-	errno := 0
-	errCore, ok := err.(ICoreError)
-	if ok == true {
-		errno = errCore.GetErrno()
-	}
-	ctx.written = true
 	if Production == false {
 		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(err.Error())
 	} else if _, ok := err.(*ServerError); ok == true {
 		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(err.Error())
 	}
 
-	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	b, _ := json.Marshal(&ResFormat{Ok: false, Message: err.Error(), Errno: errno})
+	// The error is logged even if the client is gone, only the response is skipped.
+	if ctx.ClientGone() == true {
+		ctx.written = true
+		ctx.logGone()
+		return
+	}
 
-	coreErr, ok := err.(ICoreError)
+	errno := 0
+	errCore, ok := err.(ICoreError)
 	if ok == true {
-		ctx.ResponseWriter.WriteHeader(coreErr.GetHTTPCode())
-	} else {
-		ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		errno = errCore.GetErrno()
+	}
+	ctx.written = true
+
+	var details interface{}
+	if detailErr, ok := err.(IDetailError); ok == true {
+		details = detailErr.GetDetails()
+		if d, ok := details.(*ErrorDetails); ok == true && d != nil && d.RetryAfter > 0 {
+			ctx.ResponseWriter.Header().Set("Retry-After", strconv.Itoa(d.RetryAfter))
+		}
+	}
+
+	res := &ResFormat{Ok: false, Message: err.Error(), Errno: errno, Code: appCode, Details: details}
+	if FailMessageFormatter != nil {
+		res.Message = FailMessageFormatter(ctx, res.Message)
+	}
+	if FailPath == true {
+		res.Path = ctx.Request.URL.Path
 	}
-	ctx.ResponseWriter.Write(b)
+	ctx.intercept(res)
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, _ := json.Marshal(ctx.envelope(res))
+	ctx.write(httpCode, b)
 }
 
-//ZipHandler 响应下载文件请求，返回zip文件
+// ZipHandler 响应下载文件请求，返回zip文件
 func (ctx *Context) ZipHandler(fileName string, file []byte) {
 	zipName := fileName + ".zip"
 	rw := ctx.ResponseWriter
@@ -125,19 +205,18 @@ func (ctx *Context) ZipHandler(fileName string, file []byte) {
 // ResFree Response json
 func (ctx *Context) ResFree(data interface{}) {
 	if ctx.written == true {
-		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.Success: request has been writed")
+		ctx.doubleWrite("Context.ResFree")
 		return
 	}
-	ctx.written = true
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	b, _ := json.Marshal(data)
-	ctx.ResponseWriter.WriteHeader(http.StatusOK)
-	ctx.ResponseWriter.Write(b)
+	ctx.write(http.StatusOK, b)
 }
 
 // ResStatus Response status code, use http.StatusText to write the response.
 func (ctx *Context) ResStatus(code int) (int, error) {
 	if ctx.written == true {
+		ctx.doubleWrite("Context.ResStatus")
 		return 0, errors.New("Context.ResStatus: request has been writed")
 	}
 	ctx.written = true
@@ -145,6 +224,59 @@ func (ctx *Context) ResStatus(code int) (int, error) {
 	return fmt.Fprint(ctx.ResponseWriter, http.StatusText(code))
 }
 
+// Empty responds with the status code and no body, like 204 No Content, 201 Created with a Location header, or 304 Not Modified.
+func (ctx *Context) Empty(code int) {
+	if ctx.written == true {
+		ctx.doubleWrite("Context.Empty")
+		return
+	}
+	ctx.written = true
+	ctx.finalizeHeader(code, 0)
+	ctx.ResponseWriter.WriteHeader(code)
+}
+
+// HTML responds with the status code and the HTML markup.
+func (ctx *Context) HTML(code int, markup string) {
+	ctx.writeString(code, "text/html; charset=utf-8", markup)
+}
+
+// Text responds with the status code and the plain text s.
+func (ctx *Context) Text(code int, s string) {
+	ctx.writeString(code, "text/plain; charset=utf-8", s)
+}
+
+// writeString responds with the status code and s of the type contentType.
+func (ctx *Context) writeString(code int, contentType string, s string) {
+	ctx.checkLive("Context.HTML/Text")
+	if ctx.written == true {
+		ctx.doubleWrite("Context.HTML/Text")
+		return
+	}
+	ctx.ResponseWriter.Header().Set("Content-Type", contentType)
+	ctx.write(code, []byte(s))
+}
+
+// Header sets the response header key, or deletes it if value is empty.
+// It is a shortcut for ctx.ResponseWriter.Header().Set(key, value)
+func (ctx *Context) Header(key, value string) {
+	ctx.checkLive("Context.Header")
+	if value == "" {
+		ctx.ResponseWriter.Header().Del(key)
+		return
+	}
+	ctx.ResponseWriter.Header().Set(key, value)
+}
+
+// Status returns the status code of the response, or 0 if the header hasn't been written yet.
+func (ctx *Context) Status() int {
+	return ctx.writer.status
+}
+
+// Size returns the bytes of the response body written, after compression.
+func (ctx *Context) Size() int64 {
+	return ctx.writer.size
+}
+
 // Written tells if the response has been written.
 func (ctx *Context) Written() bool {
 	return ctx.written
@@ -152,6 +284,7 @@ func (ctx *Context) Written() bool {
 
 // Next calls the next handler in the stack, but only if the response isn't already written.
 func (ctx *Context) Next() {
+	ctx.checkLive("Context.Next")
 	// Call the next handler only if there is one and the response hasn't been written.
 	if !ctx.Written() && ctx.index < len(ctx.handlersStack.Handlers)-1 {
 		ctx.index++
@@ -161,11 +294,13 @@ func (ctx *Context) Next() {
 
 // Param returns the value of the URL param.
 // It is a shortcut for c.Params.ByName(key)
-//     router.GET("/user/:id", func(c *gin.Context) {
-//         // a GET request to /user/john
-//         id := c.Param("id") // id == "john"
-//     })
+//
+//	router.GET("/user/:id", func(c *gin.Context) {
+//	    // a GET request to /user/john
+//	    id := c.Param("id") // id == "john"
+//	})
 func (ctx *Context) Param(key string) string {
+	ctx.checkLive("Context.Param")
 	return ctx.Params.ByName(key)
 }
 
@@ -228,7 +363,7 @@ func (ctx *Context) SetSession(key string, values map[string]string) error {
 
 // FreshSession set session
 func (ctx *Context) FreshSession(key string) error {
-	err := provider.UpExpire(key)
+	err := provider.UpExpire(key, "")
 	if err != nil {
 		return err
 	}
@@ -238,6 +373,9 @@ func (ctx *Context) FreshSession(key string) error {
 // DeleteSession delete session
 func (ctx *Context) DeleteSession() error {
 	sid := ctx.Data["Sid"].(string)
+	if store, ok := ctx.Data["session"].(*redisStore); ok == true && store.Values["User"] != "" {
+		provider.Revoke(store.Values["User"], sid)
+	}
 	ctx.Data["session"] = nil
 	provider.Destroy(sid)
 	cookie := httpCookie
@@ -246,7 +384,7 @@ func (ctx *Context) DeleteSession() error {
 	return nil
 }
 
-//GetSid 获取sid
+// GetSid 获取sid
 func (ctx *Context) GetSid() string {
 	sid := ctx.Data["Sid"]
 	if sid == nil {
@@ -271,14 +409,21 @@ func (ctx *Context) genSid(key string) string {
 //	defer c.Recover()
 func (ctx *Context) Recover() {
 	if err := recover(); err != nil {
+		defer ctx.finish()
 		if e, ok := err.(*ValidationError); ok == true {
 			ctx.Fail(e)
 			return
 		}
+		if a, ok := err.(abort); ok == true {
+			if a.err != nil && !ctx.Written() {
+				ctx.Fail(a.err)
+			}
+			return
+		}
 
 		stack := make([]byte, 64<<10)
 		n := runtime.Stack(stack[:], false)
-		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Errorln(string(stack[:n]))
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path, "handler": ctx.HandlerName()}).Errorln(string(stack[:n]))
 		if !ctx.Written() {
 			ctx.ResponseWriter.Header().Del("Content-Type")
 
@@ -292,6 +437,15 @@ func (ctx *Context) Recover() {
 	}
 }
 
+// disablePoolEnv disables the context pool when set to a true value, e.g. CORE_DISABLE_POOL=1 go test -race ./...
+const disablePoolEnv = "CORE_DISABLE_POOL"
+
+// DisablePool allocates a Context per request instead of reusing the pooled ones, trading the allocations for safety:
+// a goroutine using a context after its request has ended still reads the data of that request.
+// It is for debugging memory corruptions, or for running the tests under the race detector.
+// Default is set by the CORE_DISABLE_POOL environment variable, or the -disable-pool command line param.
+var DisablePool, _ = strconv.ParseBool(os.Getenv(disablePoolEnv))
+
 // ctxPool
 var ctxPool = sync.Pool{
 	New: func() interface{} {
@@ -303,12 +457,24 @@ var ctxPool = sync.Pool{
 	},
 }
 
-func getContext(w http.ResponseWriter, r *http.Request) *Context {
-	ctx := ctxPool.Get().(*Context)
+func getContext(hs *HandlersStack, w http.ResponseWriter, r *http.Request) *Context {
+	var ctx *Context
+	if DisablePool == true {
+		ctx = ctxPool.New().(*Context)
+	} else {
+		ctx = ctxPool.Get().(*Context)
+	}
+	atomic.AddUint32(&ctx.generation, 1)
 	ctx.Request = r
-	ctx.ResponseWriter = contextWriter{w, ctx}
+	ctx.writer.ResponseWriter = w
+	ctx.writer.context = ctx
+	ctx.writer.status = 0
+	ctx.writer.size = 0
+	ctx.writer.responder = -1
+	ctx.start = time.Now()
+	ctx.ResponseWriter = &ctx.writer
 	ctx.Data = make(map[string]interface{})
-	ctx.handlersStack = *defaultHandlersStack
+	ctx.handlersStack = *hs
 	return ctx
 }
 
@@ -316,30 +482,68 @@ func putContext(ctx *Context) {
 	if ctx.Request.Body != nil {
 		ctx.Request.Body.Close()
 	}
+	if DisablePool == true && LeakCheck == false {
+		atomic.AddUint32(&ctx.generation, 1)
+		return
+	}
 	ctx.Data = nil
 	ctx.Params = nil
 	ctx.ResponseWriter = nil
+	ctx.writer.ResponseWriter = nil
 	ctx.Request = nil
 	ctx.index = -1
 	ctx.written = false
+	ctx.gone = false
+	ctx.route = nil
 	ctx.BodyJSON = nil
+	atomic.AddUint32(&ctx.generation, 1)
+	if LeakCheck == true {
+		ctx.poison()
+		return
+	}
 	ctxPool.Put(ctx)
 }
 
 // contextWriter represents a binder that catches a downstream response writing and sets the context's written flag on the first write.
 type contextWriter struct {
 	http.ResponseWriter
-	context *Context
+	context   *Context
+	status    int   // The status of the response, 0 until written.
+	size      int64 // The bytes of the response body written.
+	responder int   // The index of the handler writing the response first, -1 until written.
 }
 
 // Write sets the context's written flag before writing the response.
-func (w contextWriter) Write(p []byte) (int, error) {
-	w.context.written = true
-	return w.ResponseWriter.Write(p)
+func (w *contextWriter) Write(p []byte) (int, error) {
+	w.record(http.StatusOK)
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	if err != nil && isClientGone(err) == true {
+		w.context.gone = true
+	}
+	return n, err
 }
 
 // WriteHeader sets the context's written flag before writing the response header.
-func (w contextWriter) WriteHeader(code int) {
-	w.context.written = true
+func (w *contextWriter) WriteHeader(code int) {
+	w.record(code)
 	w.ResponseWriter.WriteHeader(code)
 }
+
+// record sets the context's written flag, and the status of the response and its responder if they are unset.
+// The writers deferring the header, like the compression, record it when the handler writes, so that the middleware see it.
+func (w *contextWriter) record(code int) {
+	w.context.written = true
+	if w.status == 0 {
+		w.status = code
+		w.responder = w.context.index
+	}
+}
+
+// Flush sends the buffered response to the client, if the underlying ResponseWriter is an http.Flusher.
+func (w *contextWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok == true {
+		w.context.written = true
+		f.Flush()
+	}
+}
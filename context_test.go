@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -78,3 +79,146 @@ func TestRecoverCustom(t *testing.T) {
 		t.Errorf("body: want %q, got %q", bodyWant, bodyGot)
 	}
 }
+
+func TestGet(t *testing.T) {
+	ctx := &Context{Data: map[string]interface{}{}}
+	SetTyped(ctx, "user", 42)
+	if v, ok := Get[int](ctx, "user"); ok == false || v != 42 {
+		t.Errorf("Get: want 42, got %v %v", v, ok)
+	}
+	if _, ok := Get[string](ctx, "user"); ok == true {
+		t.Error("Get: want false for another type")
+	}
+	if MustGet[int](ctx, "user") != 42 {
+		t.Error("MustGet: want 42")
+	}
+	defer func() {
+		if msg := recover(); msg != `core.MustGet: "user" is int, not string` {
+			t.Errorf("MustGet: want a type panic, got %v", msg)
+		}
+	}()
+	MustGet[string](ctx, "user")
+}
+
+func TestCopy(t *testing.T) {
+	done := make(chan *Context)
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.Params = Params{{Key: "id", Value: "42"}}
+		SetTyped(c, "user", "bob")
+		cp := c.Copy()
+		c.Ok(nil)
+		go func() { done <- cp }()
+	})
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	cp := <-done
+	if cp.Param("id") != "42" || MustGet[string](cp, "user") != "bob" || cp.Request.URL.Path != "/users/42" {
+		t.Errorf("copy: want the request snapshot, got %v %v %s", cp.Params, cp.Data, cp.Request.URL.Path)
+	}
+	defer func() {
+		if msg, _ := recover().(string); msg == "" {
+			t.Error("Ok: want a panic on a copied context")
+		}
+	}()
+	cp.Ok(nil)
+}
+
+func TestLeakCheck(t *testing.T) {
+	LeakCheck = true
+	defer func() { LeakCheck = false }()
+	var leaked *Context
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		leaked = c
+		c.Ok(nil)
+	})
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	if leaked.Request.URL.Path != "/released-context" || leaked.Request.Context().Err() == nil {
+		t.Errorf("request: want poisoned, got %s", leaked.Request.URL.Path)
+	}
+	defer func() {
+		if msg, _ := recover().(string); msg == "" || !strings.Contains(msg, "context_test.go:") {
+			t.Errorf("Param: want a panic with the call site, got %q", msg)
+		}
+	}()
+	leaked.Param("id")
+}
+
+func TestDisablePool(t *testing.T) {
+	DisablePool = true
+	defer func() { DisablePool = false }()
+	var contexts []*Context
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		SetTyped(c, "user", "bob")
+		contexts = append(contexts, c)
+		c.Ok(nil)
+	})
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "/users/42", nil)
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if contexts[0] == contexts[1] {
+		t.Error("contexts: want a context per request")
+	}
+	if v, _ := Get[string](contexts[0], "user"); v != "bob" || contexts[0].Request.URL.Path != "/users/42" {
+		t.Errorf("ended context: want its request data, got %q %v", v, contexts[0].Request.URL)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestOnFinish(t *testing.T) {
+	for _, panics := range []bool{false, true} {
+		var order []string
+		hs := NewHandlersStack()
+		hs.Use(func(c *Context) {
+			c.OnFinish(func() { order = append(order, "first") })
+			c.AddCloser(closerFunc(func() error { order = append(order, "closer"); return nil }))
+			c.OnFinish(func() { panic("cleanup") })
+			if panics == true {
+				panic("handler")
+			}
+			c.Ok(nil)
+		})
+		r, _ := http.NewRequest("GET", "/", nil)
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if strings.Join(order, ",") != "closer,first" {
+			t.Errorf("panics %t: want the callbacks in reverse order, got %v", panics, order)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(ua string) string {
+		var fp string
+		hs := NewHandlersStack()
+		hs.Use(func(c *Context) {
+			fp = c.Fingerprint()
+			c.Ok(nil)
+		})
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.7:4242"
+		r.Header.Set("User-Agent", ua)
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		return fp
+	}
+	a := fingerprint("curl/8.0")
+	if len(a) != 32 || fingerprint("curl/8.0") != a {
+		t.Errorf("fingerprint: want a stable 32 characters hash, got %q", a)
+	}
+	if fingerprint("Mozilla/5.0") == a {
+		t.Error("fingerprint: want another hash for another user agent")
+	}
+	FingerprintKey = []byte("secret")
+	defer func() { FingerprintKey = nil }()
+	if fingerprint("curl/8.0") == a {
+		t.Error("fingerprint: want another hash with a key")
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrCopiedContext is returned by the response writer of a copied context.
+var ErrCopiedContext = errors.New("core: a copied context can't write the response")
+
+// Copy returns a read-only snapshot of ctx: the request metadata, the params and the data, safe to use in a goroutine
+// after the handler returns, when ctx is back in the pool and reused by another request.
+// Its request has no body, and its request context is still canceled when the request ends, so that background work
+// must not depend on it. A copied context can't respond: its writes are reported as double writes, and panic when not in Production.
+//
+//	cp := ctx.Copy()
+//	go func() {
+//		audit(cp.Param("id"), cp.Principal())
+//	}()
+//	ctx.Ok(nil)
+func (ctx *Context) Copy() *Context {
+	ctx.checkLive("Context.Copy")
+	cp := &Context{
+		Request:       ctx.Request.Clone(ctx.Request.Context()),
+		index:         len(ctx.handlersStack.Handlers),
+		handlersStack: ctx.handlersStack,
+		written:       true,
+		copied:        true,
+		generation:    1,
+		Params:        append(Params(nil), ctx.Params...),
+		Data:          make(map[string]interface{}, len(ctx.Data)),
+		gone:          ctx.gone,
+		route:         ctx.route,
+		start:         ctx.start,
+	}
+	cp.Request.Body = http.NoBody
+	cp.ResponseWriter = copyWriter{}
+	for k, v := range ctx.Data {
+		cp.Data[k] = v
+	}
+	if ctx.BodyJSON != nil {
+		cp.BodyJSON = make(map[string]interface{}, len(ctx.BodyJSON))
+		for k, v := range ctx.BodyJSON {
+			cp.BodyJSON[k] = v
+		}
+	}
+	cp.writer.status = ctx.writer.status
+	cp.writer.size = ctx.writer.size
+	cp.writer.responder = ctx.writer.responder
+	return cp
+}
+
+// copiedWrite reports the write of the response by method on a copied context.
+func (ctx *Context) copiedWrite(method string) {
+	msg := method + ": copied context can't write, by " + callerLocation()
+	if Production == false {
+		panic(msg)
+	}
+	log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(msg)
+}
+
+// copyWriter is the response writer of a copied context, which discards the response.
+type copyWriter struct{}
+
+func (copyWriter) Header() http.Header { return http.Header{} }
+
+func (copyWriter) Write([]byte) (int, error) { return 0, ErrCopiedContext }
+
+func (copyWriter) WriteHeader(int) {}
@@ -0,0 +1,33 @@
+package core
+
+import "fmt"
+
+// Get returns the value of key in the data of ctx, if set and of type T.
+//
+//	user, ok := core.Get[*User](ctx, "user")
+func Get[T any](ctx *Context, key string) (T, bool) {
+	ctx.checkLive("core.Get")
+	v, ok := ctx.Data[key].(T)
+	return v, ok
+}
+
+// MustGet returns the value of key in the data of ctx, and panics if it is not set or not of type T.
+// It is for the values set by the middleware of the route, when a missing value is a programming error.
+func MustGet[T any](ctx *Context, key string) T {
+	ctx.checkLive("core.MustGet")
+	raw, set := ctx.Data[key]
+	if set == false {
+		panic(fmt.Sprintf("core.MustGet: %q is not set", key))
+	}
+	v, ok := raw.(T)
+	if ok == false {
+		panic(fmt.Sprintf("core.MustGet: %q is %T, not %T", key, raw, v))
+	}
+	return v
+}
+
+// SetTyped sets the value of key in the data of ctx, with its type checked at compile time against the readers of key.
+func SetTyped[T any](ctx *Context, key string, v T) {
+	ctx.checkLive("core.SetTyped")
+	ctx.Data[key] = v
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrBodyTooLarge is the error of reading a decompressed request body beyond its limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// Decompress returns the middleware accepting the request bodies compressed with gzip, deflate or zstd, as told by their Content-Encoding.
+// The body is decompressed transparently for the next handlers, up to maxSize bytes: reading beyond fails with ErrBodyTooLarge,
+// to prevent the zip bombs, and so do the zstd frames asking for a larger window.
+// The requests with another encoding fail with 415 Unsupported Media Type.
+//
+//	core.Routers.POST("/ingest", core.Decompress(64<<20), ingest)
+func Decompress(maxSize int64) RouterHandler {
+	return func(ctx *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(ctx.Request.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || ctx.Request.Body == nil {
+			ctx.Next()
+			return
+		}
+
+		var body io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(ctx.Request.Body)
+		case "deflate":
+			body, err = zlib.NewReader(ctx.Request.Body)
+		case "zstd":
+			// The window is allocated from the frame header, before any byte is decoded: it is bounded by maxSize too.
+			window := uint64(maxSize)
+			if window < zstd.MinWindowSize {
+				window = zstd.MinWindowSize
+			}
+			var d *zstd.Decoder
+			d, err = zstd.NewReader(ctx.Request.Body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true), zstd.WithDecoderMaxMemory(window))
+			if err == nil {
+				body = d.IOReadCloser()
+			}
+		default:
+			ctx.FailWithStatus(http.StatusUnsupportedMediaType, (&ValidationError{}).New("unsupported Content-Encoding "+encoding))
+			return
+		}
+		if err != nil {
+			ctx.Fail((&ValidationError{}).New("invalid compressed body: " + err.Error()))
+			return
+		}
+
+		ctx.Request.Body = &decompressedBody{ReadCloser: body, raw: ctx.Request.Body, remaining: maxSize}
+		ctx.Request.Header.Del("Content-Encoding")
+		ctx.Request.Header.Del("Content-Length")
+		ctx.Request.ContentLength = -1
+		ctx.Next()
+	}
+}
+
+// decompressedBody is a decompressed request body, limited to remaining bytes.
+type decompressedBody struct {
+	io.ReadCloser
+	raw       io.ReadCloser
+	remaining int64
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Reads one more byte to tell a body of exactly the limit from a larger one.
+		var one [1]byte
+		if n, _ := b.ReadCloser.Read(one[:]); n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if err == zstd.ErrWindowSizeExceeded || err == zstd.ErrDecoderSizeExceeded {
+		err = ErrBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.raw.Close()
+}
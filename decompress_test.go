@@ -0,0 +1,70 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompress(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(Decompress(16))
+	hs.Use(func(c *Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.FailWithStatus(http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		c.Ok(string(body))
+	})
+	serve := func(encoding string, body []byte) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/ingest", bytes.NewReader(body))
+		r.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+	gzipped := func(s string) []byte {
+		var b bytes.Buffer
+		gz := gzip.NewWriter(&b)
+		gz.Write([]byte(s))
+		gz.Close()
+		return b.Bytes()
+	}
+	enc, _ := zstd.NewWriter(nil)
+	zstded := enc.EncodeAll([]byte(`{"event":"click"}`[:16]), nil)
+	// A short body in a frame asking for a 64 MiB window, larger than the limit.
+	var wide bytes.Buffer
+	zw, _ := zstd.NewWriter(&wide, zstd.WithWindowSize(64<<20))
+	zw.Write([]byte("a"))
+	zw.Flush() // Sends the frame header before the size is known.
+	zw.Close()
+
+	tests := []struct {
+		encoding string
+		body     []byte
+		code     int
+		data     string
+	}{
+		{"gzip", gzipped(`{"a":1}`), http.StatusOK, `{"a":1}`},
+		{"zstd", zstded, http.StatusOK, `{"event":"click"`},
+		{"gzip", gzipped(strings.Repeat("a", 1<<20)), http.StatusRequestEntityTooLarge, ""},
+		{"zstd", wide.Bytes(), http.StatusRequestEntityTooLarge, ""},
+		{"br", []byte("x"), http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		w := serve(tt.encoding, tt.body)
+		if w.Code != tt.code {
+			t.Errorf("%s: want %d, got %d %s", tt.encoding, tt.code, w.Code, w.Body.String())
+		}
+		if tt.data != "" && strings.Contains(w.Body.String(), `"data":"`+strings.Replace(tt.data, `"`, `\"`, -1)+`"`) == false {
+			t.Errorf("%s: want data %s, got %s", tt.encoding, tt.data, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientGone tells if the client has gone away: its request has been canceled, or writing the response failed
+// with a broken pipe or a connection reset.
+// Handlers doing expensive work can check it to stop early, as nothing will be responded.
+func (ctx *Context) ClientGone() bool {
+	if ctx.gone == true {
+		return true
+	}
+	if ctx.Request.Context().Err() == context.Canceled {
+		ctx.gone = true
+	}
+	return ctx.gone
+}
+
+// write writes the response status code and body b, with its Content-Length.
+// When the client has gone away, nothing is written and it is counted in Metrics under "client_gone".
+func (ctx *Context) write(code int, b []byte) {
+	ctx.checkLive("Context.write")
+	ctx.written = true
+	if ctx.ClientGone() == true {
+		ctx.logGone()
+		return
+	}
+	ctx.finalizeHeader(code, int64(len(b)))
+	ctx.ResponseWriter.WriteHeader(code)
+	if _, err := ctx.ResponseWriter.Write(b); err != nil {
+		if ctx.gone == true {
+			ctx.logGone()
+			return
+		}
+		ctx.warn("write_error", err.Error())
+	}
+}
+
+// logGone logs and counts a response dropped because the client has gone away.
+func (ctx *Context) logGone() {
+	Metrics.Add("client_gone", 1)
+	if LogSampling != nil && LogSampling.Allow("client_gone") == false {
+		return
+	}
+	log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Debugln("Context: client has gone away")
+}
+
+// isClientGone tells if err is caused by the client going away.
+func isClientGone(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
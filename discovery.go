@@ -0,0 +1,52 @@
+package core
+
+// RouteDescription describes the routes of a path, for the discovery endpoint.
+type RouteDescription struct {
+	Path       string                `json:"path"`
+	Methods    []string              `json:"methods"`
+	Params     []string              `json:"params,omitempty"`
+	Scopes     map[string][]string   `json:"scopes,omitempty"`     // The scopes required by method.
+	Deprecated []string              `json:"deprecated,omitempty"` // The deprecated methods.
+	Routes     map[string]*RouteInfo `json:"-"`                    // The route of each method.
+}
+
+// Describe returns the registered routes grouped by path, in registration order.
+func (engine *Engine) Describe() []*RouteDescription {
+	var descriptions []*RouteDescription
+	byPath := map[string]*RouteDescription{}
+	for _, info := range engine.Routes() {
+		info := info
+		d, ok := byPath[info.Path]
+		if ok == false {
+			d = &RouteDescription{Path: info.Path, Params: info.Params, Routes: map[string]*RouteInfo{}}
+			byPath[info.Path] = d
+			descriptions = append(descriptions, d)
+		}
+		d.Methods = append(d.Methods, info.Method)
+		d.Routes[info.Method] = &info
+		if len(info.Scopes) > 0 {
+			if d.Scopes == nil {
+				d.Scopes = map[string][]string{}
+			}
+			d.Scopes[info.Method] = info.Scopes
+		}
+		if info.Deprecated == true {
+			d.Deprecated = append(d.Deprecated, info.Method)
+		}
+	}
+	return descriptions
+}
+
+// MountDiscovery mounts the discovery endpoint on the path of the router, usually /_routes, protected by the guards handlers
+// (at least one is required). It returns the routes grouped by path, with their methods, params, required scopes and deprecations,
+// for the internal tooling and the gateways.
+//
+//	core.MountDiscovery(core.Routers, "/_routes", internalOnly)
+func MountDiscovery(router *Engine, path string, guards ...RouterHandler) *Route {
+	assert1(len(guards) > 0, "the discovery endpoint must be protected by a guard handler")
+	handlers := append(RouterHandlerChain{}, guards...)
+	handlers = append(handlers, func(ctx *Context) {
+		ctx.Ok(router.Describe())
+	})
+	return router.GET(path, handlers...)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StrictWrites makes a second write of a response panic when not in Production, to find the offending handler at once.
+// In Production, the double writes are logged with the stack of the second writer.
+// The double writes are always counted in Metrics under "double_write".
+var StrictWrites bool
+
+// coreDir is the directory of the package sources, to skip the framework frames of the callers.
+var coreDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// doubleWrite reports the second write of the response by method.
+func (ctx *Context) doubleWrite(method string) {
+	Metrics.Add("double_write", 1)
+	if ctx.copied == true {
+		ctx.copiedWrite(method)
+		return
+	}
+	msg := method + ": request has been writed, by " + callerLocation()
+	if StrictWrites == true {
+		if Production == false {
+			panic(msg)
+		}
+		stack := make([]byte, 64<<10)
+		n := runtime.Stack(stack, false)
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(msg + "\n" + string(stack[:n]))
+		return
+	}
+	ctx.warn("double_write", msg)
+}
+
+// callerLocation returns the location of the first caller outside of the framework.
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if filepath.Dir(f.File) != coreDir || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s (%s:%d)", f.Function, filepath.Base(f.File), f.Line)
+		}
+		if more == false {
+			return "unknown caller"
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import "sync"
+
+// EnvelopeFormatter returns the value marshaled as the body of the success and fail responses, from their envelope res.
+type EnvelopeFormatter func(ctx *Context, res *ResFormat) interface{}
+
+// EnvelopeHeader is the request header selecting the envelope version.
+var EnvelopeHeader = "X-Envelope-Version"
+
+// EnvelopeVersion returns the envelope version of the request, by default its EnvelopeHeader.
+// It can be replaced to derive the version from the API version, the route or the client.
+var EnvelopeVersion = func(ctx *Context) string {
+	return ctx.Request.Header.Get(EnvelopeHeader)
+}
+
+var (
+	envelopesMu sync.RWMutex
+	envelopes   = map[string]EnvelopeFormatter{}
+)
+
+// RegisterEnvelope registers the formatter of the envelope version.
+// The requests without a registered version get the ResFormat envelope.
+// Once a formatter is registered, the responses vary by EnvelopeHeader.
+//
+//	core.RegisterEnvelope("legacy", core.LegacyEnvelope)
+func RegisterEnvelope(version string, formatter EnvelopeFormatter) {
+	envelopesMu.Lock()
+	envelopes[version] = formatter
+	envelopesMu.Unlock()
+}
+
+// LegacyEnvelope formats the envelope as {"success": ok, "result": data, "error": message, "code": errno}.
+func LegacyEnvelope(ctx *Context, res *ResFormat) interface{} {
+	legacy := map[string]interface{}{"success": res.Ok, "result": res.Data}
+	if res.Ok == false {
+		legacy["error"] = res.Message
+		legacy["code"] = res.Errno
+	}
+	return legacy
+}
+
+// envelope returns the body of the response from its envelope res, with the formatter of the request envelope version.
+func (ctx *Context) envelope(res *ResFormat) interface{} {
+	envelopesMu.RLock()
+	defer envelopesMu.RUnlock()
+	if len(envelopes) == 0 {
+		return res
+	}
+	ctx.ResponseWriter.Header().Add("Vary", EnvelopeHeader)
+	if formatter, ok := envelopes[EnvelopeVersion(ctx)]; ok == true {
+		return formatter(ctx, res)
+	}
+	return res
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// errorMapping maps the errors matching a value or a type to an http status code and a client message.
+type errorMapping struct {
+	target   error
+	typ      reflect.Type
+	httpCode int
+	message  string
+}
+
+var (
+	errorMappingsMu sync.RWMutex
+	errorMappings   []errorMapping
+)
+
+// MapError makes Fail respond with httpCode and message the errors matching target with errors.Is, including the wrapped ones.
+// If message is empty, the error message is kept.
+//
+//	core.MapError(sql.ErrNoRows, http.StatusNotFound, "not found")
+//
+// So domain packages can return their own errors without importing core.
+// Mappings are evaluated in the registration order.
+func MapError(target error, httpCode int, message string) {
+	errorMappingsMu.Lock()
+	errorMappings = append(errorMappings, errorMapping{target: target, httpCode: httpCode, message: message})
+	errorMappingsMu.Unlock()
+}
+
+// MapErrorType makes Fail respond with httpCode and message the errors of the type of target, found with errors.As.
+// If message is empty, the error message is kept.
+//
+//	core.MapErrorType((*os.PathError)(nil), http.StatusNotFound, "file not found")
+func MapErrorType(target error, httpCode int, message string) {
+	errorMappingsMu.Lock()
+	errorMappings = append(errorMappings, errorMapping{typ: reflect.TypeOf(target), httpCode: httpCode, message: message})
+	errorMappingsMu.Unlock()
+}
+
+// mapError returns the ICoreError of err: itself or the wrapped one, or the mapping of err.
+// It returns nil if err has no ICoreError nor mapping.
+func mapError(err error) ICoreError {
+	var coreErr ICoreError
+	if errors.As(err, &coreErr) == true {
+		return coreErr
+	}
+
+	errorMappingsMu.RLock()
+	defer errorMappingsMu.RUnlock()
+	for _, m := range errorMappings {
+		matched := err
+		if m.target != nil {
+			if errors.Is(err, m.target) == false {
+				continue
+			}
+		} else {
+			target := reflect.New(m.typ)
+			if errors.As(err, target.Interface()) == false {
+				continue
+			}
+			matched = target.Elem().Interface().(error)
+		}
+		message := m.message
+		if message == "" {
+			message = matched.Error()
+		}
+		return &coreError{HTTPCode: m.httpCode, Message: message}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"net/http"
+	"strings"
 )
 
 // ICoreError core error interface
@@ -11,11 +12,30 @@ type ICoreError interface {
 	GetErrno() int
 }
 
+// IDetailError is implemented by the errors carrying machine-readable details for the fail response.
+type IDetailError interface {
+	GetDetails() interface{}
+}
+
+// ErrorDetails is the common details payload of the fail response.
+type ErrorDetails struct {
+	Fields     []FieldError `json:"fields,omitempty"`     // Per-field validation errors.
+	RetryAfter int          `json:"retryAfter,omitempty"` // Seconds to wait before retrying, also sent as the Retry-After header.
+	Link       string       `json:"link,omitempty"`       // Documentation of the error.
+}
+
+// FieldError is the validation error of a field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // coreError core error define
 type coreError struct {
 	HTTPCode int
 	Errno    int
 	Message  string
+	Details  interface{}
 }
 
 // New http.StatusInternalServerError
@@ -41,6 +61,11 @@ func (e *coreError) GetErrno() int {
 	return e.Errno
 }
 
+// GetDetails get error Details
+func (e *coreError) GetDetails() interface{} {
+	return e.Details
+}
+
 // ServerError http.StatusInternalServerError
 type ServerError struct {
 	coreError
@@ -95,6 +120,17 @@ func (e *ValidationError) New(message string) *ValidationError {
 	return e
 }
 
+// NewFields ValidationError.New with the per-field errors as details, the message joins the field messages.
+func (e *ValidationError) NewFields(fields ...FieldError) *ValidationError {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Message
+	}
+	e.New(strings.Join(messages, "; "))
+	e.Details = &ErrorDetails{Fields: fields}
+	return e
+}
+
 // NotFoundError route not found.
 type NotFoundError struct {
 	coreError
@@ -107,3 +143,29 @@ func (e *NotFoundError) New(message string) *NotFoundError {
 	e.Message = message
 	return e
 }
+
+// UnauthorizedError http.StatusUnauthorized, the request isn't authenticated.
+type UnauthorizedError struct {
+	coreError
+}
+
+// New UnauthorizedError.New
+func (e *UnauthorizedError) New(message string) *UnauthorizedError {
+	e.HTTPCode = http.StatusUnauthorized
+	e.Errno = 0
+	e.Message = message
+	return e
+}
+
+// ForbiddenError http.StatusForbidden, the request is authenticated but not allowed.
+type ForbiddenError struct {
+	coreError
+}
+
+// New ForbiddenError.New
+func (e *ForbiddenError) New(message string) *ForbiddenError {
+	e.HTTPCode = http.StatusForbidden
+	e.Errno = 0
+	e.Message = message
+	return e
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Experiment is an A/B experiment, splitting the clients between variants.
+type Experiment struct {
+	Name     string
+	Variants []Variant
+
+	// Key returns the key of the client bucketed in a variant, for example the user ID.
+	// Default uses the session ID, or the client IP.
+	Key func(ctx *Context) string
+
+	// CookieMaxAge is the lifetime of the cookie persisting the variant, default is 30 days.
+	CookieMaxAge time.Duration
+}
+
+// Variant is a variant of an Experiment, with its relative weight.
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+var (
+	experimentsMu sync.RWMutex
+	experiments   = map[string]*Experiment{}
+)
+
+// RegisterExperiment registers the experiment e, to be used with Context.Experiment.
+func RegisterExperiment(e *Experiment) {
+	assert1(len(e.Variants) > 0, "the experiment "+e.Name+" has no variants")
+	experimentsMu.Lock()
+	experiments[e.Name] = e
+	experimentsMu.Unlock()
+}
+
+// Experiment returns the variant of the experiment name for the request, or "" if the experiment isn't registered.
+// The variant is deterministic for a client key, persisted in the "exp_<name>" cookie,
+// kept in Context.Data["experiments"] for logging, and counted in Metrics under "experiment.<name>.<variant>".
+func (ctx *Context) Experiment(name string) string {
+	assigned, _ := ctx.Data["experiments"].(map[string]string)
+	if v, ok := assigned[name]; ok == true {
+		return v
+	}
+	experimentsMu.RLock()
+	e := experiments[name]
+	experimentsMu.RUnlock()
+	if e == nil {
+		return ""
+	}
+
+	cookieName := "exp_" + name
+	variant := ""
+	if c, err := ctx.Request.Cookie(cookieName); err == nil && e.hasVariant(c.Value) {
+		variant = c.Value
+	} else {
+		key := ""
+		if e.Key != nil {
+			key = e.Key(ctx)
+		} else if sid := ctx.GetSid(); sid != "" {
+			key = sid
+		} else {
+			key = ctx.ClientIP()
+		}
+		variant = e.bucket(key)
+		maxAge := e.CookieMaxAge
+		if maxAge == 0 {
+			maxAge = 30 * 24 * time.Hour
+		}
+		http.SetCookie(ctx.ResponseWriter, &http.Cookie{Name: cookieName, Value: variant, Path: "/", MaxAge: int(maxAge.Seconds()), HttpOnly: true})
+	}
+
+	if assigned == nil {
+		assigned = make(map[string]string)
+		ctx.Data["experiments"] = assigned
+	}
+	assigned[name] = variant
+	Metrics.Add("experiment."+name+"."+variant, 1)
+	return variant
+}
+
+// bucket returns the variant of key, by weight.
+func (e *Experiment) bucket(key string) string {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return e.Variants[0].Name
+	}
+	n := int(hashBucket(e.Name+":"+key, uint32(total)))
+	for _, v := range e.Variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1].Name
+}
+
+func (e *Experiment) hasVariant(name string) bool {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
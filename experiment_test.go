@@ -0,0 +1,99 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestExperiment(t *testing.T) {
+	RegisterExperiment(&Experiment{
+		Name:     "checkout",
+		Variants: []Variant{{"control", 3}, {"onepage", 1}},
+		Key:      func(ctx *Context) string { return ctx.Request.Header.Get("X-User") },
+	})
+	defer func() {
+		experimentsMu.Lock()
+		delete(experiments, "checkout")
+		experimentsMu.Unlock()
+	}()
+
+	var variant string
+	var data map[string]string
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		variant = c.Experiment("checkout")
+		if again := c.Experiment("checkout"); again != variant {
+			t.Errorf("want the same variant in a request, got %s and %s", variant, again)
+		}
+		data, _ = c.Data["experiments"].(map[string]string)
+		if c.Experiment("unknown") != "" {
+			t.Error("unknown experiment: want no variant")
+		}
+		c.Ok(nil)
+	})
+	serve := func(user string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", user)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		serve("user"+strconv.Itoa(i), nil)
+		counts[variant]++
+	}
+	if len(counts) != 2 || counts["onepage"] < 150 || counts["onepage"] > 350 {
+		t.Errorf("want about a quarter of onepage, got %v", counts)
+	}
+
+	w := serve("bob", nil)
+	first := variant
+	if data["checkout"] != first {
+		t.Errorf("Data: want %s, got %v", first, data)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "exp_checkout" || cookies[0].Value != first || cookies[0].MaxAge != 30*24*3600 {
+		t.Fatalf("want one exp_checkout cookie, got %v", cookies)
+	}
+	if serve("bob", nil); variant != first {
+		t.Errorf("want the variant deterministic for a key, got %s and %s", first, variant)
+	}
+
+	other := "control"
+	if first == "control" {
+		other = "onepage"
+	}
+	w = serve("bob", &http.Cookie{Name: "exp_checkout", Value: other})
+	if variant != other || len(w.Result().Cookies()) != 0 {
+		t.Errorf("cookie: want the variant %s of the cookie, got %s", other, variant)
+	}
+	if serve("bob", &http.Cookie{Name: "exp_checkout", Value: "removed"}); variant != first {
+		t.Errorf("unknown cookie variant: want %s, got %s", first, variant)
+	}
+}
+
+func TestExperimentBucket(t *testing.T) {
+	e := &Experiment{Name: "e", Variants: []Variant{{"a", 0}, {"b", 0}}}
+	if got := e.bucket("x"); got != "a" {
+		t.Errorf("no weights: want the first variant, got %s", got)
+	}
+	e.Variants = []Variant{{"a", 0}, {"b", 1}}
+	for i := 0; i < 10; i++ {
+		if got := e.bucket(strconv.Itoa(i)); got != "b" {
+			t.Errorf("zero weight: want b, got %s", got)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterExperiment without variants: want a panic")
+		}
+	}()
+	RegisterExperiment(&Experiment{Name: "empty"})
+}
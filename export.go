@@ -0,0 +1,132 @@
+package core
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Content types of the exports.
+const (
+	CSVContentType  = "text/csv; charset=utf-8"
+	XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ExportFlushRows is the number of rows written between two flushes of an export, so that the client receives it progressively.
+var ExportFlushRows = 1000
+
+// RowIterator returns the next row of an export, or the io.EOF error after the last one.
+type RowIterator func() ([]string, error)
+
+// SheetWriter writes the rows of an export in a file format. Close completes the file, but doesn't close the underlying writer.
+// An xlsx adapter can implement it with the streaming writer of an xlsx library.
+type SheetWriter interface {
+	WriteRow(row []string) error
+	Close() error
+}
+
+// csvWriter is the SheetWriter of the CSV format.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) WriteRow(row []string) error {
+	return w.w.Write(row)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// CSV streams the CSV export filename, with the headers row then the rows of next.
+// The rows are quoted as needed, and the export is compressed with gzip when the client accepts it.
+//
+//	ctx.CSV("users.csv", []string{"id", "name"}, func() ([]string, error) {
+//		if !rows.Next() {
+//			return nil, io.EOF
+//		}
+//		var id, name string
+//		err := rows.Scan(&id, &name)
+//		return []string{id, name}, err
+//	})
+func (ctx *Context) CSV(filename string, headers []string, next RowIterator) {
+	ctx.Export(CSVContentType, filename, func(w io.Writer) SheetWriter { return &csvWriter{w: csv.NewWriter(w)} }, headers, next)
+}
+
+// Export streams the export filename of the type contentType, with the headers row then the rows of next, written by the SheetWriter of newWriter.
+// It doesn't build the whole file in memory: the rows are flushed to the client every ExportFlushRows.
+// As the status is already sent, an error of next ends the export early, and is logged.
+func (ctx *Context) Export(contentType, filename string, newWriter func(io.Writer) SheetWriter, headers []string, next RowIterator) {
+	if ctx.written == true {
+		ctx.doubleWrite("Context.Export")
+		return
+	}
+	ctx.written = true
+	if ctx.ClientGone() == true {
+		ctx.logGone()
+		return
+	}
+
+	h := ctx.ResponseWriter.Header()
+	h.Set("Content-Type", contentType)
+	if filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	var out io.Writer = ctx.ResponseWriter
+	flush := ctx.writer.Flush
+	if contentType == CSVContentType && strings.Contains(ctx.Request.Header.Get("Accept-Encoding"), "gzip") {
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		gz := gzip.NewWriter(ctx.ResponseWriter)
+		defer gz.Close()
+		out = gz
+		flush = func() {
+			gz.Flush()
+			ctx.writer.Flush()
+		}
+	}
+	ctx.finalizeHeader(http.StatusOK, -1)
+	ctx.ResponseWriter.WriteHeader(http.StatusOK)
+
+	sw := newWriter(out)
+	err := ctx.exportRows(sw, headers, next, flush)
+	if closeErr := sw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil && ctx.ClientGone() == false {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.Export: " + err.Error())
+	}
+}
+
+// exportRows writes the headers and the rows of next with sw, calling flush every ExportFlushRows.
+func (ctx *Context) exportRows(sw SheetWriter, headers []string, next RowIterator, flush func()) error {
+	if len(headers) > 0 {
+		if err := sw.WriteRow(headers); err != nil {
+			return err
+		}
+	}
+	for n := 1; ; n++ {
+		row, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := sw.WriteRow(row); err != nil {
+			return err
+		}
+		if ExportFlushRows > 0 && n%ExportFlushRows == 0 {
+			if ctx.ClientGone() == true {
+				return nil
+			}
+			flush()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	rows := [][]string{{"1", "Smith, John"}, {"2", `say "hi"`}}
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		i := 0
+		c.CSV("users.csv", []string{"id", "name"}, func() ([]string, error) {
+			if i == len(rows) {
+				return nil, io.EOF
+			}
+			i++
+			return rows[i-1], nil
+		})
+	})
+	want := "id,name\n1,\"Smith, John\"\n2,\"say \"\"hi\"\"\"\n"
+
+	for _, encoding := range []string{"", "gzip"} {
+		r, _ := http.NewRequest("GET", "/users.csv", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Disposition"); got != `attachment; filename=users.csv` {
+			t.Errorf("Content-Disposition: got %q", got)
+		}
+		var body io.Reader = w.Body
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gz
+		}
+		got, _ := ioutil.ReadAll(body)
+		if string(got) != want {
+			t.Errorf("encoding %q: want %q, got %q", encoding, want, got)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func serveFail(t *testing.T, h RouterHandler) (*httptest.ResponseRecorder, map[string]interface{}) {
+	hs := NewHandlersStack()
+	hs.Use(h)
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+func TestFailWithStatus(t *testing.T) {
+	w, body := serveFail(t, func(c *Context) {
+		c.FailWithStatus(http.StatusConflict, (&BusinessError{}).New(1001, "already exists"))
+	})
+	if w.Code != http.StatusConflict {
+		t.Errorf("status code: want %d, got %d", http.StatusConflict, w.Code)
+	}
+	if body["errno"] != float64(1001) {
+		t.Errorf("errno: want 1001, got %v", body["errno"])
+	}
+	if body["message"] != "already exists" {
+		t.Errorf("message: want %q, got %v", "already exists", body["message"])
+	}
+}
+
+func TestFailWithCode(t *testing.T) {
+	w, body := serveFail(t, func(c *Context) {
+		c.FailWithCode(http.StatusUnprocessableEntity, "user.invalid_email", "invalid email")
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status code: want %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	if body["code"] != "user.invalid_email" {
+		t.Errorf("code: want %q, got %v", "user.invalid_email", body["code"])
+	}
+}
+
+func TestFailDetails(t *testing.T) {
+	w, body := serveFail(t, func(c *Context) {
+		err := (&ValidationError{}).NewFields(FieldError{"email", "invalid email"}, FieldError{"age", "must be positive"})
+		err.Details.(*ErrorDetails).RetryAfter = 30
+		c.Fail(err)
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status code: want %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After: want %q, got %q", "30", got)
+	}
+	details, _ := body["details"].(map[string]interface{})
+	fields, _ := details["fields"].([]interface{})
+	if len(fields) != 2 {
+		t.Fatalf("details fields: want 2, got %v", body["details"])
+	}
+	if field := fields[0].(map[string]interface{})["field"]; field != "email" {
+		t.Errorf("first field: want %q, got %v", "email", field)
+	}
+	if body["message"] != "invalid email; must be positive" {
+		t.Errorf("message: want joined field messages, got %v", body["message"])
+	}
+}
+
+func TestFailPath(t *testing.T) {
+	_, body := serveFail(t, func(c *Context) { c.Fail((&ServerError{}).New("boom")) })
+	if _, ok := body["path"]; ok {
+		t.Errorf("path: want none by default, got %v", body["path"])
+	}
+
+	FailPath = true
+	FailMessageFormatter = func(c *Context, message string) string { return c.Request.URL.Path + ": " + message }
+	defer func() {
+		FailPath = false
+		FailMessageFormatter = nil
+	}()
+	_, body = serveFail(t, func(c *Context) { c.Fail((&ServerError{}).New("boom")) })
+	if body["path"] != "/users/42" {
+		t.Errorf("path: want %q, got %v", "/users/42", body["path"])
+	}
+	if body["message"] != "/users/42: boom" {
+		t.Errorf("message: want %q, got %v", "/users/42: boom", body["message"])
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	var gone bool
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		gone = c.ClientGone()
+		c.Fail((&ServerError{}).New("too late"))
+	})
+	rctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	w := httptest.NewRecorder()
+	var logs bytes.Buffer
+	defer log.SetOutput(log.StandardLogger().Out)
+	log.SetOutput(&logs)
+	hs.ServeHTTP(w, r.WithContext(rctx))
+
+	if gone == false {
+		t.Error("ClientGone: want true for a canceled request")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body: want empty, got %q", w.Body.String())
+	}
+	if strings.Contains(logs.String(), "too late") == false {
+		t.Errorf("log: want the server error logged, got %q", logs.String())
+	}
+	if !isClientGone(fmt.Errorf("write: %w", syscall.EPIPE)) {
+		t.Error("isClientGone: want true for a broken pipe")
+	}
+}
+
+func TestAbort(t *testing.T) {
+	find := func() {
+		Must((&NotFoundError{}).New("user not found"))
+		t.Error("Must: want an abort")
+	}
+	w, body := serveFail(t, func(c *Context) {
+		find()
+	})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status code: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if body["message"] != "user not found" {
+		t.Errorf("message: want %q, got %v", "user not found", body["message"])
+	}
+}
+
+type quotaError struct{ user string }
+
+func (e *quotaError) Error() string { return "quota exceeded for " + e.user }
+
+func TestMapError(t *testing.T) {
+	errGone := errors.New("gone")
+	MapError(errGone, http.StatusGone, "resource removed")
+	MapErrorType((*quotaError)(nil), http.StatusTooManyRequests, "")
+	defer func() { errorMappings = nil }()
+
+	tests := []struct {
+		err     error
+		code    int
+		message string
+	}{
+		{fmt.Errorf("load: %w", errGone), http.StatusGone, "resource removed"},
+		{fmt.Errorf("save: %w", &quotaError{user: "bob"}), http.StatusTooManyRequests, "quota exceeded for bob"},
+		{fmt.Errorf("find: %w", (&NotFoundError{}).New("no user")), http.StatusNotFound, "no user"},
+		{errors.New("other"), http.StatusInternalServerError, "other"},
+	}
+	for _, tt := range tests {
+		w, body := serveFail(t, func(c *Context) {
+			c.Fail(tt.err)
+		})
+		if w.Code != tt.code {
+			t.Errorf("%v: status code: want %d, got %d", tt.err, tt.code, w.Code)
+		}
+		if body["message"] != tt.message {
+			t.Errorf("%v: message: want %q, got %v", tt.err, tt.message, body["message"])
+		}
+	}
+}
+
+func TestLegacyEnvelope(t *testing.T) {
+	RegisterEnvelope("legacy", LegacyEnvelope)
+	defer func() { envelopes = map[string]EnvelopeFormatter{} }()
+
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) { c.Fail((&BusinessError{}).New(1001, "already exists")) })
+	for version, want := range map[string]string{
+		"legacy": `{"code":1001,"error":"already exists","result":null,"success":false}`,
+		"":       `{"ok":false,"data":null,"message":"already exists","errno":1001}`,
+	} {
+		r, _ := http.NewRequest("GET", "/users/42", nil)
+		r.Header.Set(EnvelopeHeader, version)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Body.String() != want {
+			t.Errorf("version %q: want %s, got %s", version, want, w.Body.String())
+		}
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.Header("Content-Length", "1")
+		c.Header("Transfer-Encoding", "chunked")
+		c.Fail((&NotFoundError{}).New("no user"))
+	})
+	r, _ := http.NewRequest("GET", "/users/42", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	if got, want := w.Header().Get("Content-Length"), fmt.Sprint(w.Body.Len()); got != want {
+		t.Errorf("Content-Length: want %s, got %s", want, got)
+	}
+	if got := w.Header().Get("Transfer-Encoding"); got != "" {
+		t.Errorf("Transfer-Encoding: want none, got %q", got)
+	}
+}
+
+func TestLogSampler(t *testing.T) {
+	s := NewLogSampler(2, 3, time.Minute)
+	var logged int
+	for i := 0; i < 10; i++ {
+		if s.Allow("double_write") == true {
+			logged++
+		}
+	}
+	// The first 2, then the 5th and the 8th.
+	if logged != 4 {
+		t.Errorf("logged: want 4, got %d", logged)
+	}
+	if got := logDropped.Get("double_write"); got == nil || got.String() != "6" {
+		t.Errorf("dropped: want 6, got %v", got)
+	}
+	if s.Allow("write_error") == false {
+		t.Errorf("other key: want logged")
+	}
+}
+
+func TestStrictWrites(t *testing.T) {
+	StrictWrites = true
+	defer func() { StrictWrites = false }()
+	var msg interface{}
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.Ok(nil)
+		defer func() { msg = recover() }()
+		c.Ok(nil)
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	s, _ := msg.(string)
+	if strings.HasPrefix(s, "Context.Ok: request has been writed, by ") == false || strings.Contains(s, "fail_test.go:") == false {
+		t.Errorf("panic: want the second writer location, got %v", msg)
+	}
+}
+
+func TestFailNil(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) { c.FailWithStatus(http.StatusNotFound, nil) })
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+
+	want := `{"ok":false,"data":null,"message":"Internal Server Error","errno":0}`
+	if w.Code != http.StatusInternalServerError || w.Body.String() != want {
+		t.Errorf("want 500 %s, got %d %s", want, w.Code, w.Body.String())
+	}
+}
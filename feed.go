@@ -0,0 +1,243 @@
+package core
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Content types of the feeds.
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+)
+
+// FeedMaxAge is the max-age of the Cache-Control header of the feeds, in place of the default no-cache.
+var FeedMaxAge = 15 * time.Minute
+
+// Feed is a RSS or Atom feed.
+type Feed struct {
+	Title       string
+	Link        string // The absolute URL of the site.
+	Description string
+	ID          string // The Atom id, default is Link.
+	Author      string
+	Language    string    // The RSS language, like "en-us".
+	Updated     time.Time // The last update, also the Last-Modified of the response.
+}
+
+// FeedItem is an item of a feed.
+type FeedItem struct {
+	Title       string
+	Link        string // The absolute URL of the item.
+	Description string // The summary of the item.
+	Content     string // The HTML content of the item, Atom only.
+	ID          string // The RSS guid or the Atom id, default is Link.
+	Author      string // The RSS author is an email address.
+	Published   time.Time
+	Updated     time.Time // Atom only, default is Published.
+}
+
+// FeedIterator returns the next item of a feed, or the io.EOF error after the last one.
+type FeedIterator func() (*FeedItem, error)
+
+type rssItem struct {
+	XMLName     xml.Name `xml:"item"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Author      string   `xml:"author,omitempty"`
+	GUID        *rssGUID `xml:"guid,omitempty"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	ID          string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	XMLName   xml.Name    `xml:"entry"`
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      *atomLink   `xml:"link,omitempty"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+}
+
+// RSS streams the RSS 2.0 feed of the items of next.
+//
+//	ctx.RSS(&core.Feed{Title: "Blog", Link: "https://example.com", Updated: last}, func() (*core.FeedItem, error) {
+//		if i == len(posts) {
+//			return nil, io.EOF
+//		}
+//		i++
+//		return &core.FeedItem{Title: posts[i-1].Title, Link: posts[i-1].URL, Published: posts[i-1].Date}, nil
+//	})
+func (ctx *Context) RSS(feed *Feed, next FeedIterator) {
+	ctx.streamXML("Context.RSS", RSSContentType, FeedMaxAge, feed.Updated, func(enc *xml.Encoder, flush func()) error {
+		rss := xml.StartElement{Name: xml.Name{Local: "rss"}, Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: "2.0"}}}
+		channel := xml.StartElement{Name: xml.Name{Local: "channel"}}
+		if err := encodeTokens(enc, rss, channel); err != nil {
+			return err
+		}
+		elements := []xmlElement{
+			{"title", feed.Title},
+			{"link", feed.Link},
+			{"description", feed.Description},
+		}
+		if feed.Language != "" {
+			elements = append(elements, xmlElement{"language", feed.Language})
+		}
+		if feed.Updated.IsZero() == false {
+			elements = append(elements, xmlElement{"lastBuildDate", feed.Updated.UTC().Format(time.RFC1123Z)})
+		}
+		if err := encodeElements(enc, elements); err != nil {
+			return err
+		}
+		err := ctx.feedItems(next, flush, func(item *FeedItem) error {
+			e := rssItem{Title: item.Title, Link: item.Link, Description: item.Description, Author: item.Author}
+			if id := item.ID; id != "" {
+				e.GUID = &rssGUID{ID: id}
+			} else if item.Link != "" {
+				e.GUID = &rssGUID{ID: item.Link, IsPermaLink: true}
+			}
+			if item.Published.IsZero() == false {
+				e.PubDate = item.Published.UTC().Format(time.RFC1123Z)
+			}
+			return enc.Encode(e)
+		})
+		if err != nil {
+			return err
+		}
+		return encodeTokens(enc, channel.End(), rss.End())
+	})
+}
+
+// Atom streams the Atom feed of the items of next.
+func (ctx *Context) Atom(feed *Feed, next FeedIterator) {
+	ctx.streamXML("Context.Atom", AtomContentType, FeedMaxAge, feed.Updated, func(enc *xml.Encoder, flush func()) error {
+		start := xml.StartElement{Name: xml.Name{Local: "feed"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: "http://www.w3.org/2005/Atom"}}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		id := feed.ID
+		if id == "" {
+			id = feed.Link
+		}
+		elements := []xmlElement{
+			{"title", feed.Title},
+			{"id", id},
+			{"updated", feed.Updated.UTC().Format(time.RFC3339)},
+		}
+		if feed.Link != "" {
+			elements = append(elements, xmlElement{"link", atomLink{Href: feed.Link, Rel: "alternate"}})
+		}
+		if feed.Description != "" {
+			elements = append(elements, xmlElement{"subtitle", feed.Description})
+		}
+		if feed.Author != "" {
+			elements = append(elements, xmlElement{"author", atomAuthor{Name: feed.Author}})
+		}
+		if err := encodeElements(enc, elements); err != nil {
+			return err
+		}
+		err := ctx.feedItems(next, flush, func(item *FeedItem) error {
+			e := atomEntry{Title: item.Title, ID: item.ID}
+			if e.ID == "" {
+				e.ID = item.Link
+			}
+			if item.Link != "" {
+				e.Link = &atomLink{Href: item.Link, Rel: "alternate"}
+			}
+			updated := item.Updated
+			if updated.IsZero() == true {
+				updated = item.Published
+			}
+			if updated.IsZero() == true {
+				updated = feed.Updated
+			}
+			e.Updated = updated.UTC().Format(time.RFC3339)
+			if item.Published.IsZero() == false {
+				e.Published = item.Published.UTC().Format(time.RFC3339)
+			}
+			if item.Description != "" {
+				e.Summary = &atomText{Body: item.Description}
+			}
+			if item.Content != "" {
+				e.Content = &atomText{Type: "html", Body: item.Content}
+			}
+			if item.Author != "" {
+				e.Author = &atomAuthor{Name: item.Author}
+			}
+			return enc.Encode(e)
+		})
+		if err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	})
+}
+
+// feedItems encodes the items of next with encode, calling flush every ExportFlushRows.
+func (ctx *Context) feedItems(next FeedIterator, flush func(), encode func(item *FeedItem) error) error {
+	for n := 1; ; n++ {
+		item, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := encode(item); err != nil {
+			return err
+		}
+		if ExportFlushRows > 0 && n%ExportFlushRows == 0 {
+			if ctx.ClientGone() == true {
+				return nil
+			}
+			flush()
+		}
+	}
+}
+
+// xmlElement is an element of a feed header.
+type xmlElement struct {
+	name  string
+	value interface{}
+}
+
+func encodeElements(enc *xml.Encoder, elements []xmlElement) error {
+	for _, e := range elements {
+		if err := enc.EncodeElement(e.value, xml.StartElement{Name: xml.Name{Local: e.name}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeTokens(enc *xml.Encoder, tokens ...xml.Token) error {
+	for _, t := range tokens {
+		if err := enc.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
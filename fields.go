@@ -0,0 +1,115 @@
+package core
+
+import (
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// FieldsParam is the query parameter of the sparse fieldsets.
+var FieldsParam = "fields"
+
+// fieldTree is the tree of the fields kept in a sparse fieldset, nil keeping a whole value.
+type fieldTree map[string]fieldTree
+
+// SparseFieldsets is a handler enabling the sparse fieldsets for the next handlers: with ?fields=id,name,profile.email,
+// the Data of the success responses is pruned to the given fields, also in the objects of arrays.
+//
+//	users := core.Routers.Group("/users", core.SparseFieldsets)
+func SparseFieldsets(ctx *Context) {
+	if fields := ctx.Request.URL.Query().Get(FieldsParam); fields != "" {
+		ctx.Data["fields"] = parseFields(fields)
+	}
+	ctx.Next()
+}
+
+// parseFields parses a comma-separated list of dotted fields.
+func parseFields(s string) fieldTree {
+	tree := fieldTree{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		t := tree
+		parts := strings.Split(f, ".")
+		for i, p := range parts {
+			sub, ok := t[p]
+			if ok == true && sub == nil {
+				// The whole value is already kept.
+				break
+			}
+			if i == len(parts)-1 {
+				t[p] = nil
+				break
+			}
+			if sub == nil {
+				sub = fieldTree{}
+				t[p] = sub
+			}
+			t = sub
+		}
+	}
+	return tree
+}
+
+// pruneFields returns the JSON encoding of v with only the fields of tree, also in the objects of arrays.
+// v is encoded once, and its encoding is pruned in one pass. If it can't be encoded, v is returned as is.
+func pruneFields(v interface{}, tree fieldTree) interface{} {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	iter := json.BorrowIterator(b)
+	defer json.ReturnIterator(iter)
+	stream := json.BorrowStream(nil)
+	defer json.ReturnStream(stream)
+	pruneValue(iter, stream, tree)
+	if iter.Error != nil || stream.Error != nil {
+		return v
+	}
+	return jsoniter.RawMessage(append([]byte(nil), stream.Buffer()...))
+}
+
+// pruneValue copies the next value of iter to stream, keeping the fields of tree in its objects.
+func pruneValue(iter *jsoniter.Iterator, stream *jsoniter.Stream, tree fieldTree) {
+	switch iter.WhatIsNext() {
+	case jsoniter.ObjectValue:
+		stream.WriteObjectStart()
+		first := true
+		iter.ReadObjectCB(func(iter *jsoniter.Iterator, field string) bool {
+			sub, ok := tree[field]
+			if ok == false {
+				iter.Skip()
+				return true
+			}
+			if first == false {
+				stream.WriteMore()
+			}
+			first = false
+			stream.WriteObjectField(field)
+			if sub == nil {
+				stream.Write(iter.SkipAndReturnBytes())
+			} else {
+				pruneValue(iter, stream, sub)
+			}
+			return true
+		})
+		stream.WriteObjectEnd()
+	case jsoniter.ArrayValue:
+		stream.WriteArrayStart()
+		first := true
+		iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+			if first == false {
+				stream.WriteMore()
+			}
+			first = false
+			pruneValue(iter, stream, tree)
+			return true
+		})
+		stream.WriteArrayEnd()
+	default:
+		stream.Write(iter.SkipAndReturnBytes())
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	type profile struct {
+		Email string `json:"email"`
+		Phone string `json:"phone"`
+	}
+	type user struct {
+		ID      int     `json:"id"`
+		Name    string  `json:"name"`
+		Secret  string  `json:"secret"`
+		Profile profile `json:"profile"`
+	}
+
+	hs := NewHandlersStack()
+	hs.Use(SparseFieldsets)
+	hs.Use(func(c *Context) {
+		c.Ok([]user{{1, "john", "x", profile{"john@example.com", "555"}}})
+	})
+	r, _ := http.NewRequest("GET", "/users?fields=id,name,profile.email", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+
+	want := `{"ok":true,"data":[{"id":1,"name":"john","profile":{"email":"john@example.com"}}],"message":"","errno":0}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("body: want %s, got %s", want, got)
+	}
+}
+
+func TestPruneFields(t *testing.T) {
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	v := H{
+		"id":     1,
+		"tags":   []string{"a", "b"},
+		"orders": []H{{"id": 2, "total": 10, "lines": []H{{"sku": "x", "qty": 1}}}, {"id": 3}},
+		"secret": "x",
+	}
+	tree := parseFields("id,tags,orders.id,orders.lines.sku,missing")
+	b, err := json.Marshal(pruneFields(v, tree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"orders":[{"id":2,"lines":[{"sku":"x"}]},{"id":3}],"tags":["a","b"]}`
+	if string(b) != want {
+		t.Errorf("want %s, got %s", want, b)
+	}
+}
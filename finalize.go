@@ -0,0 +1,20 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// finalizeHeader sets the framing headers of a response of status code with a body of length bytes, just before writing its header.
+// The Content-Length is set when the length is known, and removed for the streams (negative length) and the responses
+// without body, so that a Content-Length set by a middleware can't contradict the body.
+// The Transfer-Encoding is always left to the http server.
+func (ctx *Context) finalizeHeader(code int, length int64) {
+	h := ctx.ResponseWriter.Header()
+	h.Del("Transfer-Encoding")
+	if length < 0 || code == http.StatusNoContent || code == http.StatusNotModified || code < 200 {
+		h.Del("Content-Length")
+		return
+	}
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+var (
+	// FingerprintComponents are the components of the request fingerprint: "ip" for the client IP, or a request header name.
+	FingerprintComponents = []string{"ip", "User-Agent", "Accept-Language", "Accept-Encoding"}
+
+	// FingerprintKey keys the fingerprint hash with HMAC, so that the fingerprints can't be recomputed from the request values
+	// outside of the server. Default is nil, a plain SHA-256.
+	FingerprintKey []byte
+)
+
+// Fingerprint returns a stable hash of the FingerprintComponents of the request, as 32 hex characters,
+// to identify the anonymous clients without a cookie, e.g. for the rate limiting, the A/B bucketing or the abuse detection:
+//
+//	q := core.NewQuota("anonymous", 1000, 0)
+//	q.Key = (*core.Context).Fingerprint
+//
+// It is computed once and kept in Context.Data["fingerprint"].
+func (ctx *Context) Fingerprint() string {
+	if fp, ok := ctx.Data["fingerprint"].(string); ok == true {
+		return fp
+	}
+	var h hash.Hash
+	if FingerprintKey != nil {
+		h = hmac.New(sha256.New, FingerprintKey)
+	} else {
+		h = sha256.New()
+	}
+	for _, c := range FingerprintComponents {
+		v := ""
+		if c == "ip" {
+			v = ctx.ClientIP()
+		} else {
+			v = ctx.Request.Header.Get(c)
+		}
+		// The components are separated by a zero byte, not to be confused by shifted values.
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	fp := hex.EncodeToString(h.Sum(nil)[:16])
+	ctx.Data["fingerprint"] = fp
+	return fp
+}
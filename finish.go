@@ -0,0 +1,41 @@
+package core
+
+import (
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OnFinish registers fn to run once the request is completed, even on a panic, before the context returns to the pool,
+// e.g. to remove a temporary file or release a lock opened by a middleware.
+// The callbacks run in the reverse registration order. A panicking callback is logged and doesn't stop the next ones.
+func (ctx *Context) OnFinish(fn func()) {
+	callbacks, _ := ctx.Data["onFinish"].([]func())
+	ctx.Data["onFinish"] = append(callbacks, fn)
+}
+
+// AddCloser registers c to be closed once the request is completed, like OnFinish, e.g. a database cursor.
+// The close errors are logged.
+func (ctx *Context) AddCloser(c io.Closer) {
+	ctx.OnFinish(func() {
+		if err := c.Close(); err != nil {
+			ctx.warn("close_error", "Context.AddCloser: "+err.Error())
+		}
+	})
+}
+
+// finish runs the OnFinish callbacks, once.
+func (ctx *Context) finish() {
+	callbacks, _ := ctx.Data["onFinish"].([]func())
+	delete(ctx.Data, "onFinish")
+	for i := len(callbacks) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Errorln("Context.OnFinish:", err)
+				}
+			}()
+			callbacks[i]()
+		}()
+	}
+}
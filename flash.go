@@ -0,0 +1,136 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// FlashCookie is the name of the flash cookie.
+	FlashCookie = "_flash"
+
+	// FlashKey is the AES key encrypting and authenticating the flash cookie, of 16, 24 or 32 bytes.
+	// Default is a random key, so it must be set when several instances serve the same clients.
+	FlashKey = randomKey(32)
+)
+
+// SetFlash sets the flash value key, available with ctx.Flash on the next request only, typically after a redirect:
+//
+//	ctx.SetFlash("notice", "Your profile has been saved.")
+//	ctx.Redirect("/profile", http.StatusSeeOther)
+//
+// The values are kept in an encrypted cookie, so they must be small. It must be called before writing the response.
+func (ctx *Context) SetFlash(key, value string) {
+	out, _ := ctx.Data["flash.out"].(map[string]string)
+	if out == nil {
+		out = make(map[string]string)
+		ctx.Data["flash.out"] = out
+	}
+	out[key] = value
+	b, _ := json.Marshal(out)
+	sealed, err := sealFlash(b)
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.setFlashCookie(sealed, 0)
+}
+
+// Flash returns the flash value key set by the previous request, or an empty string.
+// Reading the flash values consumes them: the flash cookie is deleted, unless new values are set.
+func (ctx *Context) Flash(key string) string {
+	return ctx.Flashes()[key]
+}
+
+// Flashes returns all the flash values set by the previous request.
+func (ctx *Context) Flashes() map[string]string {
+	if in, ok := ctx.Data["flash"].(map[string]string); ok == true {
+		return in
+	}
+	in := map[string]string{}
+	ctx.Data["flash"] = in
+	cookie, err := ctx.Request.Cookie(FlashCookie)
+	if err != nil {
+		return in
+	}
+	if b, err := openFlash(cookie.Value); err == nil {
+		json.Unmarshal(b, &in)
+	}
+	if _, ok := ctx.Data["flash.out"]; ok == false {
+		ctx.setFlashCookie("", -1)
+	}
+	return in
+}
+
+// setFlashCookie replaces the flash cookie of the response.
+func (ctx *Context) setFlashCookie(value string, maxAge int) {
+	h := ctx.ResponseWriter.Header()
+	cookies := h["Set-Cookie"]
+	h.Del("Set-Cookie")
+	for _, c := range cookies {
+		if strings.HasPrefix(c, FlashCookie+"=") == false {
+			h.Add("Set-Cookie", c)
+		}
+	}
+	http.SetCookie(ctx.ResponseWriter, &http.Cookie{
+		Name:     FlashCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   ctx.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sealFlash encrypts and authenticates b with FlashKey.
+func sealFlash(b []byte) (string, error) {
+	aead, err := flashAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, b, []byte(FlashCookie))), nil
+}
+
+// openFlash decrypts and authenticates the sealed value s.
+func openFlash(s string) ([]byte, error) {
+	aead, err := flashAEAD()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("invalid flash cookie")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(FlashCookie))
+}
+
+func flashAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(FlashKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// randomKey returns a random key of n bytes.
+func randomKey(n int) []byte {
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
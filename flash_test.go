@@ -0,0 +1,46 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFlash(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		if c.Request.URL.Path == "/save" {
+			c.SetFlash("notice", "saved")
+			c.Redirect("/profile", http.StatusSeeOther)
+			return
+		}
+		c.Ok(c.Flash("notice"))
+	})
+	serve := func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", path, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/save", nil)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != FlashCookie {
+		t.Fatalf("cookies: want the flash cookie, got %v", cookies)
+	}
+	w = serve("/profile", cookies)
+	if want := `{"ok":true,"data":"saved","message":"","errno":0}`; w.Body.String() != want {
+		t.Errorf("body: want %s, got %s", want, w.Body.String())
+	}
+	if deleted := w.Result().Cookies(); len(deleted) != 1 || deleted[0].MaxAge >= 0 {
+		t.Errorf("cookies: want the flash cookie deleted, got %v", deleted)
+	}
+
+	cookies[0].Value = cookies[0].Value[:len(cookies[0].Value)-2] + "AA"
+	if w := serve("/profile", cookies); w.Body.String() != `{"ok":true,"data":"","message":"","errno":0}` {
+		t.Errorf("tampered: want no flash, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GeoLocation is the location of a client IP.
+type GeoLocation struct {
+	Country string `json:"country"`          // The ISO 3166-1 alpha-2 country code, like "FR".
+	Region  string `json:"region,omitempty"` // The ISO 3166-2 subdivision code, without the country, like "IDF".
+	City    string `json:"city,omitempty"`
+	ASN     uint32 `json:"asn,omitempty"` // The autonomous system number of the network.
+}
+
+// GeoResolver resolves the location of an IP, e.g. from a MaxMind database. It returns nil if the IP is unknown.
+type GeoResolver interface {
+	Resolve(ip net.IP) (*GeoLocation, error)
+}
+
+// GeoResolverFunc is a function used as a GeoResolver.
+type GeoResolverFunc func(ip net.IP) (*GeoLocation, error)
+
+// Resolve calls f.
+func (f GeoResolverFunc) Resolve(ip net.IP) (*GeoLocation, error) {
+	return f(ip)
+}
+
+// Geo is the middleware resolving the location of the client IP of the requests, for the next handlers:
+// routing, compliance blocking or logging, see Context.Geo.
+// The resolutions are cached, and the resolver errors are logged and counted in Metrics under "geo.errors":
+// the request goes on without a location.
+//
+//	core.Use(core.NewGeo(maxmind.New(db)).Handler)
+type Geo struct {
+	Resolver GeoResolver
+	Cache    *Cache // The locations by IP, default is 10000 locations for an hour. Nil disables the cache.
+}
+
+// NewGeo returns a new Geo resolving the locations with resolver.
+func NewGeo(resolver GeoResolver) *Geo {
+	return &Geo{Resolver: resolver, Cache: NewCache("geo", 10000, time.Hour)}
+}
+
+// Handler resolves the location of the request, then calls the next handlers.
+func (g *Geo) Handler(ctx *Context) {
+	if loc := g.resolve(ctx.ClientIP()); loc != nil {
+		ctx.Data["geo"] = loc
+	}
+	ctx.Next()
+}
+
+// resolve returns the location of ip, or nil.
+func (g *Geo) resolve(ip string) *GeoLocation {
+	if g.Cache != nil {
+		if loc, ok := g.Cache.Get(ip); ok == true {
+			loc, _ := loc.(*GeoLocation)
+			return loc
+		}
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+	loc, err := g.Resolver.Resolve(parsed)
+	if err != nil {
+		Metrics.Add("geo.errors", 1)
+		if LogSampling == nil || LogSampling.Allow("geo_error") == true {
+			log.WithFields(log.Fields{"ip": ip}).Warnln("Geo:", err.Error())
+		}
+		return nil
+	}
+	if g.Cache != nil {
+		g.Cache.Set(ip, loc)
+	}
+	return loc
+}
+
+// Geo returns the location of the client of the request resolved by the Geo middleware, or nil if it is unknown.
+func (ctx *Context) Geo() *GeoLocation {
+	loc, _ := ctx.Data["geo"].(*GeoLocation)
+	return loc
+}
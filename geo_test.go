@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGeo(t *testing.T) {
+	var calls int
+	g := NewGeo(GeoResolverFunc(func(ip net.IP) (*GeoLocation, error) {
+		calls++
+		if ip.Equal(net.ParseIP("198.51.100.1")) {
+			return nil, errors.New("database closed")
+		}
+		return &GeoLocation{Country: "FR", Region: "IDF"}, nil
+	}))
+	var loc *GeoLocation
+	hs := NewHandlersStack()
+	hs.Use(g.Handler)
+	hs.Use(func(c *Context) {
+		loc = c.Geo()
+		c.Ok(nil)
+	})
+	serve := func(remote string) {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve("203.0.113.7:4242")
+	serve("203.0.113.7:4243")
+	if loc == nil || loc.Country != "FR" || calls != 1 {
+		t.Errorf("location: want FR resolved once, got %+v in %d calls", loc, calls)
+	}
+	serve("198.51.100.1:4242")
+	if loc != nil {
+		t.Errorf("resolver error: want no location, got %+v", loc)
+	}
+}
+
+func TestGeoBlock(t *testing.T) {
+	g := NewGeo(GeoResolverFunc(func(ip net.IP) (*GeoLocation, error) {
+		if ip.Equal(net.ParseIP("203.0.113.9")) {
+			return &GeoLocation{Country: "US", ASN: 64500}, nil
+		}
+		return &GeoLocation{Country: "KP"}, nil
+	}))
+	block, err := NewGeoBlock([]string{"kp"}, []string{"192.0.2.0/24", "198.51.100.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := NewHandlersStack()
+	hs.Use(g.Handler)
+	hs.Use(block.Handler)
+	hs.Use(func(c *Context) { c.Ok(nil) })
+	serve := func(remote string) int {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve("203.0.113.7:1"); code != http.StatusUnavailableForLegalReasons {
+		t.Errorf("blocked country: want 451, got %d", code)
+	}
+	for _, remote := range []string{"192.0.2.10:1", "198.51.100.7:1", "203.0.113.9:1"} {
+		if code := serve(remote); code != http.StatusOK {
+			t.Errorf("%s: want 200, got %d", remote, code)
+		}
+	}
+	block.ASNs = []uint32{64500}
+	block.RedirectURL = "https://example.com/unavailable"
+	if code := serve("203.0.113.9:1"); code != http.StatusFound {
+		t.Errorf("blocked ASN: want a redirect, got %d", code)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// GeoBlock is the middleware blocking the requests from countries or networks, e.g. for the export compliance.
+// It uses the location resolved by the Geo middleware, used before it.
+// The blocked requests are counted in Metrics under "geo.blocked".
+//
+//	block, _ := core.NewGeoBlock([]string{"KP", "IR"}, []string{"10.0.0.0/8"})
+//	core.Use(geo.Handler, block.Handler)
+type GeoBlock struct {
+	Countries    []string // The blocked ISO 3166-1 alpha-2 country codes.
+	ASNs         []uint32 // The blocked autonomous system numbers.
+	BlockUnknown bool     // BlockUnknown blocks the requests without a location too.
+
+	// RedirectURL redirects the blocked requests to it with 302 Found, e.g. to a regional site.
+	RedirectURL string
+
+	// Blocked responds to the blocked requests. Default is nil, they fail with 451 Unavailable For Legal Reasons,
+	// or are redirected to RedirectURL if set.
+	Blocked RouterHandler
+
+	exempt []*net.IPNet
+}
+
+// NewGeoBlock returns a new GeoBlock blocking countries, with the exempt IPs or CIDRs, like the monitoring probes.
+func NewGeoBlock(countries []string, exempt []string) (*GeoBlock, error) {
+	b := &GeoBlock{}
+	for _, c := range countries {
+		b.Countries = append(b.Countries, strings.ToUpper(c))
+	}
+	for _, e := range exempt {
+		if strings.Contains(e, "/") == false {
+			if strings.Contains(e, ":") {
+				e += "/128"
+			} else {
+				e += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, err
+		}
+		b.exempt = append(b.exempt, n)
+	}
+	return b, nil
+}
+
+// Handler blocks the request, or calls the next handlers.
+func (b *GeoBlock) Handler(ctx *Context) {
+	if b.blocks(ctx) == false {
+		ctx.Next()
+		return
+	}
+	Metrics.Add("geo.blocked", 1)
+	switch {
+	case b.Blocked != nil:
+		b.Blocked(ctx)
+	case b.RedirectURL != "":
+		ctx.Redirect(b.RedirectURL, http.StatusFound)
+	default:
+		ctx.FailWithStatus(http.StatusUnavailableForLegalReasons, (&ForbiddenError{}).New("not available in your region"))
+	}
+}
+
+// blocks tells if the request is blocked.
+func (b *GeoBlock) blocks(ctx *Context) bool {
+	if ip := net.ParseIP(ctx.ClientIP()); ip != nil {
+		for _, n := range b.exempt {
+			if n.Contains(ip) {
+				return false
+			}
+		}
+	}
+	loc := ctx.Geo()
+	if loc == nil {
+		return b.BlockUnknown
+	}
+	if stringIn(strings.ToUpper(loc.Country), b.Countries) {
+		return true
+	}
+	for _, asn := range b.ASNs {
+		if loc.ASN == asn {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,6 @@
 module github.com/HiLittleCat/core
 
-go 1.12
+go 1.18
 
 replace (
 	golang.org/x/net => github.com/golang/net v0.0.0-20180821023952-922f4815f713
@@ -11,6 +11,20 @@ replace (
 
 require (
 	github.com/HiLittleCat/conn v0.0.0-20190401124320-c0c7e5d51b61
+	github.com/json-iterator/go v1.1.6
+	github.com/klauspost/compress v1.11.13
+	github.com/lestrrat/go-file-rotatelogs v0.0.0-20180223000712-d3151e2a480f
+	github.com/pkg/errors v0.8.1
+	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5
+	github.com/sirupsen/logrus v1.4.1
+	golang.org/x/crypto v0.17.0
+	golang.org/x/text v0.14.0
+	gopkg.in/go-playground/validator.v9 v9.28.0
+	gopkg.in/redis.v5 v5.2.9
+	gopkg.in/tylerb/graceful.v1 v1.2.15
+)
+
+require (
 	github.com/eclipse/paho.mqtt.golang v1.2.0 // indirect
 	github.com/fastly/go-utils v0.0.0-20180712184237-d95a45783239 // indirect
 	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8 // indirect
@@ -18,21 +32,16 @@ require (
 	github.com/go-playground/universal-translator v0.16.0 // indirect
 	github.com/jehiah/go-strftime v0.0.0-20171201141054-1d33003b3869 // indirect
 	github.com/jonboulle/clockwork v0.1.0 // indirect
-	github.com/json-iterator/go v1.1.6
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
 	github.com/leodido/go-urn v1.1.0 // indirect
 	github.com/lestrrat/go-envload v0.0.0-20180220120943-6ed08b54a570 // indirect
-	github.com/lestrrat/go-file-rotatelogs v0.0.0-20180223000712-d3151e2a480f
 	github.com/lestrrat/go-strftime v0.0.0-20180220042222-ba3bf9c1d042 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
-	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/onsi/ginkgo v1.8.0 // indirect
 	github.com/onsi/gomega v1.5.0 // indirect
-	github.com/pkg/errors v0.8.1
-	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5
-	github.com/sirupsen/logrus v1.4.1
 	github.com/tebeka/strftime v0.0.0-20140926081919-3f9c7761e312 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
-	gopkg.in/go-playground/validator.v9 v9.28.0
-	gopkg.in/redis.v5 v5.2.9
-	gopkg.in/tylerb/graceful.v1 v1.2.15
 )
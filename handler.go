@@ -8,6 +8,19 @@ import (
 type HandlersStack struct {
 	Handlers     []RouterHandler // The handlers stack.
 	PanicHandler RouterHandler   // The handler called in case of panic. Useful to send custom server error information. Context.Data["panic"] contains the panic error.
+
+	plugins []Plugin
+}
+
+// defaultHeaders are the "good practice" headers set on every response.
+// The values are shared by all the responses: their capacity is limited so that Header().Add copies them instead of writing in place.
+var defaultHeaders = map[string][]string{
+	"Cache-Control":                {"no-cache"},
+	"Content-Type":                 {"application/json"},
+	"Connection":                   {"keep-alive"},
+	"Vary":                         {"Accept-Encoding"},
+	"Access-Control-Allow-Headers": {"X-Requested-With"},
+	"Access-Control-Allow-Methods": {"PUT,POST,GET,DELETE,OPTIONS"},
 }
 
 // defaultHandlersStack contains the default handlers stack used for serving.
@@ -45,16 +58,15 @@ func HandlePanic(h RouterHandler) {
 // ServeHTTP makes a context for the request, sets some good practice default headers and enters the handlers stack.
 func (hs *HandlersStack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get a context for the request from ctxPool.
-	c := getContext(w, r)
+	c := getContext(hs, w, r)
 
 	// Set some "good practice" default headers.
-	c.ResponseWriter.Header().Set("Cache-Control", "no-cache")
-	c.ResponseWriter.Header().Set("Content-Type", "application/json")
-	c.ResponseWriter.Header().Set("Connection", "keep-alive")
-	c.ResponseWriter.Header().Set("Vary", "Accept-Encoding")
+	// The header keys are canonical, so the values are assigned directly instead of allocating with Header().Set.
 	//c.ResponseWriter.Header().Set("Access-Control-Allow-Origin", "*")
-	c.ResponseWriter.Header().Set("Access-Control-Allow-Headers", "X-Requested-With")
-	c.ResponseWriter.Header().Set("Access-Control-Allow-Methods", "PUT,POST,GET,DELETE,OPTIONS")
+	h := c.ResponseWriter.Header()
+	for k, v := range defaultHeaders {
+		h[k] = v[:len(v):len(v)]
+	}
 
 	// Always recover form panics.
 	defer c.Recover()
@@ -66,6 +78,7 @@ func (hs *HandlersStack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// if c.written == false {
 	// 	c.Fail(errors.New("not written"))
 	// }
-	// Put the context to ctxPool
+	// Release the resources of the request, and put the context to ctxPool
+	c.finish()
 	putContext(c)
 }
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// HandlerName returns the name of the function h, like "github.com/me/app/users.List" or "github.com/me/app/users.(*API).List".
+func HandlerName(h RouterHandler) string {
+	if h == nil {
+		return ""
+	}
+	f := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if f == nil {
+		return ""
+	}
+	// Method values are wrapped in a "-fm" function.
+	return strings.TrimSuffix(f.Name(), "-fm")
+}
+
+// handlerNames returns the names of handlers.
+func handlerNames(handlers RouterHandlerChain) []string {
+	names := make([]string, len(handlers))
+	for i, h := range handlers {
+		names[i] = HandlerName(h)
+	}
+	return names
+}
+
+// HandlerName returns the name of the route handler of the request, or an empty string if it isn't routed.
+func (ctx *Context) HandlerName() string {
+	if ctx.route == nil || len(ctx.route.names) == 0 {
+		return ""
+	}
+	return ctx.route.names[len(ctx.route.names)-1]
+}
+
+// ResponderName returns the name of the handler or middleware that wrote the response, or an empty string if it isn't written.
+// The routing middleware show up as the methods of Route, e.g. "(*Route).guard" for the missing scopes.
+func (ctx *Context) ResponderName() string {
+	i := ctx.writer.responder
+	if i < 0 || i >= len(ctx.handlersStack.Handlers) {
+		return ""
+	}
+	return HandlerName(ctx.handlersStack.Handlers[i])
+}
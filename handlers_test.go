@@ -3,6 +3,8 @@ package core
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -43,3 +45,101 @@ func TestServeHTTP(t *testing.T) {
 		t.Errorf("body: want %q, got %q", bodyWant, bodyGot)
 	}
 }
+
+// TestServeHTTPStack checks that each handlers stack serves its own handlers, not the ones of the default stack.
+func TestServeHTTPStack(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) { c.ResponseWriter.Write([]byte("own")) })
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	if w.Body.String() != "own" {
+		t.Errorf("body: want %q, got %q", "own", w.Body.String())
+	}
+}
+
+// TestServeHTTPConcurrentRoutes checks that the concurrent requests of different routes don't share the handlers of their stack,
+// when it has room to append the route handlers.
+func TestServeHTTPConcurrentRoutes(t *testing.T) {
+	engine := New()
+	engine.GET("/a", func(c *Context) { c.ResponseWriter.Write([]byte("a")) })
+	engine.GET("/b", func(c *Context) { c.ResponseWriter.Write([]byte("b")) })
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) { c.Next() })
+	hs.Use(func(c *Context) { c.Next() })
+	hs.Use(engine.Handler())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		for _, path := range []string{"/a", "/b"} {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					r, _ := http.NewRequest("GET", path, nil)
+					w := httptest.NewRecorder()
+					hs.ServeHTTP(w, r)
+					if w.Body.String() != path[1:] {
+						t.Errorf("%s: got %q", path, w.Body.String())
+						return
+					}
+				}
+			}(path)
+		}
+	}
+	wg.Wait()
+}
+
+// testPlugin records its lifecycle calls.
+type testPlugin struct {
+	calls []string
+}
+
+func (p *testPlugin) Name() string { return "test" }
+
+func (p *testPlugin) Init(hs *HandlersStack) error {
+	p.calls = append(p.calls, "init")
+	return nil
+}
+
+func (p *testPlugin) Routes(router IRouter) {
+	router.GET("/plugin", func(c *Context) { c.Ok(nil) })
+}
+
+func (p *testPlugin) Middleware() []RouterHandler {
+	return []RouterHandler{func(c *Context) {
+		c.ResponseWriter.Header().Set("X-Plugin", "test")
+		c.Next()
+	}}
+}
+
+func (p *testPlugin) Shutdown() error {
+	p.calls = append(p.calls, "shutdown")
+	return nil
+}
+
+func TestUsePlugin(t *testing.T) {
+	p := new(testPlugin)
+	hs := NewHandlersStack()
+	engine := New()
+	if err := hs.UsePlugin(p, engine); err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.UsePlugin(p, engine); err != ErrPluginLoaded {
+		t.Errorf("loaded twice: want %v, got %v", ErrPluginLoaded, err)
+	}
+	hs.Use(engine.Handler())
+
+	r, _ := http.NewRequest("GET", "/plugin", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("X-Plugin") != "test" {
+		t.Errorf("want 200 with the plugin header, got %d %q", w.Code, w.Header().Get("X-Plugin"))
+	}
+	if err := hs.ShutdownPlugins(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(p.calls, ","); got != "init,shutdown" {
+		t.Errorf("calls: want init,shutdown, got %s", got)
+	}
+}
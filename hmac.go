@@ -0,0 +1,156 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignatureScheme extracts the signature of a signed request, as the header format of its sender.
+// HeaderScheme, StripeScheme, GitHubScheme and SlackScheme are provided.
+type SignatureScheme interface {
+	// Parse returns the payload signed by the sender, the candidate signatures, and the signature timestamp (zero if the scheme has none).
+	Parse(r *http.Request, body []byte) (payload []byte, signatures [][]byte, timestamp time.Time, err error)
+}
+
+// NonceStore remembers the signatures of the verified requests, for the replay protection.
+// The signatures are the nonces: a header outside of the signed payload can't make a captured request new.
+type NonceStore interface {
+	// Seen records nonce for ttl, and tells if it was already recorded.
+	Seen(nonce string, ttl time.Duration) (bool, error)
+}
+
+// HMAC is the middleware verifying the HMAC signature of the requests, for the webhook receivers and the B2B endpoints.
+// The requests with a missing or invalid signature, a timestamp beyond MaxSkew, or an already seen signature fail with 401 Unauthorized.
+//
+//	hooks := core.Routers.Group("/hooks", core.NewHMAC(secret).Handler)
+//
+// With the default scheme, the client sends the X-Timestamp header with the unix time,
+// the X-Nonce header with a random value, and the X-Signature header with the hex HMAC-SHA256 of "<timestamp>.<nonce>.<body>".
+// The nonce distinguishes the identical requests sent in the same second.
+type HMAC struct {
+	Secret  []byte
+	Hash    func() hash.Hash // Default is sha256.New.
+	Scheme  SignatureScheme  // Default is HeaderScheme.
+	MaxSkew time.Duration    // The maximum difference between the signature timestamp and now, default is 5 minutes.
+	Nonces  NonceStore       // Default is a MemoryNonceStore, nil disables the replay protection.
+	MaxBody int64            // The maximum body size, default is 1MB.
+}
+
+// HeaderScheme is the SignatureScheme with the timestamp, the nonce and the hex signature in headers,
+// the signature covering "<timestamp>.<nonce>.<body>".
+type HeaderScheme struct {
+	SignatureHeader string
+	TimestampHeader string
+	NonceHeader     string
+}
+
+// ErrSignature is the error of an invalid request signature.
+var ErrSignature = errors.New("invalid signature")
+
+// NewHMAC returns a new HMAC verifying the signatures made with secret.
+func NewHMAC(secret []byte) *HMAC {
+	return &HMAC{
+		Secret:  secret,
+		Hash:    sha256.New,
+		Scheme:  &HeaderScheme{SignatureHeader: "X-Signature", TimestampHeader: "X-Timestamp", NonceHeader: "X-Nonce"},
+		MaxSkew: 5 * time.Minute,
+		Nonces:  NewMemoryNonceStore(),
+		MaxBody: 1 << 20,
+	}
+}
+
+// Handler is the middleware verifying the requests of the next handlers.
+func (m *HMAC) Handler(ctx *Context) {
+	body, err := ctx.readBody(m.MaxBody)
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	if err := m.Verify(ctx.Request, body); err != nil {
+		ctx.Fail((&UnauthorizedError{}).New(err.Error()))
+		return
+	}
+	ctx.Next()
+}
+
+// Verify verifies the signature of r with its body.
+func (m *HMAC) Verify(r *http.Request, body []byte) error {
+	payload, signatures, timestamp, err := m.Scheme.Parse(r, body)
+	if err != nil {
+		return err
+	}
+	if timestamp.IsZero() == false {
+		skew := time.Since(timestamp)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > m.MaxSkew {
+			return errors.New("signature timestamp out of range")
+		}
+	}
+
+	mac := hmac.New(m.Hash, m.Secret)
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	var valid []byte
+	for _, s := range signatures {
+		if hmac.Equal(expected, s) == true {
+			valid = s
+			break
+		}
+	}
+	if valid == nil {
+		return ErrSignature
+	}
+
+	if m.Nonces != nil {
+		ttl := 2 * m.MaxSkew
+		if timestamp.IsZero() == true {
+			ttl = 24 * time.Hour
+		}
+		seen, err := m.Nonces.Seen(hex.EncodeToString(valid), ttl)
+		if err != nil {
+			return err
+		}
+		if seen == true {
+			return errors.New("replayed request")
+		}
+	}
+	return nil
+}
+
+// Parse returns the signed "<timestamp>.<nonce>.<body>" payload and the signature of the headers.
+func (s *HeaderScheme) Parse(r *http.Request, body []byte) ([]byte, [][]byte, time.Time, error) {
+	ts := r.Header.Get(s.TimestampHeader)
+	timestamp, err := parseUnix(ts)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get(s.SignatureHeader), "sha256="))
+	if err != nil || len(sig) == 0 {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	payload := append([]byte(ts+"."+r.Header.Get(s.NonceHeader)+"."), body...)
+	return payload, [][]byte{sig}, timestamp, nil
+}
+
+// MemoryNonceStore is a NonceStore in memory, local to the instance.
+type MemoryNonceStore struct {
+	nonces *Cache
+}
+
+// NewMemoryNonceStore returns a new MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: NewCache("", 0, 0)}
+}
+
+// Seen records nonce for ttl, and tells if it was already recorded.
+func (s *MemoryNonceStore) Seen(nonce string, ttl time.Duration) (bool, error) {
+	return s.nonces.Add(nonce, true, ttl) == false, nil
+}
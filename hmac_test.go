@@ -0,0 +1,129 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMAC(t *testing.T) {
+	secret := []byte("s3cret")
+	hs := NewHandlersStack()
+	hs.Use(NewHMAC(secret).Handler)
+	hs.Use(func(c *Context) { c.Ok(nil) })
+
+	sign := func(ts int64, nonce, body string) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(strconv.FormatInt(ts, 10) + "." + nonce + "." + body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	serve := func(ts int64, nonce, signature, body string) int {
+		r, _ := http.NewRequest("POST", "/hooks", strings.NewReader(body))
+		r.Header.Set("X-Timestamp", strconv.FormatInt(ts, 10))
+		r.Header.Set("X-Nonce", nonce)
+		r.Header.Set("X-Signature", signature)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	now := time.Now().Unix()
+	old := time.Now().Add(-time.Hour).Unix()
+	tests := []struct {
+		name      string
+		ts        int64
+		nonce     string
+		signature string
+		code      int
+	}{
+		{"valid", now, "", sign(now, "", `{"id":1}`), http.StatusOK},
+		{"replayed", now, "", sign(now, "", `{"id":1}`), http.StatusUnauthorized},
+		{"tampered", now, "", sign(now, "", `{"id":2}`), http.StatusUnauthorized},
+		{"expired", old, "", sign(old, "", `{"id":1}`), http.StatusUnauthorized},
+		{"nonce", now, "a", sign(now, "a", `{"id":1}`), http.StatusOK},
+		{"other nonce", now, "b", sign(now, "b", `{"id":1}`), http.StatusOK},
+		{"replayed nonce", now, "a", sign(now, "a", `{"id":1}`), http.StatusUnauthorized},
+		{"replayed with a new nonce", now, "c", sign(now, "a", `{"id":1}`), http.StatusUnauthorized},
+		{"replayed without nonce", now, "", sign(now, "b", `{"id":1}`), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := serve(tt.ts, tt.nonce, tt.signature, `{"id":1}`); code != tt.code {
+			t.Errorf("%s: want %d, got %d", tt.name, tt.code, code)
+		}
+	}
+}
+
+// TestHMACMaxBody checks that MaxBody applies to a body already cached by a previous handler, within BodyCacheLimit.
+func TestHMACMaxBody(t *testing.T) {
+	m := NewHMAC([]byte("s3cret"))
+	m.MaxBody = 16
+	for _, cached := range []bool{false, true} {
+		hs := NewHandlersStack()
+		if cached == true {
+			hs.Use(func(c *Context) {
+				if _, err := c.BodyBytes(); err != nil {
+					t.Fatal(err)
+				}
+				c.Next()
+			})
+		}
+		hs.Use(m.Handler)
+		hs.Use(func(c *Context) { c.Ok(nil) })
+		r, _ := http.NewRequest("POST", "/hooks", strings.NewReader(strings.Repeat("a", 32)))
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("cached %t: want 413, got %d", cached, w.Code)
+		}
+	}
+}
+
+func TestWebhookSchemes(t *testing.T) {
+	body := `{"event":"paid"}`
+	mac := func(payload string) string {
+		m := hmac.New(sha256.New, []byte("whsec"))
+		m.Write([]byte(payload))
+		return hex.EncodeToString(m.Sum(nil))
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	tests := []struct {
+		name    string
+		hook    *HMAC
+		headers map[string]string
+	}{
+		{"stripe", NewStripeWebhook("whsec"), map[string]string{
+			"Stripe-Signature": "t=" + ts + ",v1=" + mac("bad") + ",v1=" + mac(ts+"."+body),
+		}},
+		{"github", NewGitHubWebhook("whsec"), map[string]string{
+			"X-Hub-Signature-256": "sha256=" + mac(body),
+			"X-GitHub-Delivery":   "72d3162e",
+		}},
+		{"slack", NewSlackWebhook("whsec"), map[string]string{
+			"X-Slack-Request-Timestamp": ts,
+			"X-Slack-Signature":         "v0=" + mac("v0:"+ts+":"+body),
+		}},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("POST", "/hooks", strings.NewReader(body))
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		if err := tt.hook.Verify(r, []byte(body)); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if err := tt.hook.Verify(r, []byte(body+" ")); err == nil {
+			t.Errorf("%s: want an error for a tampered body", tt.name)
+		}
+		// The delivery header isn't signed: a replay with a new one is still a replay.
+		r.Header.Set("X-GitHub-Delivery", "a1b2c3d4")
+		if err := tt.hook.Verify(r, []byte(body)); err == nil {
+			t.Errorf("%s: want an error for a replay", tt.name)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TraceHeaders are the headers of the inbound request propagated to the outbound requests by HTTPClient.DoContext.
+var TraceHeaders = []string{"X-Request-Id", "Traceparent", "Tracestate", "X-B3-Traceid", "X-B3-Spanid", "X-B3-Sampled"}
+
+// errRetryBudget is returned when the retry budget is exhausted and the last attempt failed without response.
+var errRetryBudget = errors.New("httpclient: retry budget exhausted")
+
+// HTTPClient wraps an http.Client for the outbound requests of the handlers, with retries and hedging.
+//
+// Only requests with a replayable body (GetBody is set, as done by http.NewRequest) are retried or hedged.
+type HTTPClient struct {
+	Client         *http.Client  // The underlying client, default is http.DefaultClient.
+	MaxAttempts    int           // The maximum number of attempts, including the first one. Default is 3.
+	AttemptTimeout time.Duration // The timeout of each attempt, default is 0, no timeout other than the request context.
+	BackoffBase    time.Duration // The first backoff, doubled on each retry, with jitter. Default is 100ms.
+	BackoffMax     time.Duration // The maximum backoff, default is 2s.
+
+	// HedgeDelay enables the hedged requests: when an attempt has no response after HedgeDelay, another one is sent in parallel
+	// and the first response wins. Default is 0, hedging is disabled and attempts are sequential retries.
+	HedgeDelay time.Duration
+
+	// Budget limits the retries against the requests, so that retries don't amplify an outage. Default is nil, no limit.
+	Budget *RetryBudget
+
+	// Retryable tells if an attempt must be retried. Default retries the network errors, 429, 502, 503 and 504 of idempotent methods.
+	Retryable func(req *http.Request, res *http.Response, err error) bool
+}
+
+// NewHTTPClient returns a new HTTPClient with the default settings.
+func NewHTTPClient() *HTTPClient {
+	return &HTTPClient{
+		MaxAttempts: 3,
+		BackoffBase: 100 * time.Millisecond,
+		BackoffMax:  2 * time.Second,
+	}
+}
+
+// DoContext sends req in the context of the inbound request ctx: it is canceled with it and carries its TraceHeaders and Baggage.
+func (c *HTTPClient) DoContext(ctx *Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx.Request.Context())
+	for _, k := range TraceHeaders {
+		if v := ctx.Request.Header.Get(k); v != "" && req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	ctx.propagateBaggage(req)
+	return c.Do(req)
+}
+
+// Do sends req with retries or hedging.
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	attempts := c.MaxAttempts
+	if attempts < 1 || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		attempts = 1
+	}
+	if c.Budget != nil {
+		c.Budget.request()
+	}
+	if c.HedgeDelay > 0 && attempts > 1 && isIdempotent(req.Method) {
+		return c.hedge(req, attempts)
+	}
+
+	var (
+		res *http.Response
+		err error
+	)
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if c.Budget != nil && c.Budget.retry() == false {
+				break
+			}
+			if res != nil {
+				res.Body.Close()
+			}
+			if err := sleepContext(req.Context(), c.backoff(i)); err != nil {
+				return nil, err
+			}
+		}
+		res, err = c.attempt(req.Context(), req)
+		if c.retryable(req, res, err) == false {
+			return res, err
+		}
+	}
+	if res == nil && err == nil {
+		err = errRetryBudget
+	}
+	return res, err
+}
+
+// hedge sends up to attempts parallel requests, spaced by HedgeDelay, and returns the first final response.
+func (c *HTTPClient) hedge(req *http.Request, attempts int) (*http.Response, error) {
+	results := make(chan attemptResult, attempts)
+	cancels := make([]context.CancelFunc, 0, attempts)
+	launch := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		i := len(cancels) - 1
+		go func() {
+			res, err := c.attempt(ctx, req)
+			results <- attemptResult{i, res, err}
+		}()
+	}
+	// cancelOthers cancels the attempts other than the winner, and closes their responses.
+	// The winner is canceled when its response body is closed.
+	cancelOthers := func(winner attemptResult, pending int) {
+		for i, cancel := range cancels {
+			if i != winner.index {
+				cancel()
+			} else if winner.res != nil {
+				winner.res.Body = &cancelBody{winner.res.Body, cancel}
+			} else {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				if r := <-results; r.res != nil {
+					r.res.Body.Close()
+				}
+			}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(c.HedgeDelay)
+	defer timer.Stop()
+	last := attemptResult{index: -1}
+	for received := 0; received < len(cancels); {
+		select {
+		case r := <-results:
+			received++
+			if c.retryable(req, r.res, r.err) == false {
+				cancelOthers(r, len(cancels)-received)
+				return r.res, r.err
+			}
+			if last.res != nil {
+				last.res.Body.Close()
+			}
+			last = r
+			if len(cancels) < attempts && (c.Budget == nil || c.Budget.retry()) {
+				launch()
+			}
+		case <-timer.C:
+			if len(cancels) < attempts && (c.Budget == nil || c.Budget.retry()) {
+				launch()
+				timer.Reset(c.HedgeDelay)
+			}
+		}
+	}
+	cancelOthers(last, 0)
+	return last.res, last.err
+}
+
+// attemptResult is the outcome of the hedged attempt index.
+type attemptResult struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// attempt sends a copy of req, with the attempt timeout.
+func (c *HTTPClient) attempt(ctx context.Context, req *http.Request) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.AttemptTimeout)
+	}
+	r := req.WithContext(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The attempt context must live until the body is read.
+	res.Body = &cancelBody{res.Body, cancel}
+	return res, nil
+}
+
+func (c *HTTPClient) retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if c.Retryable != nil {
+		return c.Retryable(req, res, err)
+	}
+	if isIdempotent(req.Method) == false {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the exponential backoff of the retry i, with full jitter.
+func (c *HTTPClient) backoff(i int) time.Duration {
+	d := c.BackoffBase
+	for j := 1; j < i && d < c.BackoffMax; j++ {
+		d *= 2
+	}
+	if c.BackoffMax > 0 && d > c.BackoffMax {
+		d = c.BackoffMax
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE", "TRACE":
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cancelBody cancels the attempt context when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RetryBudget allows the retries as long as they stay under Ratio of the requests in the last Window, plus MinRetries.
+type RetryBudget struct {
+	Ratio      float64       // The ratio of retries to requests, for example 0.2.
+	MinRetries int           // The retries always allowed in a window.
+	Window     time.Duration // The counting window, default is 10s.
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+func (b *RetryBudget) request() {
+	b.mu.Lock()
+	b.advance()
+	b.requests++
+	b.mu.Unlock()
+}
+
+func (b *RetryBudget) retry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	if float64(b.retries) >= float64(b.MinRetries)+b.Ratio*float64(b.requests) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+func (b *RetryBudget) advance() {
+	window := b.Window
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	if now := time.Now(); now.Sub(b.windowStart) >= window {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientRetry(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Request-Id")))
+	}))
+	defer ts.Close()
+
+	c := NewHTTPClient()
+	c.BackoffBase = time.Millisecond
+	hs := NewHandlersStack()
+	var res *http.Response
+	var err error
+	hs.Use(func(ctx *Context) {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		res, err = c.DoContext(ctx, req)
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("want 200 after 3 calls, got %d after %d calls", res.StatusCode, calls)
+	}
+	if string(body) != "abc" {
+		t.Errorf("trace header: want %q, got %q", "abc", body)
+	}
+}
+
+func TestHTTPClientHedge(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer ts.Close()
+
+	c := NewHTTPClient()
+	c.HedgeDelay = 10 * time.Millisecond
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	start := time.Now()
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "fast" {
+		t.Errorf("body: want %q, got %q", "fast", body)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("hedged request took %s", d)
+	}
+}
+
+func TestHTTPClientBaggage(t *testing.T) {
+	BaggageKeys = []string{"tenant.id"}
+	defer func() { BaggageKeys = nil }()
+	var baggage string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		baggage = r.Header.Get("Baggage")
+	}))
+	defer ts.Close()
+
+	c := NewHTTPClient()
+	hs := NewHandlersStack()
+	hs.Use(func(ctx *Context) {
+		ctx.SetBaggage("flags", "beta checkout")
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		req.Header.Set("Baggage", "flags=none")
+		if res, err := c.DoContext(ctx, req); err == nil {
+			res.Body.Close()
+		}
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Baggage", "tenant.id=acme;source=edge, user.id=42")
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+
+	if want := "flags=none,tenant.id=acme"; baggage != want {
+		t.Errorf("baggage: want %q, got %q", want, baggage)
+	}
+}
@@ -0,0 +1,328 @@
+// Package imageproxy implements an image endpoint of package core, serving the source images resized and cropped
+// per signed query parameters, so that the clients can't make the server render arbitrary sizes.
+//
+//	proxy := imageproxy.New(secret, imageproxy.HTTPSource(nil, "https://uploads.example.com/"))
+//	proxy.Mount(core.Routers, "/img")
+//
+//	avatar := proxy.URL("avatars/bob.jpg", imageproxy.Options{Width: 96, Height: 96, Fit: imageproxy.Cover})
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"image"
+	_ "image/gif" // The GIF sources.
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HiLittleCat/core"
+	log "github.com/sirupsen/logrus"
+)
+
+// Fit modes of the resized images.
+const (
+	Contain = "contain" // The image fits in the box, keeping its aspect ratio.
+	Cover   = "cover"   // The image covers the box, keeping its aspect ratio, and is cropped to it around its center.
+)
+
+// ErrTooLarge is the error of the source images beyond Proxy.MaxSourceBytes or Proxy.MaxSourcePixels.
+var ErrTooLarge = errors.New("imageproxy: source image too large")
+
+// Source opens the source images by name. It returns an error matching fs.ErrNotExist if the image doesn't exist.
+type Source interface {
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// SourceFunc is a function used as a Source.
+type SourceFunc func(ctx context.Context, name string) (io.ReadCloser, error)
+
+// Open calls f.
+func (f SourceFunc) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return f(ctx, name)
+}
+
+// HTTPSource returns the Source fetching the images at baseURL + name with client, default is http.DefaultClient.
+func HTTPSource(client *http.Client, baseURL string) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return SourceFunc(func(ctx context.Context, name string) (io.ReadCloser, error) {
+		req, err := http.NewRequest("GET", strings.TrimSuffix(baseURL, "/")+"/"+name, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			return nil, fs.ErrNotExist
+		}
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, errors.New("imageproxy: " + strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode))
+		}
+		return res.Body, nil
+	})
+}
+
+// DirSource returns the Source reading the images in the directory dir.
+func DirSource(dir string) Source {
+	return SourceFunc(func(ctx context.Context, name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(path.Clean("/"+name))))
+	})
+}
+
+// BlobSource returns the Source reading the images in store.
+func BlobSource(store core.BlobStore) Source {
+	return SourceFunc(func(ctx context.Context, name string) (io.ReadCloser, error) {
+		r, _, err := store.Get(ctx, name)
+		return r, err
+	})
+}
+
+// Options are the transformations of an image.
+type Options struct {
+	Width   int    // The max width, 0 derives it from Height.
+	Height  int    // The max height, 0 derives it from Width.
+	Fit     string // Contain or Cover, default is Contain.
+	Quality int    // The JPEG quality between 1 and 100, default is 85.
+	Format  string // "jpeg" or "png", default is the source format, or png for the other formats.
+}
+
+// values returns the canonical query parameters of o, without the signature.
+func (o Options) values() url.Values {
+	v := url.Values{}
+	if o.Width > 0 {
+		v.Set("w", strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		v.Set("h", strconv.Itoa(o.Height))
+	}
+	if o.Fit != "" && o.Fit != Contain {
+		v.Set("fit", o.Fit)
+	}
+	if o.Quality > 0 {
+		v.Set("q", strconv.Itoa(o.Quality))
+	}
+	if o.Format != "" {
+		v.Set("f", o.Format)
+	}
+	return v
+}
+
+// parseOptions returns the options of the query parameters q.
+func parseOptions(q url.Values, maxSize int) (Options, error) {
+	var o Options
+	var err error
+	for _, p := range []struct {
+		name string
+		dst  *int
+		max  int
+	}{{"w", &o.Width, maxSize}, {"h", &o.Height, maxSize}, {"q", &o.Quality, 100}} {
+		s := q.Get(p.name)
+		if s == "" {
+			continue
+		}
+		if *p.dst, err = strconv.Atoi(s); err != nil || *p.dst < 1 || *p.dst > p.max {
+			return o, (&core.ValidationError{}).New("invalid image parameter " + p.name)
+		}
+	}
+	o.Fit = q.Get("fit")
+	if o.Fit != "" && o.Fit != Contain && o.Fit != Cover {
+		return o, (&core.ValidationError{}).New("invalid image parameter fit")
+	}
+	o.Format = q.Get("f")
+	if o.Format != "" && o.Format != "jpeg" && o.Format != "png" {
+		return o, (&core.ValidationError{}).New("invalid image parameter f")
+	}
+	return o, nil
+}
+
+// Proxy serves the images of Source transformed per the Options of their signed URLs.
+// The transformed images are cached and served with long-lived cache headers: a URL always serves the same image.
+// The requests with a missing or invalid signature fail with 403 Forbidden.
+type Proxy struct {
+	Source Source
+	Key    []byte      // The key of the HMAC-SHA256 signatures of the URLs.
+	Cache  *core.Cache // The transformed images by URL, default is 500 images for a day. Nil disables the cache.
+
+	MaxAge         time.Duration // The max-age of the Cache-Control header, default is a year.
+	MaxSize        int           // The max width and height of the transformed images, default is 4096.
+	MaxSourceBytes int64         // The max size of the source images, default is 20 MB.
+	// MaxSourcePixels is the max width times height of the source images, checked before decoding them, default is 40 megapixels:
+	// a small compressed image can decode into gigabytes.
+	MaxSourcePixels int64
+
+	prefix string
+	flight *core.Singleflight
+}
+
+// New returns a new Proxy of the images of source, with the URLs signed by key.
+// It panics if key is shorter than core.MinKeyLen.
+func New(key []byte, source Source) *Proxy {
+	if len(key) < core.MinKeyLen {
+		panic("imageproxy.New: the key must be at least 32 bytes")
+	}
+	return &Proxy{
+		Source:          source,
+		Key:             key,
+		Cache:           core.NewCache("images", 500, 24*time.Hour),
+		MaxAge:          365 * 24 * time.Hour,
+		MaxSize:         4096,
+		MaxSourceBytes:  20 << 20,
+		MaxSourcePixels: 40 << 20,
+		flight:          core.NewSingleflight(),
+	}
+}
+
+// Mount serves the images under prefix in group, for the URLs of URL.
+func (p *Proxy) Mount(group *core.RouterGroup, prefix string) *core.Route {
+	prefix = "/" + strings.Trim(prefix, "/")
+	p.prefix = path.Join(group.BasePath(), prefix) + "/"
+	return group.GET(prefix+"/*filepath", p.Handler)
+}
+
+// URL returns the signed URL of the image name transformed per opts, under the prefix of Mount.
+func (p *Proxy) URL(name string, opts Options) string {
+	name = strings.TrimPrefix(name, "/")
+	v := opts.values()
+	v.Set("s", p.sign(name, v))
+	return p.prefix + (&url.URL{Path: name}).EscapedPath() + "?" + v.Encode()
+}
+
+// sign returns the signature of the image name with the query parameters v.
+func (p *Proxy) sign(name string, v url.Values) string {
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(name + "?" + v.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cachedImage is a transformed image.
+type cachedImage struct {
+	contentType string
+	data        []byte
+}
+
+// Handler serves the transformed image of the request.
+func (p *Proxy) Handler(ctx *core.Context) {
+	name := strings.TrimPrefix(ctx.Param("filepath"), "/")
+	q := ctx.Request.URL.Query()
+	opts, err := parseOptions(q, p.MaxSize)
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	v := opts.values()
+	sig := p.sign(name, v)
+	if hmac.Equal([]byte(sig), []byte(q.Get("s"))) == false {
+		ctx.Fail((&core.ForbiddenError{}).New("invalid image signature"))
+		return
+	}
+	etag := `"` + sig + `"`
+	if ctx.Request.Header.Get("If-None-Match") == etag {
+		ctx.Empty(http.StatusNotModified)
+		return
+	}
+
+	key := name + "?" + v.Encode()
+	var img *cachedImage
+	if p.Cache != nil {
+		if cached, ok := p.Cache.Get(key); ok == true {
+			img = cached.(*cachedImage)
+		}
+	}
+	if img == nil {
+		res, err := ctx.Singleflight(p.flight, key, func(c context.Context) (interface{}, error) {
+			return p.render(c, name, opts)
+		})
+		if err != nil {
+			p.fail(ctx, name, err)
+			return
+		}
+		img = res.(*cachedImage)
+		if p.Cache != nil {
+			p.Cache.Set(key, img)
+		}
+	}
+	h := ctx.ResponseWriter.Header()
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(p.MaxAge/time.Second))+", immutable")
+	h.Set("ETag", etag)
+	ctx.Blob(img.contentType, img.data)
+}
+
+// fail responds with the error of the rendering of the image name.
+func (p *Proxy) fail(ctx *core.Context, name string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		ctx.Fail((&core.NotFoundError{}).New("image not found"))
+	case errors.Is(err, image.ErrFormat):
+		ctx.Fail((&core.ValidationError{}).New("unsupported image format"))
+	case errors.Is(err, ErrTooLarge):
+		e := (&core.ValidationError{}).New(err.Error())
+		e.HTTPCode = http.StatusRequestEntityTooLarge
+		ctx.Fail(e)
+	default:
+		log.WithFields(log.Fields{"image": name}).Warnln("imageproxy:", err.Error())
+		ctx.FailWithStatus(http.StatusBadGateway, err)
+	}
+}
+
+// render reads the source image name and transforms it per opts.
+func (p *Proxy) render(ctx context.Context, name string, opts Options) (*cachedImage, error) {
+	r, err := p.Source.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(r, p.MaxSourceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > p.MaxSourceBytes {
+		return nil, ErrTooLarge
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > p.MaxSourcePixels {
+		return nil, ErrTooLarge
+	}
+	src, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	dst := transform(src, opts)
+
+	if opts.Format != "" {
+		format = opts.Format
+	}
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		quality := opts.Quality
+		if quality == 0 {
+			quality = 85
+		}
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+		return &cachedImage{contentType: "image/jpeg", data: buf.Bytes()}, err
+	}
+	err = png.Encode(&buf, dst)
+	return &cachedImage{contentType: "image/png", data: buf.Bytes()}, err
+}
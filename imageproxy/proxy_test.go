@@ -0,0 +1,99 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HiLittleCat/core"
+)
+
+func TestProxy(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 6), A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	png.Encode(&encoded, src)
+	opens := 0
+	proxy := New([]byte("0123456789abcdef0123456789abcdef"), SourceFunc(func(ctx context.Context, name string) (io.ReadCloser, error) {
+		if name != "photos/a.png" {
+			return nil, fs.ErrNotExist
+		}
+		opens++
+		return ioutil.NopCloser(bytes.NewReader(encoded.Bytes())), nil
+	}))
+	engine := core.New()
+	proxy.Mount(engine.Group("/media"), "img")
+	hs := core.NewHandlersStack()
+	hs.Use(engine.Handler())
+	serve := func(target string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, tc := range []struct {
+		opts  Options
+		w, h  int
+		color uint8
+	}{
+		{Options{Width: 20}, 20, 10, 0},
+		{Options{Width: 10, Height: 10, Fit: Cover}, 10, 10, 60},
+		{Options{Width: 100}, 40, 20, 0},
+	} {
+		u := proxy.URL("photos/a.png", tc.opts)
+		if strings.HasPrefix(u, "/media/img/photos/a.png?") == false {
+			t.Fatalf("URL: got %s", u)
+		}
+		w := serve(u)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%+v: want 200, got %d %s", tc.opts, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+			t.Errorf("Cache-Control: got %q", got)
+		}
+		img, err := png.Decode(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("%+v: want %dx%d, got %dx%d", tc.opts, tc.w, tc.h, b.Dx(), b.Dy())
+		}
+		if r, _, _, _ := img.At(0, 0).RGBA(); uint8(r>>8) < tc.color || uint8(r>>8) > tc.color+6 {
+			t.Errorf("%+v: the first pixel is %d, want about %d", tc.opts, r>>8, tc.color)
+		}
+	}
+
+	u := proxy.URL("photos/a.png", Options{Width: 20})
+	serve(u)
+	if opens != 3 {
+		t.Errorf("cache: want 3 source reads, got %d", opens)
+	}
+	if w := serve(strings.Replace(u, "w=20", "w=21", 1)); w.Code != http.StatusForbidden {
+		t.Errorf("tampered: want 403, got %d", w.Code)
+	}
+	if w := serve(proxy.URL("photos/b.png", Options{Width: 20})); w.Code != http.StatusNotFound {
+		t.Errorf("missing: want 404, got %d", w.Code)
+	}
+	if w := serve(proxy.URL("photos/a.png", Options{Width: 20, Format: "jpeg"})); w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("jpeg: got %q", w.Header().Get("Content-Type"))
+	}
+	// The 40x20 source is beyond 799 pixels, rejected before its decoding.
+	proxy.MaxSourcePixels = 799
+	if w := serve(proxy.URL("photos/a.png", Options{Width: 30})); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too many pixels: want 413, got %d", w.Code)
+	}
+}
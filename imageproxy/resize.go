@@ -0,0 +1,87 @@
+package imageproxy
+
+import (
+	"image"
+	"image/draw"
+)
+
+// transform returns src resized and cropped per opts. The images are never upscaled.
+func transform(src image.Image, opts Options) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	w, h := opts.Width, opts.Height
+	if (w == 0 && h == 0) || sw == 0 || sh == 0 {
+		return src
+	}
+
+	crop := b
+	if opts.Fit == Cover && w > 0 && h > 0 {
+		// The largest centered region of the aspect ratio of the box.
+		cw, ch := sw, sw*h/w
+		if ch > sh {
+			cw, ch = sh*w/h, sh
+		}
+		x, y := b.Min.X+(sw-cw)/2, b.Min.Y+(sh-ch)/2
+		crop = image.Rect(x, y, x+cw, y+ch)
+		if w > cw {
+			w, h = cw, ch
+		}
+	} else {
+		// The box of the aspect ratio of the image fitting in w x h.
+		if w == 0 || (h > 0 && sw*h < sh*w) {
+			w = (sw*h + sh/2) / sh
+		} else {
+			h = (sh*w + sw/2) / sw
+		}
+		if w >= sw || h >= sh {
+			return src
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return resize(src, crop, w, h)
+}
+
+// resize returns the region r of src scaled to w x h, averaging the source pixels of each pixel.
+func resize(src image.Image, r image.Rectangle, w, h int) *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, r.Min, draw.Src)
+	if w == r.Dx() && h == r.Dy() {
+		return rgba
+	}
+
+	cw, ch := r.Dx(), r.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*ch/h, (y+1)*ch/h
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*cw/w, (x+1)*cw/w
+			if x1 == x0 {
+				x1++
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(rgba.Pix[i+c])
+					}
+					i += 4
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
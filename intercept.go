@@ -0,0 +1,81 @@
+package core
+
+import (
+	"io/ioutil"
+)
+
+// Interceptor rewrites the request before the next handlers, and the envelope of their response before it is written,
+// to keep a route backward compatible without touching its handler.
+//
+//	v1 := &core.Interceptor{
+//		Headers: map[string]string{"Accept-Version": "1"},
+//		Params:  map[string]string{"uid": "id"},
+//		Response: func(ctx *core.Context, res *core.ResFormat) {
+//			res.Data = map[string]interface{}{"user": res.Data}
+//		},
+//	}
+//	router.GET("/v1/users/:uid", v1.Handler, showUser)
+type Interceptor struct {
+	Headers  map[string]string                  // Sets the request headers, or deletes them when the value is empty.
+	Params   map[string]string                  // Renames the path params, from the key to the value.
+	Body     func(body []byte) ([]byte, error)  // Rewrites the request body. An error makes it fail, as a ValidationError.
+	Request  func(ctx *Context) error           // Rewrites the request. An error makes it fail.
+	Response func(ctx *Context, res *ResFormat) // Mutates the envelope of the success and fail responses.
+}
+
+// Handler rewrites the request, registers the response interceptor, and calls the next handler.
+func (i *Interceptor) Handler(ctx *Context) {
+	for k, v := range i.Headers {
+		if v == "" {
+			ctx.Request.Header.Del(k)
+			continue
+		}
+		ctx.Request.Header.Set(k, v)
+	}
+	if len(i.Params) > 0 {
+		params := make(Params, len(ctx.Params))
+		for j, p := range ctx.Params {
+			if to, ok := i.Params[p.Key]; ok == true {
+				p.Key = to
+			}
+			params[j] = p
+		}
+		ctx.Params = params
+	}
+	if i.Body != nil && ctx.Request.Body != nil {
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if err == nil {
+			body, err = i.Body(body)
+		}
+		if err != nil {
+			ctx.Fail((&ValidationError{}).New(err.Error()))
+			return
+		}
+		ctx.setBody(body)
+		ctx.Request.ContentLength = int64(len(body))
+	}
+	if i.Request != nil {
+		if err := i.Request(ctx); err != nil {
+			ctx.Fail(err)
+			return
+		}
+	}
+	if i.Response != nil {
+		ctx.addInterceptor(i.Response)
+	}
+	ctx.Next()
+}
+
+// addInterceptor registers a response interceptor for the request.
+func (ctx *Context) addInterceptor(f func(*Context, *ResFormat)) {
+	interceptors, _ := ctx.Data["interceptors"].([]func(*Context, *ResFormat))
+	ctx.Data["interceptors"] = append(interceptors, f)
+}
+
+// intercept applies the response interceptors to res, the innermost first.
+func (ctx *Context) intercept(res *ResFormat) {
+	interceptors, _ := ctx.Data["interceptors"].([]func(*Context, *ResFormat))
+	for j := len(interceptors) - 1; j >= 0; j-- {
+		interceptors[j](ctx, res)
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+// Lanes runs the requests of each priority class through a separate concurrency budget,
+// so that health checks and admin endpoints stay responsive when the bulk endpoints saturate the server.
+//
+// The routes are tagged with WithPriority, or PriorityFromHeader for the trusted callers, before the Lanes handler:
+//
+//	lanes := core.NewLanes("api", map[core.Priority]int{core.PriorityLow: 50, core.PriorityNormal: 200}, 100)
+//	bulk := core.Routers.Group("/export", core.WithPriority(core.PriorityLow), lanes.Handler)
+//
+// A priority without budget is not limited.
+type Lanes struct {
+	lanes map[Priority]*Bulkhead
+}
+
+// NewLanes returns new Lanes with the concurrency budgets by priority, each with a wait queue of maxQueue requests.
+func NewLanes(name string, budgets map[Priority]int, maxQueue int) *Lanes {
+	l := &Lanes{lanes: make(map[Priority]*Bulkhead, len(budgets))}
+	for p, n := range budgets {
+		l.lanes[p] = NewBulkhead(name+".lane."+p.String(), n, maxQueue)
+	}
+	return l
+}
+
+// Lane returns the Bulkhead of the priority p, nil if it is not limited.
+func (l *Lanes) Lane(p Priority) *Bulkhead {
+	return l.lanes[p]
+}
+
+// Handler is the middleware running the next handlers in the lane of the request priority.
+func (l *Lanes) Handler(ctx *Context) {
+	b := l.lanes[ctx.Priority()]
+	if b == nil {
+		ctx.Next()
+		return
+	}
+	b.Handler(ctx)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLanes(t *testing.T) {
+	lanes := NewLanes("test", map[Priority]int{PriorityNormal: 1}, 0)
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	serve := func(path, header string, handlers ...RouterHandler) int {
+		hs := NewHandlersStack()
+		for _, h := range handlers {
+			hs.Use(h)
+		}
+		hs.Use(lanes.Handler)
+		hs.Use(func(c *Context) {
+			if c.Request.URL.Path == "/slow" {
+				close(entered)
+				<-release
+			}
+			c.Ok(nil)
+		})
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(PriorityHeader, header)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	done := make(chan int)
+	go func() { done <- serve("/slow", "") }()
+	<-entered
+	// The normal lane is full: a client can't jump into the unlimited critical lane with the header.
+	if code := serve("/", "critical"); code != http.StatusServiceUnavailable {
+		t.Errorf("critical header: want 503 in the normal lane, got %d", code)
+	}
+	if code := serve("/", "critical", PriorityFromHeader(PriorityCritical)); code != http.StatusOK {
+		t.Errorf("trusted critical header: want 200, got %d", code)
+	}
+	if code := serve("/", "", WithPriority(PriorityLow)); code != http.StatusOK {
+		t.Errorf("low lane: want 200, got %d", code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("slow: want 200, got %d", code)
+	}
+	if lanes.Lane(PriorityNormal) == nil || lanes.Lane(PriorityHigh) != nil {
+		t.Error("Lane")
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LeakCheck detects the uses of a Context after its request has ended, e.g. by a goroutine not using ctx.Copy().
+// The contexts of the ended requests are poisoned instead of being reused, and their uses panic with the call site
+// when not in Production, or are logged with the stack in Production.
+// The uses are always counted in Metrics under "context_leak".
+// It costs a context allocation per request: it is for debugging.
+var LeakCheck bool
+
+// ErrContextReleased is returned by the response writer of a context used after its request has ended.
+var ErrContextReleased = errors.New("core: context used after its request ended")
+
+// releasedContext is the canceled request context of the poisoned contexts.
+var releasedContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// checkLive reports the use by method of ctx after its request has ended, when LeakCheck is set.
+// The generation of a context is odd while it serves a request, and even once it is back in the pool.
+func (ctx *Context) checkLive(method string) {
+	if LeakCheck == false || atomic.LoadUint32(&ctx.generation)&1 == 1 {
+		return
+	}
+	Metrics.Add("context_leak", 1)
+	msg := method + ": context used after its request ended, by " + callerLocation()
+	if Production == false {
+		panic(msg)
+	}
+	stack := make([]byte, 64<<10)
+	n := runtime.Stack(stack, false)
+	log.Warnln(msg + "\n" + string(stack[:n]))
+}
+
+// poison makes the fields of the released ctx fail loudly instead of reading the data of another request.
+func (ctx *Context) poison() {
+	r := &http.Request{Method: "RELEASED", URL: &url.URL{Path: "/released-context"}, Header: http.Header{}, Body: http.NoBody}
+	ctx.Request = r.WithContext(releasedContext)
+	ctx.ResponseWriter = leakWriter{ctx}
+	ctx.handlersStack.Handlers = nil
+}
+
+// leakWriter is the response writer of a poisoned context, which reports its uses.
+type leakWriter struct {
+	ctx *Context
+}
+
+func (w leakWriter) Header() http.Header {
+	w.ctx.checkLive("ResponseWriter.Header")
+	return http.Header{}
+}
+
+func (w leakWriter) Write([]byte) (int, error) {
+	w.ctx.checkLive("ResponseWriter.Write")
+	return 0, ErrContextReleased
+}
+
+func (w leakWriter) WriteHeader(int) {
+	w.ctx.checkLive("ResponseWriter.WriteHeader")
+}
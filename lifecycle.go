@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LifecycleHook is a function of the server lifecycle. Its context is done after the timeout of the hook.
+type LifecycleHook func(ctx context.Context) error
+
+// lifecycleHook is a registered hook.
+type lifecycleHook struct {
+	name    string
+	timeout time.Duration
+	fn      LifecycleHook
+}
+
+var onStart, onReady, onStop []lifecycleHook
+
+// LifecycleError aggregates the errors of the hooks of a lifecycle phase.
+type LifecycleError struct {
+	Phase  string // "start", "ready" or "stop".
+	Errors []error
+}
+
+func (e *LifecycleError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return e.Phase + " hooks failed: " + strings.Join(messages, "; ")
+}
+
+// OnStart registers fn to run by Run before listening, e.g. to open the database connections.
+// The start hooks run in registration order, and any error stops the server from starting.
+// The timeout of fn is 0 for none.
+func OnStart(name string, timeout time.Duration, fn LifecycleHook) {
+	onStart = append(onStart, lifecycleHook{name: name, timeout: timeout, fn: fn})
+}
+
+// OnReady registers fn to run once the server is listening, e.g. to start the schedulers. The errors are logged.
+func OnReady(name string, timeout time.Duration, fn LifecycleHook) {
+	onReady = append(onReady, lifecycleHook{name: name, timeout: timeout, fn: fn})
+}
+
+// OnStop registers fn to run once the server is stopped and its requests drained, e.g. to close the connections.
+// The stop hooks run in the reverse registration order, all of them even if some fail. The errors are logged.
+func OnStop(name string, timeout time.Duration, fn LifecycleHook) {
+	onStop = append(onStop, lifecycleHook{name: name, timeout: timeout, fn: fn})
+}
+
+// runHooks runs hooks in order, or in the reverse order, and returns their errors as a LifecycleError.
+// The start hooks stop at the first error.
+func runHooks(phase string, hooks []lifecycleHook, reverse bool) error {
+	var errs []error
+	for i := range hooks {
+		h := hooks[i]
+		if reverse == true {
+			h = hooks[len(hooks)-1-i]
+		}
+		if err := h.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			if phase == "start" {
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &LifecycleError{Phase: phase, Errors: errs}
+	}
+	return nil
+}
+
+// run runs the hook, and returns a timeout error if it doesn't return in time.
+func (h lifecycleHook) run() error {
+	ctx := context.Background()
+	if h.timeout <= 0 {
+		return h.fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- h.fn(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+}
+
+// logHooks logs the hooks error err, if any.
+func logHooks(err error) {
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string, err error) lifecycleHook {
+		return lifecycleHook{name: name, fn: func(ctx context.Context) error {
+			calls = append(calls, name)
+			return err
+		}}
+	}
+	slow := lifecycleHook{name: "slow", timeout: 10 * time.Millisecond, fn: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}}
+	hooks := []lifecycleHook{hook("db", nil), hook("cache", errors.New("refused")), slow, hook("scheduler", nil)}
+
+	err := runHooks("stop", hooks, true)
+	if got := strings.Join(calls, ","); got != "scheduler,cache,db" {
+		t.Errorf("stop order: want scheduler,cache,db, got %s", got)
+	}
+	if err == nil || err.Error() != "stop hooks failed: slow: timed out after 10ms; cache: refused" {
+		t.Errorf("stop error: got %v", err)
+	}
+
+	calls = nil
+	if err := runHooks("start", hooks, false); err == nil || len(err.(*LifecycleError).Errors) != 1 {
+		t.Errorf("start error: want the first one, got %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "db,cache" {
+		t.Errorf("start order: want db,cache, got %s", got)
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	// Locales are the supported locales, the first one is the default.
+	// Default is empty, any valid BCP 47 language tag is accepted, in its canonical form, and "en" is the default.
+	Locales []string
+
+	// LocaleParam is the query parameter and cookie overriding the locale.
+	LocaleParam = "lang"
+
+	// TimezoneParam is the query parameter and cookie giving the timezone, as an IANA name like "Europe/Paris".
+	TimezoneParam = "tz"
+
+	// TimezoneHeader is the request header giving the timezone.
+	TimezoneHeader = "Time-Zone"
+
+	// DefaultTimezone is the timezone of the requests without one.
+	DefaultTimezone = time.UTC
+
+	// UserLocale returns the locale and timezone of the user profile of the request, empty if unknown.
+	// It is used after the query and cookie overrides, before the Accept-Language and Time-Zone headers.
+	UserLocale func(ctx *Context) (locale, timezone string)
+)
+
+// Locale returns the locale of the request, from the LocaleParam query parameter or cookie, the UserLocale, or the Accept-Language header.
+// It is resolved once and kept in Context.Data["locale"].
+func (ctx *Context) Locale() string {
+	if l, ok := ctx.Data["locale"].(string); ok == true {
+		return l
+	}
+	l := ""
+	candidates := []string{ctx.Request.URL.Query().Get(LocaleParam), ctx.cookieValue(LocaleParam)}
+	if UserLocale != nil {
+		ul, _ := UserLocale(ctx)
+		candidates = append(candidates, ul)
+	}
+	for _, c := range candidates {
+		if l = matchLocale(c); l != "" {
+			break
+		}
+	}
+	if l == "" {
+		for _, a := range parseQualities(ctx.Request.Header.Get("Accept-Language")) {
+			if l = matchLocale(a.value); l != "" {
+				break
+			}
+		}
+	}
+	if l == "" {
+		l = "en"
+		if len(Locales) > 0 {
+			l = Locales[0]
+		}
+	}
+	ctx.Data["locale"] = l
+	return l
+}
+
+// Timezone returns the timezone of the request, from the TimezoneParam query parameter or cookie, the UserLocale, or the TimezoneHeader.
+// It is resolved once and kept in Context.Data["timezone"].
+func (ctx *Context) Timezone() *time.Location {
+	if tz, ok := ctx.Data["timezone"].(*time.Location); ok == true {
+		return tz
+	}
+	candidates := []string{ctx.Request.URL.Query().Get(TimezoneParam), ctx.cookieValue(TimezoneParam)}
+	if UserLocale != nil {
+		_, utz := UserLocale(ctx)
+		candidates = append(candidates, utz)
+	}
+	candidates = append(candidates, ctx.Request.Header.Get(TimezoneHeader))
+	tz := DefaultTimezone
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(c); err == nil {
+			tz = loc
+			break
+		}
+	}
+	ctx.Data["timezone"] = tz
+	return tz
+}
+
+func (ctx *Context) cookieValue(name string) string {
+	c, err := ctx.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// matchLocale returns the supported locale matching l exactly or by its language, or "".
+// Without Locales, it returns the canonical form of l if it is a valid language tag.
+func matchLocale(l string) string {
+	l = strings.TrimSpace(l)
+	if l == "" || l == "*" {
+		return ""
+	}
+	if len(Locales) == 0 {
+		tag, err := language.Parse(l)
+		if err != nil {
+			return ""
+		}
+		return tag.String()
+	}
+	for _, s := range Locales {
+		if strings.EqualFold(s, l) {
+			return s
+		}
+	}
+	lang := strings.SplitN(l, "-", 2)[0]
+	for _, s := range Locales {
+		if strings.EqualFold(strings.SplitN(s, "-", 2)[0], lang) {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocale(t *testing.T) {
+	defer func(locales []string, userLocale func(ctx *Context) (string, string)) {
+		Locales, UserLocale = locales, userLocale
+	}(Locales, UserLocale)
+	var locale, timezone string
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		locale, timezone = c.Locale(), c.Timezone().String()
+		c.Ok(nil)
+	})
+	profile := func(c *Context) (string, string) { return c.Request.Header.Get("X-Profile"), "Asia/Tokyo" }
+
+	tests := []struct {
+		name     string
+		locales  []string
+		profile  bool
+		target   string
+		headers  map[string]string
+		locale   string
+		timezone string
+	}{
+		{"default", nil, false, "/", nil, "en", "UTC"},
+		{"default of the locales", []string{"fr-FR", "en-US"}, false, "/", nil, "fr-FR", "UTC"},
+		{"header", nil, false, "/", map[string]string{"Accept-Language": "de-CH, de;q=0.8", "Time-Zone": "Europe/Berlin"}, "de-CH", "Europe/Berlin"},
+		{"header quality", []string{"en-US", "fr-FR"}, false, "/", map[string]string{"Accept-Language": "en;q=0.5, fr;q=0.9"}, "fr-FR", "UTC"},
+		{"language fallback", []string{"en-US", "pt-BR"}, false, "/", map[string]string{"Accept-Language": "pt-PT"}, "pt-BR", "UTC"},
+		{"exact match", []string{"pt-PT", "pt-BR"}, false, "/", map[string]string{"Accept-Language": "pt-br"}, "pt-BR", "UTC"},
+		{"unsupported", []string{"en-US", "fr-FR"}, false, "/", map[string]string{"Accept-Language": "ja"}, "en-US", "UTC"},
+		{"cookie over header", nil, false, "/", map[string]string{"Cookie": "lang=it; tz=Europe/Rome", "Accept-Language": "de"}, "it", "Europe/Rome"},
+		{"query over cookie", nil, false, "/?lang=es&tz=America/Mexico_City", map[string]string{"Cookie": "lang=it; tz=Europe/Rome"}, "es", "America/Mexico_City"},
+		{"user over header", nil, true, "/", map[string]string{"X-Profile": "nl", "Accept-Language": "de", "Time-Zone": "Europe/Berlin"}, "nl", "Asia/Tokyo"},
+		{"cookie over user", nil, true, "/", map[string]string{"X-Profile": "nl", "Cookie": "lang=it"}, "it", "Asia/Tokyo"},
+		{"invalid skipped", nil, false, "/?lang=<script>&tz=Nowhere/City", map[string]string{"Accept-Language": "not a tag, sv"}, "sv", "UTC"},
+		{"canonical form", nil, false, "/?lang=EN-gb", nil, "en-GB", "UTC"},
+	}
+	for _, tt := range tests {
+		Locales = tt.locales
+		UserLocale = nil
+		if tt.profile == true {
+			UserLocale = profile
+		}
+		r, _ := http.NewRequest("GET", tt.target, nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if locale != tt.locale || timezone != tt.timezone {
+			t.Errorf("%s: want %s %s, got %s %s", tt.name, tt.locale, tt.timezone, locale, timezone)
+		}
+	}
+}
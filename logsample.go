@@ -0,0 +1,74 @@
+package core
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogSampling samples the warnings logged by the framework, like the double writes and the client write errors,
+// so that a misbehaving client or handler doesn't flood the logs. Set it to nil to log them all.
+var LogSampling = NewLogSampler(10, 100, time.Second)
+
+// logDropped counts the dropped framework logs by key, published in Metrics under "log.dropped".
+var logDropped = new(expvar.Map).Init()
+
+func init() {
+	Metrics.Set("log.dropped", logDropped)
+}
+
+// LogSampler logs the First messages of a key per Interval, then every Thereafter-th one.
+type LogSampler struct {
+	First      int
+	Thereafter int // 0 drops all the messages beyond First.
+	Interval   time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*logCount
+}
+
+type logCount struct {
+	n     int
+	reset time.Time
+}
+
+// NewLogSampler returns a new LogSampler.
+func NewLogSampler(first, thereafter int, interval time.Duration) *LogSampler {
+	return &LogSampler{First: first, Thereafter: thereafter, Interval: interval, counts: make(map[string]*logCount)}
+}
+
+// Allow tells if a message of key is logged, and counts the dropped ones.
+func (s *LogSampler) Allow(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	c, ok := s.counts[key]
+	if ok == false || now.After(c.reset) {
+		if ok == false && len(s.counts) >= 1000 {
+			// The keys are of a few call sites, bound them anyway.
+			for k, c := range s.counts {
+				if now.After(c.reset) {
+					delete(s.counts, k)
+				}
+			}
+		}
+		c = &logCount{reset: now.Add(s.Interval)}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= s.First || (s.Thereafter > 0 && (c.n-s.First)%s.Thereafter == 0) {
+		return true
+	}
+	logDropped.Add(key, 1)
+	return false
+}
+
+// warn logs the warning msg of the request, sampled by LogSampling with key.
+func (ctx *Context) warn(key, msg string) {
+	if LogSampling != nil && LogSampling.Allow(key) == false {
+		return
+	}
+	log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(msg)
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Mail is an email message. It has a Text and an HTML body, or one of them.
+type Mail struct {
+	From    string // Like "Example <noreply@example.com>".
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string
+}
+
+// Mailer sends the emails, like SMTPMailer. The adapters of the email APIs implement it.
+type Mailer interface {
+	Send(ctx context.Context, email *Mail) error
+}
+
+// SMTPMailer is the Mailer of an SMTP server, with STARTTLS when the server supports it.
+type SMTPMailer struct {
+	Addr string // Like "smtp.example.com:587".
+	Auth smtp.Auth
+}
+
+// NewSMTPMailer returns a new SMTPMailer of the server addr, authenticated with username and password if set.
+func NewSMTPMailer(addr, username, password string) *SMTPMailer {
+	m := &SMTPMailer{Addr: addr}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send sends email to its recipients.
+func (m *SMTPMailer) Send(ctx context.Context, email *Mail) error {
+	b, err := email.Message()
+	if err != nil {
+		return err
+	}
+	from, err := mailAddress(email.From)
+	if err != nil {
+		return err
+	}
+	var to []string
+	for _, list := range [][]string{email.To, email.Cc, email.Bcc} {
+		for _, a := range list {
+			addr, err := mailAddress(a)
+			if err != nil {
+				return err
+			}
+			to = append(to, addr)
+		}
+	}
+	return smtp.SendMail(m.Addr, m.Auth, from, to, b)
+}
+
+// mailAddress returns the address of a, like "bob@example.com" of "Bob <bob@example.com>".
+func mailAddress(a string) (string, error) {
+	addr, err := mail.ParseAddress(a)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
+
+// Message returns the MIME message of email: multipart/alternative if it has the two bodies.
+func (email *Mail) Message() ([]byte, error) {
+	var b bytes.Buffer
+	h := textproto.MIMEHeader{}
+	h.Set("From", email.From)
+	if len(email.To) > 0 {
+		h.Set("To", strings.Join(email.To, ", "))
+	}
+	if len(email.Cc) > 0 {
+		h.Set("Cc", strings.Join(email.Cc, ", "))
+	}
+	if email.ReplyTo != "" {
+		h.Set("Reply-To", email.ReplyTo)
+	}
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	h.Set("Date", time.Now().Format(time.RFC1123Z))
+	id := make([]byte, 16)
+	rand.Read(id)
+	domain := "localhost"
+	if from, err := mailAddress(email.From); err == nil {
+		domain = from[strings.LastIndex(from, "@")+1:]
+	}
+	h.Set("Message-Id", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	h.Set("Mime-Version", "1.0")
+	for k, v := range email.Headers {
+		h.Set(k, v)
+	}
+
+	var parts [][2]string
+	if email.Text != "" || email.HTML == "" {
+		parts = append(parts, [2]string{"text/plain; charset=utf-8", email.Text})
+	}
+	if email.HTML != "" {
+		parts = append(parts, [2]string{"text/html; charset=utf-8", email.HTML})
+	}
+	if len(parts) == 1 {
+		h.Set("Content-Type", parts[0][0])
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeMailHeader(&b, h)
+		return b.Bytes(), writeQuotedPrintable(&b, parts[0][1])
+	}
+	mw := multipart.NewWriter(&b)
+	h.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	writeMailHeader(&b, h)
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {p[0]}, "Content-Transfer-Encoding": {"quoted-printable"}})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, p[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// writeMailHeader writes the header h, the standard fields first. The line breaks of the values are removed,
+// so that a value can't inject a field.
+func writeMailHeader(b *bytes.Buffer, h textproto.MIMEHeader) {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	for _, k := range []string{"From", "To", "Cc", "Reply-To", "Subject", "Date", "Message-Id", "Mime-Version"} {
+		if v := h.Get(k); v != "" {
+			b.WriteString(k + ": " + clean.Replace(v) + "\r\n")
+			h.Del(k)
+		}
+	}
+	for k, v := range h {
+		b.WriteString(k + ": " + clean.Replace(v[0]) + "\r\n")
+	}
+	b.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// Mails renders the emails from templates, and sends them with Mailer in the background.
+// The template <name> is made of "<name>.subject" and "<name>.txt" of Text, and "<name>.html" of HTML, like the templates of Assets:
+//
+//	mails := core.NewMails(core.NewSMTPMailer("smtp.example.com:587", user, password), "Example <noreply@example.com>")
+//	mails.HTML, _ = assets.Templates("mails/*.html")
+//	mails.Text = texttemplate.Must(texttemplate.ParseFS(public, "mails/*.txt"))
+//	core.Use(mails.Handler)
+//
+//	err := ctx.SendMail("welcome", user, user.Email)
+type Mails struct {
+	Mailer Mailer
+	From   string
+	HTML   *htmltemplate.Template // The HTML bodies, nil if there is none.
+	Text   *texttemplate.Template // The subjects and the text bodies.
+}
+
+// NewMails returns new Mails sent with mailer from the address from.
+func NewMails(mailer Mailer, from string) *Mails {
+	return &Mails{Mailer: mailer, From: from}
+}
+
+// Render renders the email of the template name with data, to the addresses to.
+func (m *Mails) Render(name string, data interface{}, to ...string) (*Mail, error) {
+	email := &Mail{From: m.From, To: to}
+	var b strings.Builder
+	if m.Text == nil || m.Text.Lookup(name+".subject") == nil {
+		return nil, (&ServerError{}).New("mail template " + name + ".subject not found")
+	}
+	if err := m.Text.ExecuteTemplate(&b, name+".subject", data); err != nil {
+		return nil, err
+	}
+	email.Subject = strings.TrimSpace(b.String())
+	if m.Text.Lookup(name+".txt") != nil {
+		b.Reset()
+		if err := m.Text.ExecuteTemplate(&b, name+".txt", data); err != nil {
+			return nil, err
+		}
+		email.Text = b.String()
+	}
+	if m.HTML != nil && m.HTML.Lookup(name+".html") != nil {
+		b.Reset()
+		if err := m.HTML.ExecuteTemplate(&b, name+".html", data); err != nil {
+			return nil, err
+		}
+		email.HTML = b.String()
+	}
+	if email.Text == "" && email.HTML == "" {
+		return nil, (&ServerError{}).New("mail template " + name + " has no body")
+	}
+	return email, nil
+}
+
+// Handler is the middleware enabling the ctx helper SendMail.
+func (m *Mails) Handler(ctx *Context) {
+	ctx.Data["mails"] = m
+	ctx.Next()
+}
+
+// SendMail renders the email of the template name of the Mails of the request with data, then sends it to the addresses to
+// in the background, see Context.Defer. The rendering errors are returned, the sending ones are retried then logged.
+func (ctx *Context) SendMail(name string, data interface{}, to ...string) error {
+	m, _ := ctx.Data["mails"].(*Mails)
+	if m == nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context: Mails.Handler isn't used")
+		return (&ServerError{}).New("mails aren't enabled")
+	}
+	email, err := m.Render(name, data, to...)
+	if err != nil {
+		return err
+	}
+	ctx.Defer("mail."+name, func(c context.Context) error {
+		return m.Mailer.Send(c, email)
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+	"time"
+)
+
+type mailerFunc func(ctx context.Context, m *Mail) error
+
+func (f mailerFunc) Send(ctx context.Context, m *Mail) error {
+	return f(ctx, m)
+}
+
+func TestMails(t *testing.T) {
+	sent := make(chan *Mail, 1)
+	mails := NewMails(mailerFunc(func(ctx context.Context, m *Mail) error {
+		sent <- m
+		return nil
+	}), "Example <noreply@example.com>")
+	mails.Text = texttemplate.Must(texttemplate.New("welcome.subject").Parse("Welcome {{.}}"))
+	texttemplate.Must(mails.Text.New("welcome.txt").Parse("Hello {{.}} & co"))
+	mails.HTML = htmltemplate.Must(htmltemplate.New("welcome.html").Parse("<p>Hello {{.}}</p>"))
+
+	engine := New()
+	engine.Use(mails.Handler)
+	engine.POST("/signup", func(ctx *Context) {
+		if err := ctx.SendMail("welcome", "Bob <b>", "bob@example.com"); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		if err := ctx.SendMail("missing", nil, "bob@example.com"); err == nil {
+			t.Error("missing template: want an error")
+		}
+		ctx.Ok(nil)
+	})
+	if w := serveRouter(engine, "POST", "/signup"); w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d %s", w.Code, w.Body.String())
+	}
+
+	var m *Mail
+	select {
+	case m = <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("the mail isn't sent")
+	}
+	if m.Subject != "Welcome Bob <b>" || m.Text != "Hello Bob <b> & co" || m.HTML != "<p>Hello Bob &lt;b&gt;</p>" {
+		t.Errorf("rendered: got %+v", m)
+	}
+	b, err := m.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(b)
+	for _, want := range []string{"From: Example <noreply@example.com>\r\n", "To: bob@example.com\r\n", "Subject: Welcome Bob <b>\r\n", "multipart/alternative", "Content-Type: text/html; charset=utf-8"} {
+		if strings.Contains(msg, want) == false {
+			t.Errorf("message: %q not found in %q", want, msg)
+		}
+	}
+
+	injected := &Mail{From: "noreply@example.com", To: []string{"bob@example.com"}, Subject: "Hi\r\nBcc: eve@example.com", Text: "hi"}
+	b, _ = injected.Message()
+	if strings.Contains(string(b), "\r\nBcc:") {
+		t.Errorf("header injection: %q", b)
+	}
+}
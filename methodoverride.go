@@ -0,0 +1,65 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MethodOverrideHeader is the header giving the method to use for a POST request.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideField is the form field giving the method to use for a POST request.
+const MethodOverrideField = "_method"
+
+// MethodOverrideMethods are the methods a POST request can be overridden with.
+var MethodOverrideMethods = []string{"PUT", "PATCH", "DELETE"}
+
+// MethodOverride is a handler rewriting the method of the POST requests, from the X-HTTP-Method-Override header or the _method form field,
+// for the clients and proxies that can only send GET and POST.
+// Only the MethodOverrideMethods are allowed. The original method is kept in Context.Data["originalMethod"].
+//
+// It must be used before the router:
+//
+//	core.Use(core.MethodOverride)
+func MethodOverride(ctx *Context) {
+	if ctx.Request.Method != "POST" {
+		ctx.Next()
+		return
+	}
+
+	method := ctx.Request.Header.Get(MethodOverrideHeader)
+	if method == "" {
+		method = methodOverrideField(ctx)
+	}
+	method = strings.ToUpper(method)
+	for _, m := range MethodOverrideMethods {
+		if m == method {
+			ctx.Data["originalMethod"] = ctx.Request.Method
+			ctx.Request.Method = method
+			break
+		}
+	}
+	ctx.Next()
+}
+
+// methodOverrideField reads the _method field of the query, or of an urlencoded form.
+// The form is read with BodyBytes, so the next readers still get the body. A body beyond BodyCacheLimit isn't overridden.
+func methodOverrideField(ctx *Context) string {
+	r := ctx.Request
+	if m := r.URL.Query().Get(MethodOverrideField); m != "" {
+		return m
+	}
+	cType := strings.Split(r.Header.Get("Content-Type"), ";")[0]
+	if r.Body == nil || cType != "application/x-www-form-urlencoded" {
+		return ""
+	}
+	body, err := ctx.BodyBytes()
+	if err != nil {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get(MethodOverrideField)
+}
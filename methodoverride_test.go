@@ -0,0 +1,54 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	limit := BodyCacheLimit
+	BodyCacheLimit = 64
+	defer func() { BodyCacheLimit = limit }()
+
+	var method, body string
+	hs := NewHandlersStack()
+	hs.Use(MethodOverride)
+	hs.Use(func(c *Context) {
+		method = c.Request.Method
+		b, _ := ioutil.ReadAll(c.Request.Body)
+		body = string(b)
+	})
+	tests := []struct {
+		method string
+		target string
+		header string
+		form   string
+		want   string
+	}{
+		{"POST", "/", "delete", "", "DELETE"},
+		{"POST", "/?_method=PUT", "", "", "PUT"},
+		{"POST", "/", "", "name=bob&_method=patch", "PATCH"},
+		{"POST", "/", "CONNECT", "", "POST"},
+		{"GET", "/", "DELETE", "", "GET"},
+		{"POST", "/", "", "_method=DELETE&name=" + strings.Repeat("a", 64), "POST"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.form))
+		if tt.header != "" {
+			r.Header.Set(MethodOverrideHeader, tt.header)
+		}
+		if tt.form != "" {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if method != tt.want {
+			t.Errorf("%s %s %q %q: want %s, got %s", tt.method, tt.target, tt.header, tt.form, tt.want, method)
+		}
+		if body != tt.form {
+			t.Errorf("%q: want the body for the next readers, got %q", tt.form, body)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package core
+
+import "expvar"
+
+// Metrics are the counters and gauges of the framework features, published with expvar under the "core" name.
+// They can be served with expvar.Handler(), or read with Metrics.Get.
+var Metrics = expvar.NewMap("core")
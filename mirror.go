@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// mirrorClient sends the mirrored requests of the Mirror without Client.
+var mirrorClient = &http.Client{Timeout: 5 * time.Second}
+
+// hopHeaders are the hop-by-hop headers, not forwarded to another server.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Mirror duplicates a percentage of the requests to a shadow upstream, to test a new version of a service with the production traffic.
+// The mirrored requests are fire-and-forget: they are sent asynchronously, their responses are discarded,
+// and they are dropped when MaxInFlight mirrored requests are already pending.
+// The requests with a body larger than MaxBody are not mirrored.
+//
+// The mirrored requests carry the X-Shadow-Request header. They are counted in Metrics under "mirror.sent", "mirror.dropped" and "mirror.errors".
+type Mirror struct {
+	Upstream    *url.URL
+	Percent     float64      // The percentage of mirrored requests, from 0 to 100.
+	MaxBody     int64        // NewMirror sets 1MB.
+	MaxInFlight int          // NewMirror sets 100.
+	Client      *http.Client // NewMirror sets a client with a 5 seconds timeout, a shared one is used if nil.
+
+	once sync.Once
+	sem  chan struct{}
+}
+
+// NewMirror returns a new Mirror of percent of the requests to upstream, with the default settings.
+func NewMirror(upstream string, percent float64) (*Mirror, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	return &Mirror{
+		Upstream:    u,
+		Percent:     percent,
+		MaxBody:     1 << 20,
+		MaxInFlight: 100,
+		Client:      &http.Client{Timeout: mirrorClient.Timeout},
+	}, nil
+}
+
+// Handler is the middleware mirroring the requests, before calling the next handlers.
+func (m *Mirror) Handler(ctx *Context) {
+	if m.Percent > 0 && rand.Float64()*100 < m.Percent {
+		m.mirror(ctx.Request)
+	}
+	ctx.Next()
+}
+
+func (m *Mirror) mirror(r *http.Request) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, m.MaxBody+1))
+		if err != nil || int64(len(body)) > m.MaxBody {
+			// Give the read part back to the handlers, and don't mirror.
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			Metrics.Add("mirror.dropped", 1)
+			return
+		}
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	m.once.Do(func() { m.sem = make(chan struct{}, m.MaxInFlight) })
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		Metrics.Add("mirror.dropped", 1)
+		return
+	}
+
+	u := *m.Upstream
+	u.Path = strings.TrimSuffix(u.Path, "/") + r.URL.Path
+	u.RawQuery = r.URL.RawQuery
+	req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		<-m.sem
+		Metrics.Add("mirror.errors", 1)
+		return
+	}
+	req.Header = cloneHeader(r.Header)
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	req.Header.Set("X-Shadow-Request", "1")
+
+	client := m.Client
+	if client == nil {
+		client = mirrorClient
+	}
+	go func() {
+		defer func() { <-m.sem }()
+		// A mirrored request must not take the server down.
+		defer func() {
+			if err := recover(); err != nil {
+				Metrics.Add("mirror.errors", 1)
+				log.Errorln("Mirror:", err)
+			}
+		}()
+		res, err := client.Do(req)
+		if err != nil {
+			Metrics.Add("mirror.errors", 1)
+			return
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		Metrics.Add("mirror.sent", 1)
+	}()
+}
+
+// readCloser reads from a reader and closes with a closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	type shadowed struct {
+		method, uri, body, shadow, keepAlive string
+	}
+	received := make(chan shadowed, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received <- shadowed{r.Method, r.URL.RequestURI(), string(b), r.Header.Get("X-Shadow-Request"), r.Header.Get("Keep-Alive")}
+	}))
+	defer upstream.Close()
+
+	m, err := NewMirror(upstream.URL+"/shadow/", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.MaxBody = 16
+	m.MaxInFlight = 1
+	var handled string
+	hs := NewHandlersStack()
+	hs.Use(m.Handler)
+	hs.Use(func(c *Context) {
+		b, _ := ioutil.ReadAll(c.Request.Body)
+		handled = string(b)
+		c.Ok(nil)
+	})
+	serve := func(body string) {
+		r, _ := http.NewRequest("POST", "/orders?id=1", strings.NewReader(body))
+		r.Header.Set("Keep-Alive", "timeout=5")
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		if handled != body {
+			t.Errorf("want the body %q for the handlers, got %q", body, handled)
+		}
+	}
+
+	serve(`{"total":10}`)
+	select {
+	case got := <-received:
+		want := shadowed{"POST", "/shadow/orders?id=1", `{"total":10}`, "1", ""}
+		if got != want {
+			t.Errorf("mirrored: want %+v, got %+v", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request wasn't mirrored")
+	}
+
+	// A body beyond MaxBody isn't mirrored, the handlers still get it.
+	serve(strings.Repeat("a", 32))
+
+	// No mirrored request when MaxInFlight ones are pending.
+	m.sem <- struct{}{}
+	serve("{}")
+	<-m.sem
+
+	m.Percent = 0
+	serve("{}")
+	select {
+	case got := <-received:
+		t.Errorf("want no more mirrored requests, got %+v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestMirrorLiteral checks that a Mirror without Client mirrors with the shared client.
+func TestMirrorLiteral(t *testing.T) {
+	received := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+	m := &Mirror{Upstream: u, Percent: 100, MaxInFlight: 1}
+	hs := NewHandlersStack()
+	hs.Use(m.Handler)
+	hs.Use(func(c *Context) { c.Ok(nil) })
+	r, _ := http.NewRequest("GET", "/orders", nil)
+	hs.ServeHTTP(httptest.NewRecorder(), r)
+	select {
+	case got := <-received:
+		if got != "/orders" {
+			t.Errorf("want /orders, got %s", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request wasn't mirrored")
+	}
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// serverTLSConfig returns the TLS configuration of the server, from TLSConfig, TLSCertFile, TLSKeyFile and ClientCAFile.
+func serverTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if TLSConfig != nil {
+		cfg = TLSConfig.Clone()
+	}
+	if TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(TLSCertFile, TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM(pem) == false {
+			return nil, errors.New("no certificate in " + ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = ClientAuth
+	}
+	return cfg, nil
+}
+
+// ClientCert returns the verified client certificate of the request, or nil if the client hasn't sent a certificate verified by ClientCAFile.
+// Its identities are its Subject.CommonName and its SANs: DNSNames, EmailAddresses, URIs and IPAddresses.
+func (ctx *Context) ClientCert() *x509.Certificate {
+	state := ctx.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// ClientCertAuth is the middleware authenticating the requests with their verified client certificate,
+// setting their ctx.Principal. The requests without a verified certificate fail with 401 Unauthorized.
+//
+//	auth := &core.ClientCertAuth{Identities: map[string][]string{
+//		"billing.internal": {"invoices:write"},
+//		"spiffe://example.org/reports": {"invoices:read"},
+//	}}
+//	internal := core.Routers.Group("/internal", auth.Handler)
+type ClientCertAuth struct {
+	// Identities maps the allowed certificate identities (common name or SAN) to their scopes.
+	// If nil, all the verified certificates are allowed, without scopes.
+	Identities map[string][]string
+
+	// Principal maps the certificate to the principal, instead of Identities.
+	Principal func(cert *x509.Certificate) (*Principal, error)
+}
+
+// Handler is the middleware authenticating the requests of the next handlers.
+func (a *ClientCertAuth) Handler(ctx *Context) {
+	cert := ctx.ClientCert()
+	if cert == nil {
+		ctx.Fail((&UnauthorizedError{}).New("client certificate required"))
+		return
+	}
+	if a.Principal != nil {
+		p, err := a.Principal(cert)
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.SetPrincipal(p)
+		ctx.Next()
+		return
+	}
+
+	identities := certIdentities(cert)
+	p := &Principal{ID: identities[0], Claims: map[string]interface{}{"identities": identities}}
+	if a.Identities != nil {
+		found := false
+		for _, id := range identities {
+			if scopes, ok := a.Identities[id]; ok == true {
+				p.ID, p.Scopes, found = id, scopes, true
+				break
+			}
+		}
+		if found == false {
+			ctx.Fail((&ForbiddenError{}).New("client certificate not allowed"))
+			return
+		}
+	}
+	ctx.SetPrincipal(p)
+	ctx.Next()
+}
+
+// certIdentities returns the common name then the SANs of cert.
+func certIdentities(cert *x509.Certificate) []string {
+	ids := []string{cert.Subject.CommonName}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		ids = append(ids, ip.String())
+	}
+	return ids
+}
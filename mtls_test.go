@@ -0,0 +1,45 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCertAuth(t *testing.T) {
+	auth := &ClientCertAuth{Identities: map[string][]string{"billing.internal": {"invoices:write"}}}
+	var principal *Principal
+	hs := NewHandlersStack()
+	hs.Use(auth.Handler)
+	hs.Use(func(c *Context) {
+		principal = c.Principal()
+		c.Ok(nil)
+	})
+
+	serve := func(cert *x509.Certificate) int {
+		r, _ := http.NewRequest("GET", "/internal", nil)
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(nil); code != http.StatusUnauthorized {
+		t.Errorf("no certificate: want 401, got %d", code)
+	}
+	if code := serve(&x509.Certificate{Subject: pkix.Name{CommonName: "reports"}}); code != http.StatusForbidden {
+		t.Errorf("unknown identity: want 403, got %d", code)
+	}
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, DNSNames: []string{"billing.internal"}}
+	if code := serve(cert); code != http.StatusOK {
+		t.Fatalf("known SAN: want 200, got %d", code)
+	}
+	if principal.ID != "billing.internal" || principal.HasScope("invoices:write") == false {
+		t.Errorf("principal: got %+v", principal)
+	}
+}
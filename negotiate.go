@@ -0,0 +1,166 @@
+package core
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accepts returns the offered media type preferred by the Accept header of the request, or "" if none is acceptable.
+// The ties are resolved by the order of offers, so the first one is the server preference, and the default without an Accept header.
+// Add "Accept" to the Vary header of the negotiated responses.
+//
+//	switch ctx.Accepts("application/json", "text/html") {
+//	case "text/html":
+//		ctx.HTML(http.StatusOK, page)
+//	case "":
+//		ctx.ResStatus(http.StatusNotAcceptable)
+//	default:
+//		ctx.Ok(data)
+//	}
+func (ctx *Context) Accepts(offers ...string) string {
+	return negotiate(ctx.Request.Header.Get("Accept"), offers, matchMediaType)
+}
+
+// AcceptsEncoding returns the offered content coding preferred by the Accept-Encoding header of the request, or "" if none is acceptable.
+// The "identity" coding is acceptable unless excluded by the header, with identity;q=0 or *;q=0.
+func (ctx *Context) AcceptsEncoding(offers ...string) string {
+	return negotiateEncoding(ctx.Request.Header.Get("Accept-Encoding"), offers)
+}
+
+// AcceptsLanguage returns the offered language tag preferred by the Accept-Language header of the request, or "" if none is acceptable.
+// A language range matches the tags it is a prefix of, like en for en-US.
+func (ctx *Context) AcceptsLanguage(offers ...string) string {
+	return negotiate(ctx.Request.Header.Get("Accept-Language"), offers, matchLanguage)
+}
+
+// quality is a value of a q-weighted header.
+type quality struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses a q-weighted header like Accept, in order, with the q=0 values excluding their matches.
+// The parameters of the values other than q are dropped.
+func parseAccept(header string) []quality {
+	var qs []quality
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		v := strings.TrimSpace(fields[0])
+		if v == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") || strings.HasPrefix(f, "Q=") {
+				if n, err := strconv.ParseFloat(f[2:], 64); err == nil && n >= 0 && n <= 1 {
+					q = n
+				}
+				// The parameters after q are accept extensions.
+				break
+			}
+		}
+		qs = append(qs, quality{v, q})
+	}
+	return qs
+}
+
+// parseQualities parses a q-weighted header like Accept-Language, sorted by decreasing quality. The values of quality 0 are removed.
+func parseQualities(header string) []quality {
+	var qs []quality
+	for _, q := range parseAccept(header) {
+		if q.q > 0 {
+			qs = append(qs, q)
+		}
+	}
+	sort.SliceStable(qs, func(i, j int) bool { return qs[i].q > qs[j].q })
+	return qs
+}
+
+// negotiate returns the offer of the best quality in header, the first one of the ties, or "" if none is acceptable.
+// The quality of an offer is the one of the most specific value of header matching it, match returning the specificity or -1.
+// Without a header, the first offer is returned.
+func negotiate(header string, offers []string, match func(value, offer string) int) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(header) == "" {
+		return offers[0]
+	}
+	return negotiateQualities(parseAccept(header), offers, match)
+}
+
+// negotiateQualities negotiates offers with the accepted qualities, like negotiate. The matches are case-insensitive.
+func negotiateQualities(accepted []quality, offers []string, match func(value, offer string) int) string {
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, a := range accepted {
+			if s := match(strings.ToLower(a.value), strings.ToLower(offer)); s > specificity {
+				q, specificity = a.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// negotiateEncoding negotiates the content coding of offers with the Accept-Encoding header, with identity acceptable by default.
+func negotiateEncoding(header string, offers []string) string {
+	if strings.TrimSpace(header) == "" {
+		for _, offer := range offers {
+			if strings.EqualFold(offer, "identity") {
+				return offer
+			}
+		}
+		return negotiate(header, offers, matchToken)
+	}
+	accepted := parseAccept(header)
+	for _, a := range accepted {
+		if strings.EqualFold(a.value, "identity") || a.value == "*" {
+			return negotiateQualities(accepted, offers, matchToken)
+		}
+	}
+	// The identity coding is acceptable when not excluded, after the codings of the header.
+	return negotiateQualities(append(accepted, quality{"identity", 0.001}), offers, matchToken)
+}
+
+// matchToken matches a token like a content coding: * matches any offer.
+func matchToken(value, offer string) int {
+	switch value {
+	case offer:
+		return 1
+	case "*":
+		return 0
+	}
+	return -1
+}
+
+// matchMediaType matches a media range: */* matches any offer, type/* the offers of type, type/subtype exactly.
+func matchMediaType(value, offer string) int {
+	offer = strings.TrimSpace(strings.Split(offer, ";")[0])
+	switch {
+	case value == offer:
+		return 2
+	case value == "*/*" || value == "*":
+		return 0
+	case strings.HasSuffix(value, "/*") && strings.HasPrefix(offer, value[:len(value)-1]):
+		return 1
+	}
+	return -1
+}
+
+// matchLanguage matches a language range: * matches any offer, a range the tags it is a prefix of, by subtags.
+// The specificity is the number of subtags of the range.
+func matchLanguage(value, offer string) int {
+	switch {
+	case value == "*":
+		return 0
+	case value == offer || strings.HasPrefix(offer, value+"-"):
+		return strings.Count(value, "-") + 1
+	}
+	return -1
+}
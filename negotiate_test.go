@@ -0,0 +1,41 @@
+package core
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		offers []string
+		match  func(value, offer string) int
+		want   string
+	}{
+		{"", []string{"application/json", "text/html"}, matchMediaType, "application/json"},
+		{"text/html, application/json;q=0.9", []string{"application/json", "text/html"}, matchMediaType, "text/html"},
+		{"text/*;q=0.5, */*;q=0.1", []string{"application/json", "text/html"}, matchMediaType, "text/html"},
+		{"*/*, text/html;q=0", []string{"text/html"}, matchMediaType, ""},
+		{"Application/JSON", []string{"application/json"}, matchMediaType, "application/json"},
+		{"fr-CH, fr;q=0.9, en;q=0.8", []string{"en-US", "fr-FR"}, matchLanguage, "fr-FR"},
+		{"de", []string{"en", "fr"}, matchLanguage, ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.header, tt.offers, tt.match); got != tt.want {
+			t.Errorf("%q %v: want %q, got %q", tt.header, tt.offers, tt.want, got)
+		}
+	}
+
+	encodings := []struct {
+		header, want string
+	}{
+		{"", "identity"},
+		{"gzip;q=0.5, zstd", "zstd"},
+		{"br", "identity"},
+		{"br, identity;q=0", ""},
+		{"*;q=0", ""},
+		{"gzip;q=0, *", "zstd"},
+	}
+	for _, tt := range encodings {
+		if got := negotiateEncoding(tt.header, []string{"gzip", "zstd", "identity"}); got != tt.want {
+			t.Errorf("encoding %q: want %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Notification is an SMS or a push notification.
+type Notification struct {
+	Channel string            `json:"channel"` // The provider of the notification, like "sms" or "push".
+	To      string            `json:"to"`      // The phone number, or the device token.
+	Title   string            `json:"title,omitempty"`
+	Body    string            `json:"body"`
+	Data    map[string]string `json:"data,omitempty"` // The payload of the push notifications.
+}
+
+// Notifier delivers the notifications of a channel. The adapters of the SMS and push providers implement it.
+// An error wrapped with Permanent, like an invalid phone number, isn't retried.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc is a function implementing Notifier.
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
+
+// LogNotifier is the Notifier logging the notifications instead of delivering them, for the development.
+var LogNotifier = NotifierFunc(func(ctx context.Context, n *Notification) error {
+	log.WithFields(log.Fields{"channel": n.Channel, "to": n.To, "title": n.Title}).Infoln("Notification: " + n.Body)
+	return nil
+})
+
+// WebhookNotifier is the Notifier posting the notifications in JSON to URL, like an SMS gateway.
+// The 4xx responses are Permanent errors, the other failures are retried.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string // Like the "Authorization" of the gateway.
+	Client  *http.Client      // Default is http.DefaultClient.
+}
+
+// Notify posts n to URL.
+func (w *WebhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(b))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		return Permanent(fmt.Errorf("webhook notifier: %s", res.Status))
+	}
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook notifier: %s", res.Status)
+	}
+	return nil
+}
+
+// Notifiers delivers the notifications with the Notifier of their channel, in the background with Tasks,
+// so that the OTP and the alerts are retried, drained on stop, logged and counted in Metrics
+// under "notifications.<channel>.sent":
+//
+//	notifiers := core.NewNotifiers().Register("sms", twilio).Register("push", fcm)
+//	core.Use(notifiers.Handler)
+//
+//	err := ctx.Notify(&core.Notification{Channel: "sms", To: user.Phone, Body: "Your code is " + code})
+type Notifiers struct {
+	Providers map[string]Notifier // The notifiers by channel.
+	Tasks     *Tasks              // The pool delivering the notifications, default is DefaultTasks.
+}
+
+// NewNotifiers returns new Notifiers, without provider.
+func NewNotifiers() *Notifiers {
+	return &Notifiers{Providers: map[string]Notifier{}, Tasks: DefaultTasks}
+}
+
+// Register sets the Notifier of channel.
+func (ns *Notifiers) Register(channel string, notifier Notifier) *Notifiers {
+	ns.Providers[channel] = notifier
+	return ns
+}
+
+// task returns the task delivering n, or an error if its channel has no provider.
+func (ns *Notifiers) task(n *Notification) (Task, error) {
+	notifier := ns.Providers[n.Channel]
+	if notifier == nil {
+		return nil, (&ServerError{}).New("no notifier of channel " + n.Channel)
+	}
+	return func(c context.Context) error {
+		if err := notifier.Notify(c, n); err != nil {
+			return err
+		}
+		Metrics.Add("notifications."+n.Channel+".sent", 1)
+		return nil
+	}, nil
+}
+
+// Send delivers n now, without retry.
+func (ns *Notifiers) Send(ctx context.Context, n *Notification) error {
+	task, err := ns.task(n)
+	if err != nil {
+		return err
+	}
+	return task(ctx)
+}
+
+// Queue queues n to Tasks. It doesn't block: it returns ErrTasksFull if the queue is full.
+func (ns *Notifiers) Queue(n *Notification) error {
+	task, err := ns.task(n)
+	if err != nil {
+		return err
+	}
+	return ns.Tasks.Go("notify."+n.Channel, task)
+}
+
+// Handler is the middleware enabling the ctx helper Notify.
+func (ns *Notifiers) Handler(ctx *Context) {
+	ctx.Data["notifiers"] = ns
+	ctx.Next()
+}
+
+// Notify delivers n with the Notifiers of the request in the background once the request is finished, see Context.Defer.
+// A channel without provider is returned, the delivery errors are retried then logged.
+func (ctx *Context) Notify(n *Notification) error {
+	ns, _ := ctx.Data["notifiers"].(*Notifiers)
+	if ns == nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context: Notifiers.Handler isn't used")
+		return (&ServerError{}).New("notifications aren't enabled")
+	}
+	task, err := ns.task(n)
+	if err != nil {
+		return err
+	}
+	ctx.deferTask(ns.Tasks, "notify."+n.Channel, task)
+	return nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifiers(t *testing.T) {
+	tasks := NewTasks("notify-test", 1, 10)
+	tasks.Backoff = time.Millisecond
+	defer tasks.Stop(context.Background())
+
+	var attempts int32
+	sent := make(chan *Notification, 1)
+	notifiers := NewNotifiers().Register("sms", NotifierFunc(func(ctx context.Context, n *Notification) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return errors.New("provider unavailable")
+		}
+		sent <- n
+		return nil
+	}))
+	notifiers.Tasks = tasks
+
+	engine := New()
+	engine.Use(notifiers.Handler)
+	engine.POST("/otp", func(ctx *Context) {
+		if err := ctx.Notify(&Notification{Channel: "sms", To: "+15550100", Body: "Your code is 123456"}); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		if err := ctx.Notify(&Notification{Channel: "fax", To: "+15550100"}); err == nil {
+			t.Error("unknown channel: want an error")
+		}
+		ctx.Ok(nil)
+	})
+	if w := serveRouter(engine, "POST", "/otp"); w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d %s", w.Code, w.Body.String())
+	}
+	select {
+	case n := <-sent:
+		if n.Body != "Your code is 123456" || atomic.LoadInt32(&attempts) != 2 {
+			t.Errorf("got %+v after %d attempts", n, attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the notification isn't retried")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	w := &WebhookNotifier{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := w.Notify(context.Background(), &Notification{Channel: "sms", To: "+15550100", Body: "hi"}); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+
+	tasks := NewTasks("webhook-test", 1, 10)
+	tasks.Backoff = time.Millisecond
+	unauthorized := NewNotifiers().Register("sms", &WebhookNotifier{URL: server.URL})
+	unauthorized.Tasks = tasks
+	if err := unauthorized.Queue(&Notification{Channel: "sms", To: "+15550100", Body: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	tasks.Stop(context.Background())
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("a 401 is permanent: want 2 calls, got %d", n)
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OneTimeToken is an issued one-time token.
+type OneTimeToken struct {
+	Purpose   string            `json:"purpose"` // Like "verify-email" or "magic-link", a token only verifies for its purpose.
+	Subject   string            `json:"subject"` // The user of the token.
+	Data      map[string]string `json:"data,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// TokenStore stores the one-time tokens by the hash of their value, so that a leak of the store doesn't leak the tokens.
+type TokenStore interface {
+	Save(hash string, token *OneTimeToken, ttl time.Duration) error
+	// Consume returns the token hash and deletes it atomically, so that it is returned once.
+	// It returns nil and no error if the token doesn't exist, expired or was consumed.
+	Consume(hash string) (*OneTimeToken, error)
+}
+
+// OneTimeTokens issues and verifies the opaque single-use tokens of the email verifications, the magic links
+// or the password resets. The token values are random, only their hash is stored until TTL.
+//
+//	tokens := core.NewOneTimeTokens(store.Tokens())
+//	core.Use(tokens.Handler)
+//	core.Routers.POST("/signup", func(ctx *core.Context) {
+//		token, err := ctx.IssueToken("verify-email", user.ID, nil)
+//		...
+//	})
+//	core.Routers.GET("/verify-email", tokens.Verify("verify-email"), func(ctx *core.Context) {
+//		verifyEmail(ctx.OneTimeToken().Subject)
+//	})
+type OneTimeTokens struct {
+	Store TokenStore    // Default is a MemoryTokenStore, local to the instance.
+	TTL   time.Duration // The validity of the tokens, default is an hour.
+}
+
+// NewOneTimeTokens returns a new OneTimeTokens in store, a MemoryTokenStore if nil.
+func NewOneTimeTokens(store TokenStore) *OneTimeTokens {
+	if store == nil {
+		store = NewMemoryTokenStore()
+	}
+	return &OneTimeTokens{Store: store, TTL: time.Hour}
+}
+
+// tokenHash returns the store key of the token value of purpose.
+func tokenHash(purpose, value string) string {
+	sum := sha256.Sum256([]byte(purpose + ":" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue returns the value of a new token of purpose for subject, valid for TTL.
+func (t *OneTimeTokens) Issue(purpose, subject string, data map[string]string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(b)
+	token := &OneTimeToken{Purpose: purpose, Subject: subject, Data: data, ExpiresAt: time.Now().Add(t.TTL)}
+	if err := t.Store.Save(tokenHash(purpose, value), token, t.TTL); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Consume verifies the token value of purpose and consumes it. The unknown, expired or already consumed tokens
+// fail with 401 Unauthorized.
+func (t *OneTimeTokens) Consume(purpose, value string) (*OneTimeToken, error) {
+	if value == "" {
+		return nil, (&UnauthorizedError{}).New("invalid or expired token")
+	}
+	token, err := t.Store.Consume(tokenHash(purpose, value))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.Purpose != purpose || time.Now().After(token.ExpiresAt) {
+		Metrics.Add("tokens."+purpose+".rejected", 1)
+		return nil, (&UnauthorizedError{}).New("invalid or expired token")
+	}
+	return token, nil
+}
+
+// Handler is the middleware enabling the ctx helpers IssueToken and ConsumeToken.
+func (t *OneTimeTokens) Handler(ctx *Context) {
+	ctx.Data["tokens"] = t
+	ctx.Next()
+}
+
+// Verify returns the middleware consuming the token of purpose of the token query parameter, see Context.OneTimeToken.
+func (t *OneTimeTokens) Verify(purpose string) RouterHandler {
+	return func(ctx *Context) {
+		token, err := t.Consume(purpose, ctx.Request.URL.Query().Get("token"))
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Data["oneTimeToken"] = token
+		ctx.Next()
+	}
+}
+
+// tokens returns the OneTimeTokens of the request.
+func (ctx *Context) tokens() (*OneTimeTokens, error) {
+	t, _ := ctx.Data["tokens"].(*OneTimeTokens)
+	if t == nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context: OneTimeTokens.Handler isn't used")
+		return nil, (&ServerError{}).New("one-time tokens aren't enabled")
+	}
+	return t, nil
+}
+
+// IssueToken returns the value of a new one-time token of purpose for subject, with the OneTimeTokens of the request.
+func (ctx *Context) IssueToken(purpose, subject string, data map[string]string) (string, error) {
+	t, err := ctx.tokens()
+	if err != nil {
+		return "", err
+	}
+	return t.Issue(purpose, subject, data)
+}
+
+// ConsumeToken verifies and consumes the one-time token value of purpose, with the OneTimeTokens of the request.
+func (ctx *Context) ConsumeToken(purpose, value string) (*OneTimeToken, error) {
+	t, err := ctx.tokens()
+	if err != nil {
+		return nil, err
+	}
+	return t.Consume(purpose, value)
+}
+
+// OneTimeToken returns the token consumed by the OneTimeTokens.Verify middleware, or nil.
+func (ctx *Context) OneTimeToken() *OneTimeToken {
+	token, _ := ctx.Data["oneTimeToken"].(*OneTimeToken)
+	return token
+}
+
+// MemoryTokenStore is a TokenStore in memory, local to the instance.
+type MemoryTokenStore struct {
+	mu     sync.Mutex // Makes Consume atomic.
+	tokens *Cache
+}
+
+// NewMemoryTokenStore returns a new MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: NewCache("", 0, 0)}
+}
+
+// Save saves the token hash for ttl.
+func (s *MemoryTokenStore) Save(hash string, token *OneTimeToken, ttl time.Duration) error {
+	s.tokens.SetTTL(hash, token, ttl)
+	return nil
+}
+
+// Consume returns the token hash and deletes it.
+func (s *MemoryTokenStore) Consume(hash string) (*OneTimeToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens.Get(hash)
+	if ok == false {
+		return nil, nil
+	}
+	s.tokens.Delete(hash)
+	return token.(*OneTimeToken), nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOneTimeTokens(t *testing.T) {
+	tokens := NewOneTimeTokens(nil)
+	engine := New()
+	engine.Use(tokens.Handler)
+	var issued string
+	engine.POST("/signup", func(ctx *Context) {
+		var err error
+		issued, err = ctx.IssueToken("verify-email", "bob", map[string]string{"email": "bob@example.com"})
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Ok(nil)
+	})
+	engine.GET("/verify-email", tokens.Verify("verify-email"), func(ctx *Context) {
+		token := ctx.OneTimeToken()
+		ctx.Text(http.StatusOK, token.Subject+" "+token.Data["email"])
+	})
+	engine.GET("/reset", tokens.Verify("reset-password"), func(ctx *Context) {
+		ctx.Text(http.StatusOK, "reset")
+	})
+
+	if w := serveRouter(engine, "POST", "/signup"); w.Code != http.StatusOK || len(issued) != 43 {
+		t.Fatalf("issue: got %d %q", w.Code, issued)
+	}
+	if w := serveRouter(engine, "GET", "/reset?token="+issued); w.Code != http.StatusUnauthorized {
+		t.Errorf("other purpose: want 401, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", "/verify-email?token="+issued); w.Code != http.StatusOK || w.Body.String() != "bob bob@example.com" {
+		t.Errorf("verify: got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveRouter(engine, "GET", "/verify-email?token="+issued); w.Code != http.StatusUnauthorized {
+		t.Errorf("consumed: want 401, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", "/verify-email"); w.Code != http.StatusUnauthorized {
+		t.Errorf("missing: want 401, got %d", w.Code)
+	}
+
+	tokens.TTL = -1
+	expired, _ := tokens.Issue("verify-email", "bob", nil)
+	if _, err := tokens.Consume("verify-email", expired); err == nil {
+		t.Error("expired: want an error")
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import "strings"
+
+// OpenAPI is the OpenAPI 3 document of the routes of an engine, generated from their documentation.
+type OpenAPI struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    OpenAPIInfo                             `json:"info"`
+	Paths   map[string]map[string]*OpenAPIOperation `json:"paths"`
+}
+
+// OpenAPIInfo is the info object of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIOperation is the operation of a route method in an OpenAPI document.
+type OpenAPIOperation struct {
+	Summary     string                       `json:"summary,omitempty"`
+	Description string                       `json:"description,omitempty"`
+	Tags        []string                     `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter           `json:"parameters,omitempty"`
+	Deprecated  bool                         `json:"deprecated,omitempty"`
+	Security    []map[string][]string        `json:"security,omitempty"`
+	Claims      map[string][]string          `json:"x-claims,omitempty"` // The claims required by the route.
+	Responses   map[string]map[string]string `json:"responses"`
+	Stability   Stability                    `json:"x-stability,omitempty"`
+}
+
+// OpenAPIParameter is a path parameter of an operation.
+type OpenAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+// OpenAPI returns the OpenAPI document of the routes, with title and version as its info.
+// The operations are documented by the RouteDoc of the routes; the routes of the Internal stability level are left out.
+func (engine *Engine) OpenAPI(title, version string) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: title, Version: version},
+		Paths:   map[string]map[string]*OpenAPIOperation{},
+	}
+	for _, info := range engine.Routes() {
+		op := &OpenAPIOperation{
+			Deprecated: info.Deprecated,
+			Responses:  map[string]map[string]string{"default": {"description": "The response envelope."}},
+			Stability:  Stable,
+		}
+		if info.Doc != nil {
+			if info.Doc.Stability == Internal {
+				continue
+			}
+			op.Summary, op.Description, op.Tags = info.Doc.Summary, info.Doc.Description, info.Doc.Tags
+			if info.Doc.Stability != "" {
+				op.Stability = info.Doc.Stability
+			}
+		}
+		if auth := openAPIAuth(info); auth != "" {
+			op.Security = []map[string][]string{{auth: append([]string{}, info.Scopes...)}}
+			op.Claims = info.Claims
+		}
+		for _, p := range info.Params {
+			op.Parameters = append(op.Parameters, OpenAPIParameter{Name: p, In: "path", Required: true, Schema: map[string]string{"type": "string"}})
+		}
+		path := openAPIPath(info.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(info.Method)] = op
+	}
+	return doc
+}
+
+// DefaultAuthScheme is the security scheme of the OpenAPI operations of the routes requiring scopes or claims,
+// without an Auth documentation.
+var DefaultAuthScheme = "bearer"
+
+// openAPIAuth returns the security scheme of the route of info, empty if the route is public.
+func openAPIAuth(info RouteInfo) string {
+	if info.Doc != nil && info.Doc.Auth != "" {
+		return info.Doc.Auth
+	}
+	if len(info.Scopes) > 0 || len(info.Claims) > 0 {
+		return DefaultAuthScheme
+	}
+	return ""
+}
+
+// openAPIPath returns the OpenAPI template of path, like /users/{id} for /users/:id.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// MountOpenAPI mounts the OpenAPI document of the routes of the router on its path, usually /openapi.json,
+// protected by the guards handlers if any.
+//
+//	core.MountOpenAPI(core.Routers, "/openapi.json", "Users API", "1.2.0")
+func MountOpenAPI(router *Engine, path, title, version string, guards ...RouterHandler) *Route {
+	return router.GET(path, append(guards, func(ctx *Context) {
+		ctx.ResFree(router.OpenAPI(title, version))
+	})...).Stability(Internal)
+}
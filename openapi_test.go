@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:id", listUsers).
+		Summary("Get a user").
+		Tags("users").
+		Auth("bearer").
+		Stability(Beta).
+		Options(RouteOptions{Scopes: []string{"users:read"}})
+	engine.GET("/users", listUsers)
+	engine.DELETE("/users/:id", listUsers).RequireScopes("users:write").RequireClaim("role", "admin")
+	MountOpenAPI(engine, "/openapi.json", "Users API", "1.0.0")
+
+	if doc := engine.Routes()[0].Doc; doc == nil || doc.Summary != "Get a user" || doc.Stability != Beta {
+		t.Errorf("route info: want the route doc, got %+v", doc)
+	}
+
+	w := serveRouter(engine, "GET", "/openapi.json")
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Summary    string                `json:"summary"`
+			Tags       []string              `json:"tags"`
+			Security   []map[string][]string `json:"security"`
+			Claims     map[string][]string   `json:"x-claims"`
+			Stability  string                `json:"x-stability"`
+			Parameters []OpenAPIParameter    `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	op := doc.Paths["/users/{id}"]["get"]
+	if op.Summary != "Get a user" || op.Stability != "beta" || len(op.Parameters) != 1 || op.Parameters[0].Name != "id" {
+		t.Errorf("operation: want the route doc, got %+v", op)
+	}
+	if len(op.Security) != 1 || op.Security[0]["bearer"][0] != "users:read" {
+		t.Errorf("security: want bearer with the route scopes, got %v", op.Security)
+	}
+	if op := doc.Paths["/users/{id}"]["delete"]; len(op.Security) != 1 || op.Security[0][DefaultAuthScheme][0] != "users:write" || op.Claims["role"][0] != "admin" {
+		t.Errorf("security: want the default scheme with the route scopes and claims, got %v %v", op.Security, op.Claims)
+	}
+	if len(doc.Paths["/users"]["get"].Security) != 0 {
+		t.Errorf("public route: want no security, got %v", doc.Paths["/users"]["get"].Security)
+	}
+	if doc.Paths["/users"]["get"].Stability != "stable" {
+		t.Errorf("undocumented route: want stable, got %+v", doc.Paths["/users"])
+	}
+	if _, ok := doc.Paths["/openapi.json"]; ok == true {
+		t.Error("internal route: want left out")
+	}
+}
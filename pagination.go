@@ -0,0 +1,130 @@
+package core
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// PaginationDefaultLimit is the limit of the requests without one.
+	PaginationDefaultLimit = 20
+
+	// PaginationMaxLimit is the maximum limit a request can ask.
+	PaginationMaxLimit = 100
+)
+
+// Pagination is the page asked by a list request, with the page, limit and cursor query parameters.
+type Pagination struct {
+	Page   int    // From 1.
+	Limit  int    // From 1 to PaginationMaxLimit.
+	Offset int    // The offset of the page, (Page-1)*Limit.
+	Cursor string // The opaque cursor of the page, for cursor-based lists. Page is ignored when it is set.
+}
+
+// PageMeta is the page extension of the success response of a list.
+type PageMeta struct {
+	Page  int    `json:"page,omitempty"`
+	Limit int    `json:"limit"`
+	Total *int64 `json:"total,omitempty"`
+	Next  string `json:"next,omitempty"` // The cursor of the next page, for cursor-based lists.
+	Prev  string `json:"prev,omitempty"` // The cursor of the previous page, for cursor-based lists.
+}
+
+// Pagination returns the page asked by the request.
+// It panics with a ValidationError, written as a fail response by Recover, if the page or limit isn't a positive integer,
+// or if the offset of the page is beyond math.MaxInt32. The limit is capped at PaginationMaxLimit.
+func (ctx *Context) Pagination() Pagination {
+	q := ctx.Request.URL.Query()
+	p := Pagination{Page: 1, Limit: PaginationDefaultLimit, Cursor: q.Get("cursor")}
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			panic((&ValidationError{}).New("page must be a positive integer"))
+		}
+		p.Page = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			panic((&ValidationError{}).New("limit must be a positive integer"))
+		}
+		p.Limit = n
+	}
+	if p.Limit > PaginationMaxLimit {
+		p.Limit = PaginationMaxLimit
+	}
+	// A zero PaginationDefaultLimit or PaginationMaxLimit is a limit of 1.
+	if p.Limit < 1 {
+		p.Limit = 1
+	}
+	if p.Cursor != "" {
+		p.Page = 0
+	}
+	// The offset fits in the integer columns of the databases, and doesn't overflow.
+	if p.Page-1 > math.MaxInt32/p.Limit {
+		panic((&ValidationError{}).New("page is too large"))
+	}
+	p.Offset = (p.Page - 1) * p.Limit
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// OkPage Response the items of a list page, with the page extension and the Link header (first, prev, next and last).
+// total is the count of all the items, a negative one is unknown and omitted.
+func (ctx *Context) OkPage(items interface{}, p Pagination, total int64, next, prev string) {
+	meta := &PageMeta{Page: p.Page, Limit: p.Limit, Next: next, Prev: prev}
+	if total >= 0 {
+		meta.Total = &total
+	}
+	if link := ctx.pageLinks(meta); link != "" {
+		ctx.ResponseWriter.Header().Set("Link", link)
+	}
+	ctx.ok(&ResFormat{Ok: true, Data: items, Page: meta})
+}
+
+// pageLinks returns the Link header of the page.
+func (ctx *Context) pageLinks(meta *PageMeta) string {
+	var links []string
+	link := func(rel string, set func(url.Values)) {
+		u := *ctx.Request.URL
+		q := u.Query()
+		set(q)
+		q.Set("limit", strconv.Itoa(meta.Limit))
+		u.RawQuery = q.Encode()
+		links = append(links, "<"+u.RequestURI()+`>; rel="`+rel+`"`)
+	}
+	if meta.Page == 0 {
+		// Cursor-based list.
+		if meta.Prev != "" {
+			link("prev", func(q url.Values) { q.Set("cursor", meta.Prev) })
+		}
+		if meta.Next != "" {
+			link("next", func(q url.Values) { q.Set("cursor", meta.Next) })
+		}
+		return strings.Join(links, ", ")
+	}
+	setPage := func(n int) func(url.Values) {
+		return func(q url.Values) { q.Set("page", strconv.Itoa(n)) }
+	}
+	link("first", setPage(1))
+	if meta.Page > 1 {
+		link("prev", setPage(meta.Page-1))
+	}
+	if meta.Total == nil {
+		link("next", setPage(meta.Page+1))
+		return strings.Join(links, ", ")
+	}
+	last := int((*meta.Total + int64(meta.Limit) - 1) / int64(meta.Limit))
+	if last < 1 {
+		last = 1
+	}
+	if meta.Page < last {
+		link("next", setPage(meta.Page+1))
+	}
+	link("last", setPage(last))
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	var p Pagination
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		p = c.Pagination()
+		c.Ok(nil)
+	})
+	tests := []struct {
+		query  string
+		code   int
+		page   int
+		limit  int
+		offset int
+	}{
+		{"", http.StatusOK, 1, 20, 0},
+		{"page=3&limit=10", http.StatusOK, 3, 10, 20},
+		{"limit=1000", http.StatusOK, 1, 100, 0},
+		{"cursor=abc&page=3", http.StatusOK, 0, 20, 0},
+		{"page=0", http.StatusBadRequest, 0, 0, 0},
+		{"limit=x", http.StatusBadRequest, 0, 0, 0},
+		{"page=" + strconv.Itoa(1<<40), http.StatusBadRequest, 0, 0, 0},
+		{"page=9223372036854775807&limit=100", http.StatusBadRequest, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		p = Pagination{}
+		r, _ := http.NewRequest("GET", "/items?"+tt.query, nil)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s: want %d, got %d %s", tt.query, tt.code, w.Code, w.Body.String())
+			continue
+		}
+		if tt.code == http.StatusOK && (p.Page != tt.page || p.Limit != tt.limit || p.Offset != tt.offset) {
+			t.Errorf("%s: want page %d limit %d offset %d, got %+v", tt.query, tt.page, tt.limit, tt.offset, p)
+		}
+	}
+
+	// Zero limits are a limit of 1, instead of a division by zero.
+	defer func(def, max int) { PaginationDefaultLimit, PaginationMaxLimit = def, max }(PaginationDefaultLimit, PaginationMaxLimit)
+	PaginationDefaultLimit, PaginationMaxLimit = 0, 0
+	r, _ := http.NewRequest("GET", "/items?page=3", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || p.Limit != 1 || p.Offset != 2 {
+		t.Errorf("zero limits: want limit 1 offset 2, got %d %+v", w.Code, p)
+	}
+}
+
+func TestOkPage(t *testing.T) {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.OkPage([]int{1, 2}, c.Pagination(), 45, "", "")
+	})
+	r, _ := http.NewRequest("GET", "/items?page=2&limit=20", nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	want := `</items?limit=20&page=1>; rel="first", </items?limit=20&page=1>; rel="prev", </items?limit=20&page=3>; rel="next", </items?limit=20&page=3>; rel="last"`
+	if got := w.Header().Get("Link"); got != want {
+		t.Errorf("Link: want %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,289 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// structValidate validates the patched and the bound structs.
+var structValidate = validator.New()
+
+// patchOp is an operation of a JSON Patch.
+type patchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from"`
+	Value interface{} `json:"value"`
+}
+
+// BindPatch applies the patch of the request body to target, a pointer to a struct.
+// The body is a JSON Patch (RFC 6902) with the application/json-patch+json Content-Type,
+// or a JSON Merge Patch (RFC 7386) with application/merge-patch+json or application/json.
+//
+// The patch is applied to a copy of target, validated with its validate tags: target is only changed when the patch is valid.
+// The returned error is an ICoreError to use with Fail.
+func (ctx *Context) BindPatch(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return (&ServerError{}).New("BindPatch: target must be a non-nil pointer")
+	}
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return (&ValidationError{}).New("invalid body: " + err.Error())
+	}
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, err := json.Marshal(target)
+	if err != nil {
+		return (&ServerError{}).New("BindPatch: " + err.Error())
+	}
+	var doc interface{}
+	json.Unmarshal(b, &doc)
+
+	switch strings.TrimSpace(strings.Split(ctx.Request.Header.Get("Content-Type"), ";")[0]) {
+	case "application/json-patch+json":
+		var ops []patchOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			return (&ValidationError{}).New("invalid JSON Patch: " + err.Error())
+		}
+		if doc, err = applyJSONPatch(doc, ops); err != nil {
+			return (&ValidationError{}).New(err.Error())
+		}
+	case "application/merge-patch+json", "application/json", "":
+		var patch interface{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			return (&ValidationError{}).New("invalid JSON Merge Patch: " + err.Error())
+		}
+		doc = mergePatch(doc, patch)
+	default:
+		e := (&ValidationError{}).New("unsupported patch Content-Type")
+		e.HTTPCode = http.StatusUnsupportedMediaType
+		return e
+	}
+
+	if b, err = json.Marshal(doc); err != nil {
+		return (&ServerError{}).New("BindPatch: " + err.Error())
+	}
+	patched := reflect.New(rv.Elem().Type())
+	if err := json.Unmarshal(b, patched.Interface()); err != nil {
+		return (&ValidationError{}).New("invalid patched value: " + err.Error())
+	}
+	if patched.Elem().Kind() == reflect.Struct {
+		if err := structValidate.Struct(patched.Interface()); err != nil {
+			return validatorError(err)
+		}
+	}
+	rv.Elem().Set(patched.Elem())
+	return nil
+}
+
+// validatorError converts the errors of the validator to a ValidationError with the per-field details.
+func validatorError(err error) error {
+	verrs, ok := err.(validator.ValidationErrors)
+	if ok == false {
+		return (&ValidationError{}).New(err.Error())
+	}
+	fields := make([]FieldError, len(verrs))
+	for i, e := range verrs {
+		fields[i] = FieldError{Field: e.Field(), Message: e.Field() + " fails the " + e.Tag() + " validation"}
+	}
+	return (&ValidationError{}).NewFields(fields...)
+}
+
+// mergePatch applies the JSON Merge Patch patch to doc.
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if ok == false {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if ok == false {
+		d = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+		} else {
+			d[k] = mergePatch(d[k], v)
+		}
+	}
+	return d
+}
+
+// applyJSONPatch applies the JSON Patch operations to doc.
+func applyJSONPatch(doc interface{}, ops []patchOp) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		switch op.Op {
+		case "add":
+			doc, err = pointerSet(doc, op.Path, op.Value, true)
+		case "remove":
+			doc, _, err = pointerRemove(doc, op.Path)
+		case "replace":
+			if _, err = pointerGet(doc, op.Path); err == nil {
+				doc, err = pointerSet(doc, op.Path, op.Value, false)
+			}
+		case "move":
+			var v interface{}
+			if doc, v, err = pointerRemove(doc, op.From); err == nil {
+				doc, err = pointerSet(doc, op.Path, v, true)
+			}
+		case "copy":
+			var v interface{}
+			if v, err = pointerGet(doc, op.From); err == nil {
+				doc, err = pointerSet(doc, op.Path, deepCopy(v), true)
+			}
+		case "test":
+			var v interface{}
+			if v, err = pointerGet(doc, op.Path); err == nil && !reflect.DeepEqual(v, op.Value) {
+				err = errors.New("test failed")
+			}
+		default:
+			err = errors.New("unknown operation " + strconv.Quote(op.Op))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JSON Patch operation %d (%s %s): %v", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) in unescaped tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, errors.New("invalid pointer")
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func pointerGet(doc interface{}, p string) (interface{}, error) {
+	tokens, err := parsePointer(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if ok == false {
+				return nil, errors.New("path not found")
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(d) {
+				return nil, errors.New("index out of range")
+			}
+			doc = d[i]
+		default:
+			return nil, errors.New("path not found")
+		}
+	}
+	return doc, nil
+}
+
+// pointerSet sets v at p in doc, inserting in arrays if insert is true.
+func pointerSet(doc interface{}, p string, v interface{}, insert bool) (interface{}, error) {
+	tokens, err := parsePointer(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return v, nil
+	}
+	parent, err := pointerGet(doc, parentPointer(tokens))
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch d := parent.(type) {
+	case map[string]interface{}:
+		d[last] = v
+		return doc, nil
+	case []interface{}:
+		i := len(d)
+		if last != "-" {
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(d) || (insert == false && i == len(d)) {
+				return nil, errors.New("index out of range")
+			}
+		}
+		if insert == false {
+			d[i] = v
+			return doc, nil
+		}
+		d = append(d, nil)
+		copy(d[i+1:], d[i:])
+		d[i] = v
+		// The slice may have moved, set it back in its parent.
+		return pointerSet(doc, parentPointer(tokens), d, false)
+	}
+	return nil, errors.New("path not found")
+}
+
+// pointerRemove removes the value at p in doc, and returns it.
+func pointerRemove(doc interface{}, p string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(p)
+	if err != nil || len(tokens) == 0 {
+		return nil, nil, errors.New("invalid pointer")
+	}
+	v, err := pointerGet(doc, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentPath := parentPointer(tokens)
+	parent, _ := pointerGet(doc, parentPath)
+	last := tokens[len(tokens)-1]
+	switch d := parent.(type) {
+	case map[string]interface{}:
+		delete(d, last)
+		return doc, v, nil
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		d = append(d[:i:i], d[i+1:]...)
+		doc, err = pointerSet(doc, parentPath, d, false)
+		return doc, v, err
+	}
+	return nil, nil, errors.New("path not found")
+}
+
+// parentPointer returns the pointer of the parent of the tokens, "" being the whole document.
+func parentPointer(tokens []string) string {
+	var p string
+	for _, t := range tokens[:len(tokens)-1] {
+		p += "/" + strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1)
+	}
+	return p
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(value))
+		for k, e := range value {
+			c[k] = deepCopy(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(value))
+		for i, e := range value {
+			c[i] = deepCopy(e)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchedUser struct {
+	Name  string   `json:"name" validate:"required"`
+	Email string   `json:"email,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+func bindPatch(t *testing.T, contentType, body string, target interface{}) error {
+	r, _ := http.NewRequest("PATCH", "/users/1", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	return (&Context{Request: r}).BindPatch(target)
+}
+
+func TestBindJSONPatch(t *testing.T) {
+	u := patchedUser{Name: "john", Email: "john@example.com", Tags: []string{"a", "c"}}
+	err := bindPatch(t, "application/json-patch+json", `[
+		{"op": "test", "path": "/name", "value": "john"},
+		{"op": "replace", "path": "/name", "value": "jane"},
+		{"op": "remove", "path": "/email"},
+		{"op": "add", "path": "/tags/1", "value": "b"},
+		{"op": "copy", "from": "/tags/0", "path": "/tags/-"}
+	]`, &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := patchedUser{Name: "jane", Tags: []string{"a", "b", "c", "a"}}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("want %+v, got %+v", want, u)
+	}
+
+	if err := bindPatch(t, "application/json-patch+json", `[{"op": "test", "path": "/name", "value": "john"}]`, &u); err == nil {
+		t.Error("failed test operation: want an error")
+	}
+}
+
+func TestBindMergePatch(t *testing.T) {
+	u := patchedUser{Name: "john", Email: "john@example.com"}
+	if err := bindPatch(t, "application/merge-patch+json", `{"email": null, "tags": ["x"]}`, &u); err != nil {
+		t.Fatal(err)
+	}
+	want := patchedUser{Name: "john", Tags: []string{"x"}}
+	if !reflect.DeepEqual(u, want) {
+		t.Errorf("want %+v, got %+v", want, u)
+	}
+
+	err := bindPatch(t, "application/merge-patch+json", `{"name": ""}`, &u)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("invalid patch: want a *ValidationError, got %v", err)
+	}
+	if u.Name != "john" {
+		t.Errorf("invalid patch: target changed to %+v", u)
+	}
+}
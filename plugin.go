@@ -0,0 +1,64 @@
+package core
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Plugin is a framework extension distributed as a module, e.g. metrics, auth or an admin UI, wired by UsePlugin.
+type Plugin interface {
+	Name() string
+	Init(hs *HandlersStack) error // Init is called first, with the handlers stack serving the plugin.
+	Routes(router IRouter)        // Routes registers the routes of the plugin.
+	Middleware() []RouterHandler  // Middleware returns the handlers added to the handlers stack, in order.
+	Shutdown() error              // Shutdown is called once the server is stopped.
+}
+
+// ErrPluginLoaded is returned by UsePlugin when a plugin of the same name is already loaded.
+var ErrPluginLoaded = errors.New("plugin already loaded")
+
+// UsePlugin loads the plugin p in the handlers stack, with its routes on router.
+// Its middleware is added to the handlers stack after the handlers already used.
+// If p is a ReadinessChecker too, its check is added to the readiness checks.
+func (hs *HandlersStack) UsePlugin(p Plugin, router IRouter) error {
+	for _, loaded := range hs.plugins {
+		if loaded.Name() == p.Name() {
+			return ErrPluginLoaded
+		}
+	}
+	if err := p.Init(hs); err != nil {
+		return err
+	}
+	for _, h := range p.Middleware() {
+		hs.Use(h)
+	}
+	p.Routes(router)
+	if c, ok := p.(ReadinessChecker); ok == true {
+		AddReadinessCheck(p.Name(), c.Ready)
+	}
+	hs.plugins = append(hs.plugins, p)
+	log.WithFields(log.Fields{"plugin": p.Name()}).Infoln("Plugin loaded")
+	return nil
+}
+
+// UsePlugin loads the plugin p in the default handlers stack, with its routes on Routers.
+func UsePlugin(p Plugin) error {
+	return defaultHandlersStack.UsePlugin(p, Routers)
+}
+
+// ShutdownPlugins shuts the loaded plugins down, in the reverse loading order, and returns the first error.
+func (hs *HandlersStack) ShutdownPlugins() error {
+	var first error
+	for i := len(hs.plugins) - 1; i >= 0; i-- {
+		p := hs.plugins[i]
+		if err := p.Shutdown(); err != nil {
+			log.WithFields(log.Fields{"plugin": p.Name()}).Errorln(err.Error())
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	hs.plugins = nil
+	return first
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+// PollInterval is the interval between two calls of the check function of ctx.Poll.
+var PollInterval = 500 * time.Millisecond
+
+// Poll calls check every PollInterval until it returns true, then responds with the data it returned.
+// When timeout elapses first, it responds with 204 No Content.
+// When the client goes away first, it stops without responding.
+//
+// It returns true if data has been responded.
+//
+//	router.GET("/notifications", func(ctx *core.Context) {
+//		ctx.Poll(30*time.Second, func() (interface{}, bool) {
+//			n := notifications.Since(ctx.Request.URL.Query().Get("since"))
+//			return n, len(n) > 0
+//		})
+//	})
+func (ctx *Context) Poll(timeout time.Duration, check func() (interface{}, bool)) bool {
+	if data, ok := check(); ok == true {
+		ctx.Ok(data)
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-timer.C:
+			ctx.noContent()
+			return false
+		case <-ticker.C:
+			if data, ok := check(); ok == true {
+				ctx.Ok(data)
+				return true
+			}
+		}
+	}
+}
+
+// PollChan waits for a value from ch and responds with it.
+// When timeout elapses or ch is closed first, it responds with 204 No Content.
+// When the client goes away first, it stops without responding.
+//
+// It returns true if data has been responded.
+func (ctx *Context) PollChan(timeout time.Duration, ch <-chan interface{}) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Request.Context().Done():
+		return false
+	case <-timer.C:
+	case data, ok := <-ch:
+		if ok == true {
+			ctx.Ok(data)
+			return true
+		}
+	}
+	ctx.noContent()
+	return false
+}
+
+// noContent responds with 204 No Content, which has no body.
+func (ctx *Context) noContent() {
+	if ctx.written == true {
+		return
+	}
+	ctx.written = true
+	ctx.finalizeHeader(http.StatusNoContent, 0)
+	ctx.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
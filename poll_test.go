@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	interval := PollInterval
+	PollInterval = time.Millisecond
+	defer func() { PollInterval = interval }()
+
+	serve := func(c context.Context, h RouterHandler) (*httptest.ResponseRecorder, bool) {
+		var responded bool
+		hs := NewHandlersStack()
+		hs.Use(func(ctx *Context) {
+			h(ctx)
+			responded = ctx.written
+		})
+		r := httptest.NewRequest("GET", "/poll", nil).WithContext(c)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w, responded
+	}
+
+	calls := 0
+	var polled bool
+	w, _ := serve(context.Background(), func(ctx *Context) {
+		polled = ctx.Poll(time.Second, func() (interface{}, bool) {
+			calls++
+			return "ready", calls == 3
+		})
+	})
+	if polled == false || calls != 3 || w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"data":"ready"`) == false {
+		t.Errorf("ready: want the data after 3 checks, got %t %d %d %s", polled, calls, w.Code, w.Body.String())
+	}
+
+	w, _ = serve(context.Background(), func(ctx *Context) {
+		polled = ctx.Poll(10*time.Millisecond, func() (interface{}, bool) { return nil, false })
+	})
+	if polled == true || w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("timeout: want 204, got %t %d %q", polled, w.Code, w.Body.String())
+	}
+
+	c, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, responded := serve(c, func(ctx *Context) {
+		polled = ctx.Poll(time.Second, func() (interface{}, bool) { return nil, false })
+	})
+	if polled == true || responded == true {
+		t.Errorf("client gone: want no response, got %t %t", polled, responded)
+	}
+}
+
+func TestPollChan(t *testing.T) {
+	serve := func(c context.Context, timeout time.Duration, ch <-chan interface{}) (*httptest.ResponseRecorder, bool) {
+		var polled bool
+		hs := NewHandlersStack()
+		hs.Use(func(ctx *Context) { polled = ctx.PollChan(timeout, ch) })
+		r := httptest.NewRequest("GET", "/poll", nil).WithContext(c)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w, polled
+	}
+
+	ch := make(chan interface{}, 1)
+	ch <- "event"
+	if w, polled := serve(context.Background(), time.Second, ch); polled == false || strings.Contains(w.Body.String(), `"data":"event"`) == false {
+		t.Errorf("value: want the event, got %t %s", polled, w.Body.String())
+	}
+	if w, polled := serve(context.Background(), 10*time.Millisecond, ch); polled == true || w.Code != http.StatusNoContent {
+		t.Errorf("timeout: want 204, got %t %d", polled, w.Code)
+	}
+	close(ch)
+	if w, polled := serve(context.Background(), time.Second, ch); polled == true || w.Code != http.StatusNoContent {
+		t.Errorf("closed: want 204, got %t %d", polled, w.Code)
+	}
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	if w, polled := serve(c, time.Second, make(chan interface{})); polled == true || w.Body.Len() != 0 {
+		t.Errorf("client gone: want no response, got %t %q", polled, w.Body.String())
+	}
+}
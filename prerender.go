@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Prerenderer renders the HTML of a page of a single-page application, as seen by a browser executing its scripts.
+type Prerenderer interface {
+	Render(ctx context.Context, pageURL string) ([]byte, error)
+}
+
+// PrerenderService is a Prerenderer calling a prerender service like prerender.io: GET <URL>/<page URL>.
+type PrerenderService struct {
+	URL     string // The URL of the service, like "https://service.prerender.io".
+	Token   string // The X-Prerender-Token header of the service, if any.
+	Client  *http.Client
+	MaxBody int64 // The max size of a rendered page, default is 5 MiB.
+}
+
+// Render renders pageURL with the service.
+func (s *PrerenderService) Render(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(s.URL, "/")+"/"+pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Prerender-Token", s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("prerender: " + strconv.Itoa(res.StatusCode) + " " + http.StatusText(res.StatusCode))
+	}
+	max := s.MaxBody
+	if max <= 0 {
+		max = 5 << 20
+	}
+	html, err := ioutil.ReadAll(io.LimitReader(res.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(html)) > max {
+		return nil, errors.New("prerender: page larger than " + strconv.FormatInt(max, 10) + " bytes")
+	}
+	return html, nil
+}
+
+// Prerender is the middleware serving the pages of a single-page application prerendered by Renderer to the crawlers,
+// for the SEO, while the other clients get the static shell of the next handlers.
+// The crawlers are the bots recognized by the BotDetector middleware used before it.
+// Only the GET requests of the paths without a file extension are prerendered, at BaseURL: the Host header of the
+// requests is sent by the clients.
+//
+// The page URLs keep only the QueryParams of the requests, so that the clients using a bot user agent can't get
+// a paid rendering per query string, and at most MaxConcurrent pages are rendered at once: the requests beyond are
+// counted in Metrics under "prerender.busy", and get the shell.
+//
+// The rendered pages are cached. When rendering fails, it is logged and counted in Metrics under "prerender.errors",
+// and the next handlers serve the shell.
+//
+//	prerender := core.NewPrerender("https://www.example.com", &core.PrerenderService{URL: "https://service.prerender.io", Token: token})
+//	core.Use(core.NewBotDetector().Handler, prerender.Handler)
+type Prerender struct {
+	BaseURL  string // The canonical scheme and host of the pages, like "https://www.example.com".
+	Renderer Prerenderer
+	Cache    *Cache        // The rendered pages by URL, default is 1000 pages for an hour.
+	Timeout  time.Duration // The timeout of a rendering, default is 20 seconds.
+
+	// QueryParams are the query params kept in the page URLs, like "page", default is none.
+	QueryParams []string
+
+	// MaxConcurrent is the maximum of concurrent renderings, NewPrerender sets 10. 0 is no limit.
+	MaxConcurrent int
+
+	flight *Singleflight
+	once   sync.Once
+	sem    chan struct{}
+}
+
+// errPrerenderBusy is the error of a rendering beyond MaxConcurrent.
+var errPrerenderBusy = errors.New("too many concurrent renderings")
+
+// NewPrerender returns a new Prerender rendering the pages of baseURL with renderer.
+func NewPrerender(baseURL string, renderer Prerenderer) *Prerender {
+	return &Prerender{
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		Renderer:      renderer,
+		Cache:         NewCache("prerender", 1000, time.Hour),
+		Timeout:       20 * time.Second,
+		MaxConcurrent: 10,
+		flight:        NewSingleflight(),
+	}
+}
+
+// Handler serves the prerendered page to the crawlers, or calls the next handlers.
+func (p *Prerender) Handler(ctx *Context) {
+	if p.prerenders(ctx) == false {
+		ctx.Next()
+		return
+	}
+	pageURL := p.pageURL(ctx.Request.URL)
+	if html, ok := p.Cache.Get(pageURL); ok == true {
+		p.serve(ctx, html.([]byte))
+		return
+	}
+	p.once.Do(func() {
+		if p.MaxConcurrent > 0 {
+			p.sem = make(chan struct{}, p.MaxConcurrent)
+		}
+	})
+	html, err := ctx.Singleflight(p.flight, pageURL, func(c context.Context) (interface{}, error) {
+		if p.sem != nil {
+			select {
+			case p.sem <- struct{}{}:
+				defer func() { <-p.sem }()
+			default:
+				return nil, errPrerenderBusy
+			}
+		}
+		c, cancel := context.WithTimeout(c, p.Timeout)
+		defer cancel()
+		return p.Renderer.Render(c, pageURL)
+	})
+	if err == errPrerenderBusy {
+		Metrics.Add("prerender.busy", 1)
+		ctx.Next()
+		return
+	}
+	if err != nil {
+		Metrics.Add("prerender.errors", 1)
+		log.WithFields(log.Fields{"url": pageURL}).Warnln("Prerender:", err.Error())
+		ctx.Next()
+		return
+	}
+	p.Cache.Set(pageURL, html)
+	p.serve(ctx, html.([]byte))
+}
+
+// pageURL returns the URL of the page of u at BaseURL, with its QueryParams only.
+func (p *Prerender) pageURL(u *url.URL) string {
+	pageURL := p.BaseURL + u.EscapedPath()
+	q := u.Query()
+	kept := url.Values{}
+	for _, k := range p.QueryParams {
+		if v, ok := q[k]; ok == true {
+			kept[k] = v
+		}
+	}
+	if len(kept) > 0 {
+		pageURL += "?" + kept.Encode()
+	}
+	return pageURL
+}
+
+// prerenders tells if the request is prerendered.
+func (p *Prerender) prerenders(ctx *Context) bool {
+	if ctx.Request.Method != "GET" || path.Ext(ctx.Request.URL.Path) != "" {
+		return false
+	}
+	return ctx.IsBot()
+}
+
+func (p *Prerender) serve(ctx *Context, html []byte) {
+	ctx.Header("X-Prerendered", "1")
+	ctx.HTML(http.StatusOK, string(html))
+}
@@ -0,0 +1,28 @@
+package core
+
+// Principal is the authenticated client of a request: a user, a service or an API key, with its granted scopes.
+type Principal struct {
+	ID     string
+	Scopes []string
+	Claims map[string]interface{} // The other attributes of the principal, like its tenant or its roles.
+}
+
+// HasScope tells if the principal has been granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	return stringIn(scope, p.Scopes)
+}
+
+// Principal returns the authenticated principal of the request, or nil if it isn't authenticated.
+func (ctx *Context) Principal() *Principal {
+	p, _ := ctx.Data["principal"].(*Principal)
+	return p
+}
+
+// SetPrincipal sets the authenticated principal of the request, for the next handlers.
+// It is called by the authentication middleware.
+func (ctx *Context) SetPrincipal(p *Principal) {
+	ctx.Data["principal"] = p
+}
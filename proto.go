@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProtoContentType is the content type of the protobuf requests and responses.
+const ProtoContentType = "application/x-protobuf"
+
+// protoMessage is implemented by the protobuf messages generated with gogo/protobuf.
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+var (
+	// ProtoMarshal marshals a protobuf message. Default uses its Marshal method,
+	// set it to proto.Marshal for the messages generated with google.golang.org/protobuf:
+	//
+	//	core.ProtoMarshal = func(msg interface{}) ([]byte, error) { return proto.Marshal(msg.(proto.Message)) }
+	ProtoMarshal = func(msg interface{}) ([]byte, error) {
+		if m, ok := msg.(protoMessage); ok == true {
+			return m.Marshal()
+		}
+		return nil, errors.New("core: ProtoMarshal isn't set")
+	}
+
+	// ProtoUnmarshal unmarshals a protobuf message. Default uses its Unmarshal method,
+	// set it to proto.Unmarshal for the messages generated with google.golang.org/protobuf.
+	ProtoUnmarshal = func(b []byte, msg interface{}) error {
+		if m, ok := msg.(protoMessage); ok == true {
+			return m.Unmarshal(b)
+		}
+		return errors.New("core: ProtoUnmarshal isn't set")
+	}
+)
+
+// BindProto decodes the request body into msg: as protobuf if its Content-Type is ProtoContentType, as JSON otherwise.
+// It panics a ValidationError if the body is invalid, like the Controller helpers, failing with 413 if it is larger than BodyCacheLimit.
+func (ctx *Context) BindProto(msg interface{}) {
+	body, err := ctx.BodyBytes()
+	if e, ok := err.(*ValidationError); ok == true {
+		panic(e)
+	}
+	if err != nil {
+		panic((&ValidationError{}).New("invalid body: " + err.Error()))
+	}
+	if isProtoType(ctx.Request.Header.Get("Content-Type")) == true {
+		err = ProtoUnmarshal(body, msg)
+	} else {
+		err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(body, msg)
+	}
+	if err != nil {
+		panic((&ValidationError{}).New("invalid body: " + err.Error()))
+	}
+}
+
+// Proto responds with msg: as protobuf if the request accepts ProtoContentType before JSON, in the Ok envelope otherwise.
+// So the same route can serve both the JSON and the protobuf clients.
+func (ctx *Context) Proto(msg interface{}) {
+	ctx.ResponseWriter.Header().Add("Vary", "Accept")
+	if acceptsProto(ctx.Request.Header.Get("Accept")) == false {
+		ctx.Ok(msg)
+		return
+	}
+	b, err := ProtoMarshal(msg)
+	if err != nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(err.Error())
+		ctx.Fail((&ServerError{}).New(http.StatusText(http.StatusInternalServerError)))
+		return
+	}
+	ctx.Blob(ProtoContentType, b)
+}
+
+// acceptsProto tells if the Accept header prefers protobuf to JSON.
+func acceptsProto(accept string) bool {
+	return isProtoType(negotiate(accept, []string{"application/json", ProtoContentType, "application/protobuf", "application/vnd.google.protobuf"}, matchMediaType))
+}
+
+// isProtoType tells if the media type of contentType is protobuf.
+func isProtoType(contentType string) bool {
+	t, _, _ := mime.ParseMediaType(contentType)
+	return t == ProtoContentType || t == "application/protobuf" || t == "application/vnd.google.protobuf"
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testProto is a protobuf message encoded as "proto:<name>".
+type testProto struct {
+	Name string `json:"name"`
+}
+
+func (m *testProto) Marshal() ([]byte, error) {
+	return []byte("proto:" + m.Name), nil
+}
+
+func (m *testProto) Unmarshal(b []byte) error {
+	if strings.HasPrefix(string(b), "proto:") == false {
+		return errors.New("invalid message")
+	}
+	m.Name = strings.TrimPrefix(string(b), "proto:")
+	return nil
+}
+
+func TestProto(t *testing.T) {
+	defer func(limit int64) { BodyCacheLimit = limit }(BodyCacheLimit)
+	BodyCacheLimit = 64
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		msg := new(testProto)
+		c.BindProto(msg)
+		c.Proto(msg)
+	})
+
+	tests := []struct {
+		contentType, accept, body string
+		code                      int
+		responseType, response    string
+	}{
+		{ProtoContentType, ProtoContentType, "proto:bob", http.StatusOK, ProtoContentType, "proto:bob"},
+		{"application/protobuf", "application/x-protobuf, application/json;q=0.9", "proto:bob", http.StatusOK, ProtoContentType, "proto:bob"},
+		{"application/json", "application/json", `{"name":"bob"}`, http.StatusOK, "application/json", `{"ok":true,"data":{"name":"bob"},"message":"","errno":0}`},
+		{ProtoContentType, "application/json, application/x-protobuf;q=0.5", "proto:bob", http.StatusOK, "application/json", `"name":"bob"`},
+		{ProtoContentType, "", "proto:bob", http.StatusOK, "application/json", `"name":"bob"`},
+		{"application/json", ProtoContentType, `{"name":"bob"}`, http.StatusOK, ProtoContentType, "proto:bob"},
+		{ProtoContentType, ProtoContentType, `{"name":"bob"}`, http.StatusBadRequest, "application/json", "invalid body"},
+		{"application/json", ProtoContentType, "proto:bob", http.StatusBadRequest, "application/json", "invalid body"},
+		{ProtoContentType, ProtoContentType, "proto:" + strings.Repeat("x", 64), http.StatusRequestEntityTooLarge, "application/json", "body too large"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		if w.Code != tt.code || strings.HasPrefix(w.Header().Get("Content-Type"), tt.responseType) == false || strings.Contains(w.Body.String(), tt.response) == false {
+			t.Errorf("%s %.20q accepting %q: want %d %s %q, got %d %s %q", tt.contentType, tt.body, tt.accept, tt.code, tt.responseType, tt.response, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if tt.code == http.StatusOK && varies(w.Header(), "Accept") == false {
+			t.Errorf("%s accepting %q: want Vary: Accept, got %q", tt.contentType, tt.accept, w.Header().Values("Vary"))
+		}
+	}
+}
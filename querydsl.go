@@ -0,0 +1,136 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// The filter operators.
+const (
+	OpEq   = "eq"
+	OpNe   = "ne"
+	OpGt   = "gt"
+	OpGte  = "gte"
+	OpLt   = "lt"
+	OpLte  = "lte"
+	OpIn   = "in" // The value is a comma-separated list.
+	OpLike = "like"
+)
+
+// QuerySpec allowlists the fields and operators a list request can sort and filter on.
+type QuerySpec struct {
+	Sort   []string            // The sortable fields.
+	Filter map[string][]string // The filterable fields, with their allowed operators.
+}
+
+// SortField is a field of the sort parameter.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a filter parameter, like filter[status]=active or filter[age][gte]=18.
+type Filter struct {
+	Field  string
+	Op     string
+	Values []string // A single value, but for OpIn.
+}
+
+// Value returns the first value of the filter.
+func (f Filter) Value() string {
+	if len(f.Values) == 0 {
+		return ""
+	}
+	return f.Values[0]
+}
+
+// ListQuery is the sort and filters of a list request.
+type ListQuery struct {
+	Sort    []SortField
+	Filters []Filter
+}
+
+// ListQuery parses the sort (?sort=-created_at,name) and filter (?filter[status]=active, ?filter[age][gte]=18) query parameters,
+// allowed by spec. The fields and operators that aren't allowed are returned as a ValidationError with the per-field details.
+// The filters are sorted by field and operator, so that the queries built from them are stable.
+func (ctx *Context) ListQuery(spec QuerySpec) (ListQuery, error) {
+	var (
+		q      ListQuery
+		errors []FieldError
+	)
+	query := ctx.Request.URL.Query()
+	for _, s := range strings.Split(query.Get("sort"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		f := SortField{Field: strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+"), Desc: strings.HasPrefix(s, "-")}
+		if stringIn(f.Field, spec.Sort) == false {
+			errors = append(errors, FieldError{"sort", "can't sort on " + f.Field})
+			continue
+		}
+		q.Sort = append(q.Sort, f)
+	}
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		field, op, ok := parseFilterKey(key)
+		if ok == false {
+			errors = append(errors, FieldError{key, "malformed filter"})
+			continue
+		}
+		ops, ok := spec.Filter[field]
+		if ok == false {
+			errors = append(errors, FieldError{key, "can't filter on " + field})
+			continue
+		}
+		if stringIn(op, ops) == false {
+			errors = append(errors, FieldError{key, "can't filter " + field + " with " + op})
+			continue
+		}
+		f := Filter{Field: field, Op: op, Values: values[:1]}
+		if op == OpIn {
+			f.Values = strings.Split(values[0], ",")
+		}
+		q.Filters = append(q.Filters, f)
+	}
+	sort.Slice(q.Filters, func(i, j int) bool {
+		if q.Filters[i].Field != q.Filters[j].Field {
+			return q.Filters[i].Field < q.Filters[j].Field
+		}
+		return q.Filters[i].Op < q.Filters[j].Op
+	})
+
+	if len(errors) > 0 {
+		return q, (&ValidationError{}).NewFields(errors...)
+	}
+	return q, nil
+}
+
+// parseFilterKey parses filter[field] and filter[field][op].
+func parseFilterKey(key string) (field, op string, ok bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	i := strings.Index(rest, "]")
+	if i <= 0 {
+		return "", "", false
+	}
+	field, rest = rest[:i], rest[i+1:]
+	if rest == "" {
+		return field, OpEq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", false
+	}
+	return field, rest[1 : len(rest)-1], true
+}
+
+func stringIn(s string, l []string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
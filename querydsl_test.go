@@ -0,0 +1,40 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestListQuery(t *testing.T) {
+	spec := QuerySpec{
+		Sort:   []string{"created_at", "name"},
+		Filter: map[string][]string{"status": {OpEq, OpIn}, "age": {OpGte, OpLt}},
+	}
+	r, _ := http.NewRequest("GET", "/users?sort=-created_at,name&filter[status][in]=active,invited&filter[age][gte]=18", nil)
+	ctx := &Context{Request: r}
+
+	q, err := ctx.ListQuery(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSort := []SortField{{"created_at", true}, {"name", false}}
+	if !reflect.DeepEqual(q.Sort, wantSort) {
+		t.Errorf("sort: want %v, got %v", wantSort, q.Sort)
+	}
+	wantFilters := []Filter{{"age", OpGte, []string{"18"}}, {"status", OpIn, []string{"active", "invited"}}}
+	if !reflect.DeepEqual(q.Filters, wantFilters) {
+		t.Errorf("filters: want %v, got %v", wantFilters, q.Filters)
+	}
+
+	r, _ = http.NewRequest("GET", "/users?sort=password&filter[age][like]=1&filter[role]=admin", nil)
+	ctx = &Context{Request: r}
+	_, err = ctx.ListQuery(spec)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("error: want a *ValidationError, got %v", err)
+	}
+	if fields := verr.Details.(*ErrorDetails).Fields; len(fields) != 3 {
+		t.Errorf("field errors: want 3, got %v", fields)
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// QuotaStore stores the usage counters of the quotas.
+type QuotaStore interface {
+	// Incr increments the counter key, expiring at expiry, and returns its new value.
+	Incr(key string, expiry time.Time) (int64, error)
+}
+
+// Quota enforces daily and monthly request budgets per principal: API key, user or tenant.
+// The responses get the X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset headers of the closest budget,
+// and the requests beyond a budget fail with Status and a Retry-After header until the budget resets.
+//
+//	quota := core.NewQuota("api", 10000, 200000)
+//	api := core.Routers.Group("/api", keys.Handler, quota.Handler)
+//
+// The budgets are counted in UTC days and months. The requests without a principal aren't counted:
+// the Quota handler comes after the authentication.
+// If the store fails, the requests are let through.
+type Quota struct {
+	Name    string
+	Daily   int64                     // The budget of a day, 0 for none.
+	Monthly int64                     // The budget of a month, 0 for none.
+	Key     func(ctx *Context) string // Returns the principal of the request, default is the ID of ctx.Principal.
+	Store   QuotaStore                // Default is a MemoryQuotaStore, local to the instance.
+	Status  int                       // The status of the requests beyond a budget, default is 429 Too Many Requests, 402 Payment Required is the other common choice.
+}
+
+// NewQuota returns a new Quota with the daily and monthly budgets.
+func NewQuota(name string, daily, monthly int64) *Quota {
+	return &Quota{
+		Name:    name,
+		Daily:   daily,
+		Monthly: monthly,
+		Key:     principalKey,
+		Store:   NewMemoryQuotaStore(),
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
+// Handler is the middleware counting the requests of the next handlers.
+func (q *Quota) Handler(ctx *Context) {
+	principal := q.Key(ctx)
+	if principal == "" {
+		ctx.Next()
+		return
+	}
+
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periods := []struct {
+		budget int64
+		key    string
+		reset  time.Time
+	}{
+		{q.Daily, day.Format("2006-01-02"), day.AddDate(0, 0, 1)},
+		{q.Monthly, month.Format("2006-01"), month.AddDate(0, 1, 0)},
+	}
+
+	remaining := int64(-1)
+	for _, p := range periods {
+		if p.budget <= 0 {
+			continue
+		}
+		n, err := q.Store.Incr("quota:"+q.Name+":"+principal+":"+p.key, p.reset)
+		if err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Quota: " + err.Error())
+			break
+		}
+		left := p.budget - n
+		if left < 0 {
+			left = 0
+		}
+		if remaining < 0 || left < remaining {
+			remaining = left
+			h := ctx.ResponseWriter.Header()
+			h.Set("X-Quota-Limit", strconv.FormatInt(p.budget, 10))
+			h.Set("X-Quota-Remaining", strconv.FormatInt(left, 10))
+			h.Set("X-Quota-Reset", strconv.FormatInt(p.reset.Unix(), 10))
+		}
+		if n > p.budget {
+			Metrics.Add("quota."+q.Name+".exceeded", 1)
+			err := (&BusinessError{}).New(0, "quota exceeded")
+			err.Details = &ErrorDetails{RetryAfter: int(p.reset.Sub(now)/time.Second) + 1}
+			ctx.FailWithStatus(q.Status, err)
+			return
+		}
+	}
+	ctx.Next()
+}
+
+// MemoryQuotaStore is a QuotaStore in memory, local to the instance.
+type MemoryQuotaStore struct {
+	mu       sync.Mutex // Makes Incr atomic.
+	counters *Cache
+}
+
+// NewMemoryQuotaStore returns a new MemoryQuotaStore.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counters: NewCache("", 0, 0)}
+}
+
+// Incr increments the counter key, expiring at expiry, and returns its new value.
+func (s *MemoryQuotaStore) Incr(key string, expiry time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.counters.Get(key); ok == true {
+		n := v.(*int64)
+		*n++
+		return *n, nil
+	}
+	n := int64(1)
+	s.counters.SetTTL(key, &n, time.Until(expiry))
+	return n, nil
+}
+
+// principalKey returns the ID of the authenticated principal of the request, or "" if there is none.
+func principalKey(ctx *Context) string {
+	if p := ctx.Principal(); p != nil {
+		return p.ID
+	}
+	return ""
+}
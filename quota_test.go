@@ -0,0 +1,48 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuota(t *testing.T) {
+	quota := NewQuota("test", 2, 10)
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		if user := c.Request.Header.Get("X-User"); user != "" {
+			c.SetPrincipal(&Principal{ID: user})
+		}
+		c.Next()
+	})
+	hs.Use(quota.Handler)
+	hs.Use(func(c *Context) { c.Ok(nil) })
+
+	serve := func(user string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", user)
+		r.Header.Set("X-API-Key", "shared")
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+	for i, want := range []string{"1", "0"} {
+		w := serve("alice")
+		if w.Code != http.StatusOK || w.Header().Get("X-Quota-Remaining") != want {
+			t.Errorf("request %d: want 200 with %s remaining, got %d with %q", i, want, w.Code, w.Header().Get("X-Quota-Remaining"))
+		}
+	}
+	w := serve("alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("exhausted: want 429 with Retry-After, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := serve("bob"); w.Code != http.StatusOK {
+		t.Errorf("other principal: want 200, got %d", w.Code)
+	}
+	// The unauthenticated requests aren't counted, whatever their X-API-Key header.
+	for i := 0; i < 3; i++ {
+		if w := serve(""); w.Code != http.StatusOK || w.Header().Get("X-Quota-Remaining") != "" {
+			t.Errorf("anonymous: want 200 without quota, got %d %q", w.Code, w.Header().Get("X-Quota-Remaining"))
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errInvalidRange is returned by parseRange when the Range header is malformed or not satisfiable.
+var errInvalidRange = errors.New("invalid range")
+
+// byteRange is a range of content, from start, of length bytes.
+type byteRange struct {
+	start, length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// ServeRange Response the content of size bytes, honoring the Range and If-Range headers of the request.
+// It writes a 206 Partial Content with one or several ranges (multipart/byteranges), a 416 Requested Range Not Satisfiable,
+// or a 200 with the whole content when there is no range.
+// It is meant for dynamically generated or proxied content, static files should use http.ServeContent.
+// The Content-Type header should be set before calling it, the zero modtime omits Last-Modified.
+func (ctx *Context) ServeRange(content io.ReadSeeker, size int64, modtime time.Time) {
+	if ctx.written == true {
+		ctx.doubleWrite("Context.ServeRange")
+		return
+	}
+	h := ctx.ResponseWriter.Header()
+	h.Set("Accept-Ranges", "bytes")
+	if modtime.IsZero() == false {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+
+	rangeHeader := ctx.Request.Header.Get("Range")
+	if rangeHeader != "" && ctx.ifRangeMatch(modtime) == false {
+		rangeHeader = ""
+	}
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil {
+		h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		ctx.ResStatus(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	var total int64
+	for _, r := range ranges {
+		total += r.length
+	}
+	if total > size {
+		// The ranges overlap too much, send the whole content.
+		ranges = nil
+	}
+
+	switch len(ranges) {
+	case 0:
+		ctx.finalizeHeader(http.StatusOK, size)
+		ctx.ResponseWriter.WriteHeader(http.StatusOK)
+		ctx.copyRange(content, byteRange{0, size})
+	case 1:
+		h.Set("Content-Range", ranges[0].contentRange(size))
+		ctx.finalizeHeader(http.StatusPartialContent, ranges[0].length)
+		ctx.ResponseWriter.WriteHeader(http.StatusPartialContent)
+		ctx.copyRange(content, ranges[0])
+	default:
+		ctx.serveMultiRange(content, size, ranges)
+	}
+}
+
+// serveMultiRange writes the ranges as a multipart/byteranges body.
+func (ctx *Context) serveMultiRange(content io.ReadSeeker, size int64, ranges []byteRange) {
+	h := ctx.ResponseWriter.Header()
+	cType := h.Get("Content-Type")
+	mw := multipart.NewWriter(ctx.ResponseWriter)
+	h.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	ctx.finalizeHeader(http.StatusPartialContent, -1)
+	ctx.ResponseWriter.WriteHeader(http.StatusPartialContent)
+	if ctx.Request.Method == "HEAD" {
+		return
+	}
+	for _, r := range ranges {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {cType},
+			"Content-Range": {r.contentRange(size)},
+		})
+		if err != nil {
+			return
+		}
+		if _, err := content.Seek(r.start, io.SeekStart); err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(err.Error())
+			return
+		}
+		if _, err := io.CopyN(part, content, r.length); err != nil {
+			return
+		}
+	}
+	mw.Close()
+}
+
+// copyRange writes the range r of content.
+func (ctx *Context) copyRange(content io.ReadSeeker, r byteRange) {
+	if ctx.Request.Method == "HEAD" {
+		return
+	}
+	if _, err := content.Seek(r.start, io.SeekStart); err != nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(err.Error())
+		return
+	}
+	io.CopyN(ctx.ResponseWriter, content, r.length)
+}
+
+// ifRangeMatch tells if the If-Range header of the request, if any, matches the response ETag or modtime.
+func (ctx *Context) ifRangeMatch(modtime time.Time) bool {
+	ir := ctx.Request.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, `W/"`) {
+		// A weak ETag never matches for ranges.
+		etag := ctx.ResponseWriter.Header().Get("ETag")
+		return strings.HasPrefix(ir, `"`) && ir == etag
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && modtime.IsZero() == false && modtime.Unix() == t.Unix()
+}
+
+// parseRange parses a Range header for a content of size bytes, following RFC 7233.
+func parseRange(s string, size int64) ([]byteRange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, errInvalidRange
+	}
+	var ranges []byteRange
+	for _, spec := range strings.Split(s[len(b):], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.Index(spec, "-")
+		if i < 0 {
+			return nil, errInvalidRange
+		}
+		start, end := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+		var r byteRange
+		if start == "" {
+			// Suffix range: the last n bytes.
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n <= 0 {
+				return nil, errInvalidRange
+			}
+			if n > size {
+				n = size
+			}
+			r = byteRange{size - n, n}
+		} else {
+			first, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || first < 0 {
+				return nil, errInvalidRange
+			}
+			if first >= size {
+				// Not satisfiable, it may be satisfied by another range.
+				continue
+			}
+			last := size - 1
+			if end != "" {
+				last, err = strconv.ParseInt(end, 10, 64)
+				if err != nil || last < first {
+					return nil, errInvalidRange
+				}
+				if last >= size {
+					last = size - 1
+				}
+			}
+			r = byteRange{first, last - first + 1}
+		}
+		if r.length > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, errInvalidRange
+	}
+	return ranges, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveRange(rangeHeader string) *httptest.ResponseRecorder {
+	hs := NewHandlersStack()
+	hs.Use(func(c *Context) {
+		c.Header("Content-Type", "text/plain")
+		c.ServeRange(strings.NewReader("0123456789"), 10, time.Time{})
+	})
+	r, _ := http.NewRequest("GET", "/", nil)
+	if rangeHeader != "" {
+		r.Header.Set("Range", rangeHeader)
+	}
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	return w
+}
+
+func TestServeRange(t *testing.T) {
+	tests := []struct {
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"", http.StatusOK, "", "0123456789"},
+		{"bytes=2-4", http.StatusPartialContent, "bytes 2-4/10", "234"},
+		{"bytes=7-", http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"bytes=-3", http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"bytes=5-100", http.StatusPartialContent, "bytes 5-9/10", "56789"},
+		{"bytes=20-30", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"items=1-2", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+	}
+	for _, tt := range tests {
+		w := serveRange(tt.rangeHeader)
+		if w.Code != tt.status {
+			t.Errorf("%q: status code: want %d, got %d", tt.rangeHeader, tt.status, w.Code)
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%q: Content-Range: want %q, got %q", tt.rangeHeader, tt.contentRange, got)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%q: body: want %q, got %q", tt.rangeHeader, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestServeMultiRange(t *testing.T) {
+	w := serveRange("bytes=0-1,8-9")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status code: want %d, got %d", http.StatusPartialContent, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "multipart/byteranges; boundary=") {
+		t.Errorf("Content-Type: want multipart/byteranges, got %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Content-Range: bytes 0-1/10", "\r\n\r\n01\r\n", "Content-Range: bytes 8-9/10", "\r\n\r\n89\r\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body: want to contain %q, got %q", want, body)
+		}
+	}
+}
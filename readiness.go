@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ReadinessTimeout is the timeout of the readiness checks of a readiness request.
+	ReadinessTimeout = 2 * time.Second
+
+	// ShutdownDelay is the time between the readiness flipping to failing and the listener closing on shutdown,
+	// for the load balancers to stop sending traffic. Default is 0, the listener closes at once.
+	ShutdownDelay time.Duration
+)
+
+// ReadinessCheck checks a dependency of the server, e.g. pings the database.
+type ReadinessCheck func(ctx context.Context) error
+
+// ReadinessChecker is implemented by the plugins checking their dependencies: UsePlugin registers their check.
+type ReadinessChecker interface {
+	Ready(ctx context.Context) error
+}
+
+var (
+	readinessMu     sync.RWMutex
+	readinessChecks = map[string]ReadinessCheck{}
+
+	// ready is 1 once the ready hooks have run, until the shutdown.
+	ready int32
+)
+
+// AddReadinessCheck registers the readiness check of name.
+func AddReadinessCheck(name string, check ReadinessCheck) {
+	readinessMu.Lock()
+	readinessChecks[name] = check
+	readinessMu.Unlock()
+}
+
+// Ready tells if the server is ready: started and not shutting down.
+func Ready() bool {
+	return atomic.LoadInt32(&ready) == 1
+}
+
+func setReady(on bool) {
+	if on == true {
+		atomic.StoreInt32(&ready, 1)
+		return
+	}
+	atomic.StoreInt32(&ready, 0)
+}
+
+// checkReadiness runs the readiness checks in parallel, and returns the errors by check name.
+func checkReadiness(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, ReadinessTimeout)
+	defer cancel()
+	readinessMu.RLock()
+	defer readinessMu.RUnlock()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make(map[string]string)
+	for name, check := range readinessChecks {
+		wg.Add(1)
+		go func(name string, check ReadinessCheck) {
+			defer wg.Done()
+			if err := check(ctx); err != nil {
+				mu.Lock()
+				failed[name] = err.Error()
+				mu.Unlock()
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return failed
+}
+
+// MountReadiness mounts the readiness endpoint of the load balancers on the path of the router.
+// It responds 503 Service Unavailable until the ready hooks and the warm-ups have run, during the shutdown, or when a readiness check fails,
+// with the failed checks in the details.
+//
+//	core.MountReadiness(core.Routers, "/ready")
+func MountReadiness(router *Engine, path string, guards ...RouterHandler) *Route {
+	return router.GET(path, append(guards, func(ctx *Context) {
+		if Ready() == false {
+			ctx.FailWithStatus(http.StatusServiceUnavailable, (&ServerError{}).New("not ready"))
+			return
+		}
+		if failed := checkReadiness(ctx.Request.Context()); len(failed) > 0 {
+			err := (&ServerError{}).New("readiness checks failed")
+			err.Details = failed
+			ctx.FailWithStatus(http.StatusServiceUnavailable, err)
+			return
+		}
+		ctx.Ok("ready")
+	})...)
+}
+
+// beforeShutdown flips the readiness to failing, and waits ShutdownDelay before the listener closes.
+func beforeShutdown() bool {
+	setReady(false)
+	time.Sleep(ShutdownDelay)
+	return true
+}
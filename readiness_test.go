@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestMountReadiness(t *testing.T) {
+	engine := New()
+	MountReadiness(engine, "/ready")
+	defer setReady(false)
+
+	if w := serveRouter(engine, "GET", "/ready"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("not started: want 503, got %d", w.Code)
+	}
+	setReady(true)
+	if w := serveRouter(engine, "GET", "/ready"); w.Code != http.StatusOK {
+		t.Errorf("ready: want 200, got %d", w.Code)
+	}
+
+	AddReadinessCheck("db", func(ctx context.Context) error { return errors.New("connection refused") })
+	defer func() {
+		readinessMu.Lock()
+		delete(readinessChecks, "db")
+		readinessMu.Unlock()
+	}()
+	w := serveRouter(engine, "GET", "/ready")
+	want := `{"ok":false,"data":null,"message":"readiness checks failed","errno":0,"details":{"db":"connection refused"}}`
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != want {
+		t.Errorf("failing check: want 503 %s, got %d %s", want, w.Code, w.Body.String())
+	}
+
+	beforeShutdown()
+	if Ready() == true {
+		t.Errorf("shutdown: want not ready")
+	}
+}
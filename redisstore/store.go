@@ -0,0 +1,270 @@
+// Package redisstore implements the stores of package core with a Redis client, so that the servers of a cluster share them.
+//
+//	store := redisstore.New(client, "myapp")
+//	quota := core.NewQuota("api", 10000, 200000)
+//	quota.Store = store.Quota()
+//	keys := core.NewAPIKeys("sk", store.APIKeys())
+package redisstore
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/HiLittleCat/core"
+	redis "gopkg.in/redis.v5"
+)
+
+// Store returns the core stores of a Redis client. Their keys are prefixed with "<Namespace>:<store>:".
+type Store struct {
+	Client    *redis.Client
+	Namespace string
+}
+
+// New returns a new Store of client, with its keys in namespace.
+func New(client *redis.Client, namespace string) *Store {
+	return &Store{Client: client, Namespace: namespace}
+}
+
+// key returns the Redis key of the key of the store kind.
+func (s *Store) key(kind, key string) string {
+	if s.Namespace == "" {
+		return kind + ":" + key
+	}
+	return s.Namespace + ":" + kind + ":" + key
+}
+
+// Quota returns the QuotaStore of s.
+func (s *Store) Quota() core.QuotaStore {
+	return &quotaStore{s}
+}
+
+// APIKeys returns the APIKeyStore of s.
+func (s *Store) APIKeys() core.APIKeyStore {
+	return &apiKeyStore{s}
+}
+
+// Nonces returns the NonceStore of s.
+func (s *Store) Nonces() core.NonceStore {
+	return &nonceStore{s}
+}
+
+// Attempts returns the AttemptStore of s.
+func (s *Store) Attempts() core.AttemptStore {
+	return &attemptStore{s}
+}
+
+// Tokens returns the TokenStore of s.
+func (s *Store) Tokens() core.TokenStore {
+	return &tokenStore{s}
+}
+
+// Refresh returns the RefreshStore of s.
+func (s *Store) Refresh() core.RefreshStore {
+	return &refreshStore{s}
+}
+
+type quotaStore struct {
+	*Store
+}
+
+// Incr increments the quota counter key, expiring at expiry, in one round trip.
+func (s *quotaStore) Incr(key string, expiry time.Time) (int64, error) {
+	k := s.key("quota", key)
+	var incr *redis.IntCmd
+	_, err := s.Client.TxPipelined(func(pipe *redis.Pipeline) error {
+		incr = pipe.Incr(k)
+		pipe.ExpireAt(k, expiry)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+type apiKeyStore struct {
+	*Store
+}
+
+// Save saves the API key, expiring with it.
+func (s *apiKeyStore) Save(key *core.APIKey) error {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if key.ExpiresAt.IsZero() == false {
+		ttl = time.Until(key.ExpiresAt)
+		if ttl <= 0 {
+			return s.Delete(key.ID)
+		}
+	}
+	return s.Client.Set(s.key("apikey", key.ID), b, ttl).Err()
+}
+
+// Get returns the API key id, or nil if it doesn't exist.
+func (s *apiKeyStore) Get(id string) (*core.APIKey, error) {
+	b, err := s.Client.Get(s.key("apikey", id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := new(core.APIKey)
+	if err := json.Unmarshal(b, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Delete deletes the API key id.
+func (s *apiKeyStore) Delete(id string) error {
+	return s.Client.Del(s.key("apikey", id)).Err()
+}
+
+type nonceStore struct {
+	*Store
+}
+
+// Seen records the nonce for ttl, and tells if it was already recorded.
+func (s *nonceStore) Seen(nonce string, ttl time.Duration) (bool, error) {
+	set, err := s.Client.SetNX(s.key("nonce", nonce), 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	return set == false, nil
+}
+
+type attemptStore struct {
+	*Store
+}
+
+// Get returns the record of the attempts key, or a zero record.
+func (s *attemptStore) Get(key string) (core.AttemptRecord, error) {
+	var record core.AttemptRecord
+	values, err := s.Client.HGetAll(s.key("attempts", key)).Result()
+	if err != nil {
+		return record, err
+	}
+	record.Failures, _ = strconv.Atoi(values["failures"])
+	if until, _ := strconv.ParseInt(values["lockedUntil"], 10, 64); until > 0 {
+		record.LockedUntil = time.Unix(0, until)
+	}
+	return record, nil
+}
+
+// Incr increments the failures of the attempts key with HINCRBY, kept for ttl, in one round trip.
+func (s *attemptStore) Incr(key string, ttl time.Duration) (int, error) {
+	k := s.key("attempts", key)
+	var incr *redis.IntCmd
+	_, err := s.Client.TxPipelined(func(pipe *redis.Pipeline) error {
+		incr = pipe.HIncrBy(k, "failures", 1)
+		pipe.Expire(k, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+// Lock locks the attempts key out until until, keeping its failures for ttl, in one round trip.
+func (s *attemptStore) Lock(key string, until time.Time, ttl time.Duration) error {
+	k := s.key("attempts", key)
+	_, err := s.Client.TxPipelined(func(pipe *redis.Pipeline) error {
+		pipe.HSet(k, "lockedUntil", strconv.FormatInt(until.UnixNano(), 10))
+		pipe.Expire(k, ttl)
+		return nil
+	})
+	return err
+}
+
+// Delete deletes the record of the attempts key.
+func (s *attemptStore) Delete(key string) error {
+	return s.Client.Del(s.key("attempts", key)).Err()
+}
+
+type tokenStore struct {
+	*Store
+}
+
+// Save saves the one-time token hash for ttl.
+func (s *tokenStore) Save(hash string, token *core.OneTimeToken, ttl time.Duration) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return s.Client.Set(s.key("token", hash), b, ttl).Err()
+}
+
+// Consume returns the one-time token hash and deletes it, in one transaction.
+func (s *tokenStore) Consume(hash string) (*core.OneTimeToken, error) {
+	k := s.key("token", hash)
+	var get *redis.StringCmd
+	_, err := s.Client.TxPipelined(func(pipe *redis.Pipeline) error {
+		get = pipe.Get(k)
+		pipe.Del(k)
+		return nil
+	})
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := new(core.OneTimeToken)
+	if err := json.Unmarshal([]byte(get.Val()), token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+type refreshStore struct {
+	*Store
+}
+
+// Save saves the refresh token hash for ttl.
+func (s *refreshStore) Save(hash string, token *core.RefreshToken, ttl time.Duration) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return s.Client.Set(s.key("refresh", hash), b, ttl).Err()
+}
+
+// Use returns the refresh token hash and marks it as used, in one transaction.
+func (s *refreshStore) Use(hash string) (*core.RefreshToken, bool, error) {
+	k := s.key("refresh", hash)
+	var get *redis.StringCmd
+	var used *redis.BoolCmd
+	_, err := s.Client.TxPipelined(func(pipe *redis.Pipeline) error {
+		get = pipe.Get(k)
+		used = pipe.SetNX(s.key("refresh-used", hash), 1, 0)
+		return nil
+	})
+	if err == redis.Nil {
+		s.Client.Del(s.key("refresh-used", hash))
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	token := new(core.RefreshToken)
+	if err := json.Unmarshal([]byte(get.Val()), token); err != nil {
+		return nil, false, err
+	}
+	s.Client.ExpireAt(s.key("refresh-used", hash), token.ExpiresAt)
+	return token, used.Val() == false, nil
+}
+
+// Revoke adds id to the revocation list for ttl.
+func (s *refreshStore) Revoke(id string, ttl time.Duration) error {
+	return s.Client.Set(s.key("revoked", id), 1, ttl).Err()
+}
+
+// Revoked tells if id is in the revocation list.
+func (s *refreshStore) Revoked(id string) (bool, error) {
+	return s.Client.Exists(s.key("revoked", id)).Result()
+}
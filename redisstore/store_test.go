@@ -0,0 +1,84 @@
+package redisstore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HiLittleCat/core"
+	redis "gopkg.in/redis.v5"
+)
+
+func TestKey(t *testing.T) {
+	if got := New(nil, "myapp").key("quota", "api:bob"); got != "myapp:quota:api:bob" {
+		t.Errorf("want myapp:quota:api:bob, got %s", got)
+	}
+	if got := New(nil, "").key("nonce", "n1"); got != "nonce:n1" {
+		t.Errorf("want nonce:n1, got %s", got)
+	}
+}
+
+// TestStores runs against the Redis server at REDIS_ADDR.
+func TestStores(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR isn't set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	s := New(client, "redisstore_test:"+time.Now().Format("150405.000"))
+
+	if n, err := s.Quota().Incr("bob", time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("quota: want 1, got %d %v", n, err)
+	}
+	if seen, err := s.Nonces().Seen("n1", time.Minute); err != nil || seen == true {
+		t.Errorf("nonce: want unseen, got %t %v", seen, err)
+	}
+	if seen, _ := s.Nonces().Seen("n1", time.Minute); seen == false {
+		t.Errorf("nonce: want seen")
+	}
+	key := &core.APIKey{ID: "k1", Principal: "bob"}
+	if err := s.APIKeys().Save(key); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.APIKeys().Get("k1"); err != nil || got == nil || got.Principal != "bob" {
+		t.Errorf("apikey: want bob, got %v %v", got, err)
+	}
+	for i := 1; i <= 3; i++ {
+		if n, err := s.Attempts().Incr("bob", time.Minute); err != nil || n != i {
+			t.Fatalf("attempts: want %d failures, got %d %v", i, n, err)
+		}
+	}
+	record := core.AttemptRecord{Failures: 3, LockedUntil: time.Now().Add(time.Minute)}
+	if err := s.Attempts().Lock("bob", record.LockedUntil, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Attempts().Get("bob"); err != nil || got.Failures != 3 || got.LockedUntil.Equal(record.LockedUntil) == false {
+		t.Errorf("attempts: want %v, got %v %v", record, got, err)
+	}
+	token := &core.OneTimeToken{Purpose: "verify-email", Subject: "bob", ExpiresAt: time.Now().Add(time.Minute)}
+	if err := s.Tokens().Save("h1", token, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Tokens().Consume("h1"); err != nil || got == nil || got.Subject != "bob" {
+		t.Errorf("token: want bob, got %v %v", got, err)
+	}
+	if got, err := s.Tokens().Consume("h1"); err != nil || got != nil {
+		t.Errorf("consumed token: want nil, got %v %v", got, err)
+	}
+	refresh := &core.RefreshToken{Family: "f1", Subject: "bob", ExpiresAt: time.Now().Add(time.Minute)}
+	if err := s.Refresh().Save("r1", refresh, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got, reused, err := s.Refresh().Use("r1"); err != nil || got == nil || got.Subject != "bob" || reused == true {
+		t.Errorf("refresh: want bob, got %v %v %v", got, reused, err)
+	}
+	if _, reused, err := s.Refresh().Use("r1"); err != nil || reused == false {
+		t.Errorf("reused refresh: want reused, got %v %v", reused, err)
+	}
+	if err := s.Refresh().Revoke("family:f1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := s.Refresh().Revoked("family:f1"); err != nil || revoked == false {
+		t.Errorf("revoked: want true, got %v %v", revoked, err)
+	}
+}
@@ -0,0 +1,302 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TokenPair is the response of the token endpoint, in the fields of OAuth 2.0.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // The validity of AccessToken in seconds.
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken is an issued refresh token. The tokens rotated from the same sign in share their Family.
+type RefreshToken struct {
+	Family    string    `json:"family"`
+	Subject   string    `json:"subject"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"` // The expiration of the family.
+}
+
+// RefreshStore stores the refresh tokens by the hash of their value, and the revocation list
+// of the families and the access tokens.
+type RefreshStore interface {
+	Save(hash string, token *RefreshToken, ttl time.Duration) error
+	// Use marks the token hash as used and returns it atomically, with reused if it was already used.
+	// It returns nil and no error if the token doesn't exist or expired.
+	Use(hash string) (token *RefreshToken, reused bool, err error)
+	Revoke(id string, ttl time.Duration) error
+	Revoked(id string) (bool, error)
+}
+
+// AuthTokens issues the short-lived access tokens and the refresh tokens of the token endpoint.
+// The access tokens are signed with Key, verified by Handler without a store lookup except the revocation list.
+// A refresh token is used once: it is rotated into a new pair, and a reused one, stolen then, revokes
+// its whole family with its access tokens.
+//
+//	tokens := core.NewAuthTokens(secret, store.Refresh())
+//	core.Routers.POST("/login", func(ctx *core.Context) {
+//		...
+//		pair, err := tokens.Issue(user.ID, user.Scopes)
+//		...
+//		ctx.Ok(pair)
+//	})
+//	core.Routers.POST("/token", tokens.TokenHandler)
+//	core.Routers.POST("/logout", tokens.RevokeHandler)
+//	api := core.Routers.Group("/api", tokens.Handler)
+type AuthTokens struct {
+	Key        []byte        // The key of the HMAC-SHA256 signatures of the access tokens, MinKeyLen bytes at least.
+	Store      RefreshStore  // Default is a MemoryRefreshStore, local to the instance.
+	AccessTTL  time.Duration // Default is 15 minutes.
+	RefreshTTL time.Duration // The validity of the refresh tokens of a sign in, default is 30 days.
+}
+
+// NewAuthTokens returns new AuthTokens signed with key, with their refresh tokens in store, a MemoryRefreshStore if nil.
+// It panics if key is shorter than MinKeyLen.
+func NewAuthTokens(key []byte, store RefreshStore) *AuthTokens {
+	if len(key) < MinKeyLen {
+		panic("core.NewAuthTokens: " + errShortKey)
+	}
+	if store == nil {
+		store = NewMemoryRefreshStore()
+	}
+	return &AuthTokens{Key: key, Store: store, AccessTTL: 15 * time.Minute, RefreshTTL: 30 * 24 * time.Hour}
+}
+
+// accessClaims are the claims of an access token.
+type accessClaims struct {
+	ID        string   `json:"jti"`
+	Subject   string   `json:"sub"`
+	Scopes    []string `json:"scp,omitempty"`
+	Family    string   `json:"fam"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func refreshHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue returns a new token pair of subject with scopes, starting a new family.
+func (t *AuthTokens) Issue(subject string, scopes []string) (*TokenPair, error) {
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(&RefreshToken{Family: family, Subject: subject, Scopes: scopes, ExpiresAt: time.Now().Add(t.RefreshTTL)})
+}
+
+// issue returns a new pair of the family of token.
+func (t *AuthTokens) issue(token *RefreshToken) (*TokenPair, error) {
+	value, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Store.Save(refreshHash(value), token, time.Until(token.ExpiresAt)); err != nil {
+		return nil, err
+	}
+	claims := &accessClaims{ID: id, Subject: token.Subject, Scopes: token.Scopes, Family: token.Family, ExpiresAt: time.Now().Add(t.AccessTTL).Unix()}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return &TokenPair{
+		AccessToken:  payload + "." + t.sign(payload),
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.AccessTTL / time.Second),
+		RefreshToken: value,
+	}, nil
+}
+
+func (t *AuthTokens) sign(payload string) string {
+	mac := hmac.New(sha256.New, t.Key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Refresh rotates the refresh token value into a new pair. The unknown, expired or revoked tokens fail with
+// 401 Unauthorized, and a reused one also revokes its family.
+func (t *AuthTokens) Refresh(value string) (*TokenPair, error) {
+	if value == "" {
+		return nil, (&UnauthorizedError{}).New("invalid refresh token")
+	}
+	token, reused, err := t.Store.Use(refreshHash(value))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || time.Now().After(token.ExpiresAt) {
+		return nil, (&UnauthorizedError{}).New("invalid refresh token")
+	}
+	if revoked, err := t.Store.Revoked("family:" + token.Family); err != nil {
+		return nil, err
+	} else if revoked == true {
+		return nil, (&UnauthorizedError{}).New("invalid refresh token")
+	}
+	if reused == true {
+		Metrics.Add("authtokens.reused", 1)
+		log.WithFields(log.Fields{"subject": token.Subject, "family": token.Family}).Warnln("AuthTokens: refresh token reused, family revoked")
+		if err := t.revokeFamily(token); err != nil {
+			return nil, err
+		}
+		return nil, (&UnauthorizedError{}).New("invalid refresh token")
+	}
+	return t.issue(token)
+}
+
+// Revoke revokes the family of the refresh token value, with its access tokens, like on sign out.
+// An unknown token isn't an error.
+func (t *AuthTokens) Revoke(value string) error {
+	token, _, err := t.Store.Use(refreshHash(value))
+	if err != nil || token == nil {
+		return err
+	}
+	return t.revokeFamily(token)
+}
+
+func (t *AuthTokens) revokeFamily(token *RefreshToken) error {
+	return t.Store.Revoke("family:"+token.Family, time.Until(token.ExpiresAt))
+}
+
+// RevokeAccess revokes the access token id, the "accessToken" claim of its Principal, until its expiration.
+func (t *AuthTokens) RevokeAccess(id string) error {
+	return t.Store.Revoke("access:"+id, t.AccessTTL)
+}
+
+// Validate returns the principal of the access token, or a 401 Unauthorized error
+// if it is invalid, expired or revoked.
+func (t *AuthTokens) Validate(access string) (*Principal, error) {
+	i := strings.IndexByte(access, '.')
+	if i < 0 || hmac.Equal([]byte(t.sign(access[:i])), []byte(access[i+1:])) == false {
+		return nil, (&UnauthorizedError{}).New("invalid access token")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(access[:i])
+	claims := new(accessClaims)
+	if err != nil || json.Unmarshal(b, claims) != nil {
+		return nil, (&UnauthorizedError{}).New("invalid access token")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, (&UnauthorizedError{}).New("expired access token")
+	}
+	for _, id := range []string{"access:" + claims.ID, "family:" + claims.Family} {
+		revoked, err := t.Store.Revoked(id)
+		if err != nil {
+			return nil, err
+		}
+		if revoked == true {
+			return nil, (&UnauthorizedError{}).New("revoked access token")
+		}
+	}
+	return &Principal{ID: claims.Subject, Scopes: claims.Scopes, Claims: map[string]interface{}{"accessToken": claims.ID}}, nil
+}
+
+// Handler is the middleware resolving the Authorization Bearer access token of the request into its principal,
+// with ctx.Principal. The requests without a valid token fail with 401 Unauthorized.
+func (t *AuthTokens) Handler(ctx *Context) {
+	auth := ctx.Request.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") == false {
+		ctx.ResponseWriter.Header().Set("WWW-Authenticate", "Bearer")
+		ctx.Fail((&UnauthorizedError{}).New("access token required"))
+		return
+	}
+	p, err := t.Validate(strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		ctx.ResponseWriter.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.Fail(err)
+		return
+	}
+	ctx.SetPrincipal(p)
+	ctx.Next()
+}
+
+// TokenHandler is the token endpoint of the refresh_token grant: it responds with the pair rotated
+// from the refresh_token form value of the body. The query string is ignored, as it is logged by the proxies.
+func (t *AuthTokens) TokenHandler(ctx *Context) {
+	if grant := ctx.Request.PostFormValue("grant_type"); grant != "refresh_token" {
+		ctx.Fail((&ValidationError{}).New("unsupported grant_type " + grant))
+		return
+	}
+	pair, err := t.Refresh(ctx.Request.PostFormValue("refresh_token"))
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.ResponseWriter.Header().Set("Cache-Control", "no-store")
+	ctx.Ok(pair)
+}
+
+// RevokeHandler revokes the family of the refresh_token form value of the body, see Revoke.
+func (t *AuthTokens) RevokeHandler(ctx *Context) {
+	if err := t.Revoke(ctx.Request.PostFormValue("refresh_token")); err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.Ok(nil)
+}
+
+// MemoryRefreshStore is a RefreshStore in memory, local to the instance.
+type MemoryRefreshStore struct {
+	mu      sync.Mutex // Makes Use atomic.
+	tokens  *Cache
+	used    *Cache
+	revoked *Cache
+}
+
+// NewMemoryRefreshStore returns a new MemoryRefreshStore.
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{tokens: NewCache("", 0, 0), used: NewCache("", 0, 0), revoked: NewCache("", 0, 0)}
+}
+
+// Save saves the token hash for ttl.
+func (s *MemoryRefreshStore) Save(hash string, token *RefreshToken, ttl time.Duration) error {
+	s.tokens.SetTTL(hash, token, ttl)
+	return nil
+}
+
+// Use marks the token hash as used, and returns it. The used tokens are kept until their expiration.
+func (s *MemoryRefreshStore) Use(hash string) (*RefreshToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens.Get(hash)
+	if ok == false {
+		return nil, false, nil
+	}
+	t := token.(*RefreshToken)
+	return t, s.used.Add(hash, true, time.Until(t.ExpiresAt)) == false, nil
+}
+
+// Revoke adds id to the revocation list for ttl.
+func (s *MemoryRefreshStore) Revoke(id string, ttl time.Duration) error {
+	s.revoked.SetTTL(id, true, ttl)
+	return nil
+}
+
+// Revoked tells if id is in the revocation list.
+func (s *MemoryRefreshStore) Revoked(id string) (bool, error) {
+	_, ok := s.revoked.Get(id)
+	return ok, nil
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthTokens(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("short key: want a panic")
+			}
+		}()
+		NewAuthTokens([]byte("secret"), nil)
+	}()
+
+	tokens := NewAuthTokens([]byte("0123456789abcdef0123456789abcdef"), nil)
+	engine := New()
+	engine.POST("/token", tokens.TokenHandler)
+	engine.POST("/logout", tokens.RevokeHandler)
+	engine.GET("/me", tokens.Handler, func(ctx *Context) {
+		ctx.Ok(ctx.Principal().ID)
+	})
+	hs := NewHandlersStack()
+	hs.Use(engine.Handler())
+	post := func(path, refresh string) (*httptest.ResponseRecorder, *TokenPair) {
+		form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refresh}}
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, req)
+		var res struct{ Data *TokenPair }
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w, res.Data
+	}
+	get := func(access string) int {
+		req := httptest.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	first, err := tokens.Issue("bob", []string{"orders:read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(first.AccessToken); code != http.StatusOK {
+		t.Errorf("access token: want 200, got %d", code)
+	}
+	if code := get(first.AccessToken + "x"); code != http.StatusUnauthorized {
+		t.Errorf("tampered access token: want 401, got %d", code)
+	}
+
+	w, second := post("/token", first.RefreshToken)
+	if w.Code != http.StatusOK || second == nil || second.RefreshToken == first.RefreshToken || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("rotation: want a new pair, got %d %s", w.Code, w.Body.String())
+	}
+	if code := get(second.AccessToken); code != http.StatusOK {
+		t.Errorf("rotated access token: want 200, got %d", code)
+	}
+
+	// The reuse of the first refresh token revokes the family.
+	if w, _ := post("/token", first.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token: want 401, got %d", w.Code)
+	}
+	if w, _ := post("/token", second.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh token of a revoked family: want 401, got %d", w.Code)
+	}
+	if code := get(second.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("access token of a revoked family: want 401, got %d", code)
+	}
+
+	other, _ := tokens.Issue("alice", nil)
+	if w, _ := post("/logout", other.RefreshToken); w.Code != http.StatusOK {
+		t.Errorf("logout: want 200, got %d", w.Code)
+	}
+	if w, _ := post("/token", other.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh token after logout: want 401, got %d", w.Code)
+	}
+
+	// The refresh tokens of the query string, logged by the proxies, are ignored.
+	query, _ := tokens.Issue("dave", nil)
+	req := httptest.NewRequest("POST", "/token?"+url.Values{"grant_type": {"refresh_token"}, "refresh_token": {query.RefreshToken}}.Encode(), nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	hs.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Errorf("refresh token in the query string: want an error, got %d", w.Code)
+	}
+	if w, _ := post("/token", query.RefreshToken); w.Code != http.StatusOK {
+		t.Errorf("refresh token in the body: want 200, got %d", w.Code)
+	}
+
+	third, _ := tokens.Issue("carol", nil)
+	p, err := tokens.Validate(third.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens.RevokeAccess(p.Claims["accessToken"].(string))
+	if code := get(third.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("revoked access token: want 401, got %d", code)
+	}
+}
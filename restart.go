@@ -0,0 +1,18 @@
+package core
+
+import (
+	"net"
+	"sync"
+)
+
+// servingListener is a listener calling serving once, when the server starts accepting its connections.
+type servingListener struct {
+	net.Listener
+	once    sync.Once
+	serving func()
+}
+
+func (l *servingListener) Accept() (net.Conn, error) {
+	l.once.Do(l.serving)
+	return l.Listener.Accept()
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"net"
+	"testing"
+)
+
+func TestServingListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notified := 0
+	l := &servingListener{Listener: inner, serving: func() { notified++ }}
+	defer l.Close()
+	if notified != 0 {
+		t.Fatal("notified before serving")
+	}
+	for i := 0; i < 2; i++ {
+		go func() {
+			if conn, err := net.Dial("tcp", inner.Addr().String()); err == nil {
+				conn.Close()
+			}
+		}()
+		conn, err := l.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if notified != 1 {
+		t.Errorf("want 1 notification, got %d", notified)
+	}
+}
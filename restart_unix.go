@@ -0,0 +1,78 @@
+//go:build !windows
+// +build !windows
+
+package core
+
+import (
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+// listenerFDEnv tells a restarted process the file descriptor of its inherited listener.
+const listenerFDEnv = "CORE_LISTENER_FD"
+
+// listen returns the listener inherited from the previous process on a graceful restart, or a new one on addr.
+func listen(addr string) (l net.Listener, inherited bool, err error) {
+	if fd, err := strconv.Atoi(os.Getenv(listenerFDEnv)); err == nil {
+		f := os.NewFile(uintptr(fd), "listener")
+		l, err := net.FileListener(f)
+		f.Close()
+		os.Unsetenv(listenerFDEnv)
+		return l, true, err
+	}
+	l, err = net.Listen("tcp", addr)
+	return l, false, err
+}
+
+// watchRestart starts a new process of the server when SIGUSR2 is received, handing over the listener l.
+// The new process stops this one once it is serving, which then drains with the graceful shutdown.
+func watchRestart(srv *graceful.Server, l net.Listener) {
+	tl, ok := l.(*net.TCPListener)
+	if ok == false {
+		return
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR2)
+	for range c {
+		p, err := restart(tl, os.Args[1:])
+		if err != nil {
+			log.Errorln("graceful restart:", err)
+			continue
+		}
+		log.Warnln("Graceful restart: started process", p.Pid)
+	}
+}
+
+// restart starts a new process of the executable with the command line args, inheriting the listener l.
+func restart(l *net.TCPListener, args []string) (*os.Process, error) {
+	// os.Args[0] may be a relative path, or a name found in the PATH.
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	f, err := l.File()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The inherited files are numbered from 3, after stdin, stdout and stderr.
+	env := append(os.Environ(), listenerFDEnv+"=3")
+	return os.StartProcess(path, append([]string{os.Args[0]}, args...), &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, f},
+	})
+}
+
+// notifyParent stops the previous process after a graceful restart, so that it drains its requests.
+// It is called by the servingListener of the new process, when it starts accepting the connections.
+func notifyParent() {
+	if err := syscall.Kill(os.Getppid(), syscall.SIGTERM); err != nil {
+		log.Errorln("graceful restart:", err)
+	}
+}
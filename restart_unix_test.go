@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package core
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestRestartChild is the new process started by TestRestart, serving "inherited" on its inherited listener.
+func TestRestartChild(t *testing.T) {
+	if os.Getenv("CORE_TEST_RESTART_CHILD") != "1" {
+		t.Skip("started by TestRestart")
+	}
+	l, inherited, err := listen("127.0.0.1:0")
+	if err != nil || inherited == false {
+		t.Fatalf("listen: want an inherited listener, got %v %v", inherited, err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("inherited"))
+	conn.Close()
+}
+
+func TestRestart(t *testing.T) {
+	l, inherited, err := listen("127.0.0.1:0")
+	if err != nil || inherited == true {
+		t.Fatalf("listen: want a new listener, got %v %v", inherited, err)
+	}
+	defer l.Close()
+
+	os.Setenv("CORE_TEST_RESTART_CHILD", "1")
+	p, err := restart(l.(*net.TCPListener), []string{"-test.run=^TestRestartChild$"})
+	os.Unsetenv("CORE_TEST_RESTART_CHILD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Kill()
+
+	// The listener isn't accepting in this process: the connection is served by the new one.
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	b, _ := ioutil.ReadAll(conn)
+	if string(b) != "inherited" {
+		t.Errorf("want the response of the new process, got %q", b)
+	}
+	if state, err := p.Wait(); err != nil || state.Success() == false {
+		t.Errorf("new process: got %v %v", state, err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package core
+
+import (
+	"net"
+
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+// listen returns a new listener on addr, the graceful restart is not supported on Windows.
+func listen(addr string) (l net.Listener, inherited bool, err error) {
+	l, err = net.Listen("tcp", addr)
+	return l, false, err
+}
+
+func watchRestart(srv *graceful.Server, l net.Listener) {}
+
+func notifyParent() {}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Route is a registered route, returned by the routing methods of the router groups to set its options.
+// It embeds the routes of its group, so that the routing methods can still be chained:
+//
+//	router.GET("/users", listUsers).POST("/users", createUser)
+type Route struct {
+	IRoutes
+	Methods []string
+	Path    string
+
+	before  RouterHandlerChain                // Handlers of the route options, run before the route handlers.
+	around  []func(ctx *Context, next func()) // Wrappers of the route options, around the route handlers.
+	limiter RouterHandler                     // The rate limiting middleware of the route, run by the guard after the authentication.
+	guards  atomic.Value                      // The *routeGuards of the route, replaced under the lock of the engine.
+	sunset  *time.Time                        // The sunset of the deprecated route, zero if it has none.
+	options *RouteOptions
+	docs    *RouteDoc
+	names   []string               // The names of the middleware and handlers of the route.
+	stats   map[string]*routeStats // The concurrency stats by method, set on registration.
+	engine  *Engine
+}
+
+// routeGuards are the scopes and the claims required by a route. They are never modified, but replaced.
+type routeGuards struct {
+	scopes []string            // The scopes required by the route.
+	claims map[string][]string // The accepted values of the claims required by the route.
+}
+
+// newRoute returns a new route of the group, at relativePath, guarded by the group before it is added.
+func (group *RouterGroup) newRoute(relativePath string) *Route {
+	route := &Route{IRoutes: group.returnObj(), Path: group.calculateAbsolutePath(relativePath), engine: group.engine}
+	if len(group.guards.scopes) > 0 || len(group.guards.claims) > 0 {
+		route.guards.Store(group.guards.clone())
+	}
+	return route
+}
+
+// Before adds handlers run before the route handlers, to implement route options.
+// They must not call ctx.Next: when one of them writes the response, the route handlers aren't called.
+func (route *Route) Before(handlers ...RouterHandler) *Route {
+	route.before = append(route.before, handlers...)
+	return route
+}
+
+// serve runs the handlers of the route options, then the route handlers within its wrappers.
+func (route *Route) serve(ctx *Context) {
+	ctx.route = route
+	if s := route.stats[ctx.Request.Method]; s != nil {
+		defer s.dispatch(ctx)()
+	} else if s := route.stats["GET"]; s != nil && ctx.Request.Method == "HEAD" {
+		// A HEAD request served by the GET route.
+		defer s.dispatch(ctx)()
+	}
+	for _, h := range route.before {
+		h(ctx)
+		if ctx.Written() == true {
+			return
+		}
+	}
+	route.next(ctx, 0)
+}
+
+// next runs the wrapper i, or the route handlers after the last one.
+func (route *Route) next(ctx *Context, i int) {
+	if i == len(route.around) {
+		ctx.Next()
+		return
+	}
+	route.around[i](ctx, func() { route.next(ctx, i+1) })
+}
+
+// RequireScopes requires the scopes from the principal of the requests, set by the authentication middleware.
+// The requests without a principal fail with 401 Unauthorized, the ones missing a scope with 403 Forbidden.
+//
+//	router.GET("/admin/users", listUsers).RequireScopes("admin:read")
+//
+// It is safe to call while serving, but a route added at runtime serves its first requests before it:
+// require the scopes of the runtime routes on their group with RouterGroup.RequireScopes, so that they are guarded once added.
+func (route *Route) RequireScopes(scopes ...string) *Route {
+	route.require(func(g *routeGuards) {
+		g.scopes = append(g.scopes, scopes...)
+	})
+	return route
+}
+
+// RequireClaim requires the claim name of the principal of the requests to be one of values, like its role or its plan.
+// A claim of a list of values matches if one of them does. The requests failing it fail like RequireScopes.
+//
+//	router.DELETE("/users/:id", deleteUser).RequireClaim("role", "admin", "owner")
+func (route *Route) RequireClaim(name string, values ...string) *Route {
+	route.require(func(g *routeGuards) {
+		g.claims[name] = append(g.claims[name], values...)
+	})
+	return route
+}
+
+// require replaces the guards of the route by a copy changed by update, under the lock of the engine:
+// the route may already be serving, when it is added at runtime.
+func (route *Route) require(update func(g *routeGuards)) {
+	route.engine.mu.Lock()
+	defer route.engine.mu.Unlock()
+	g := &routeGuards{}
+	if old := route.loadGuards(); old != nil {
+		g = old.clone()
+	}
+	if g.claims == nil {
+		g.claims = map[string][]string{}
+	}
+	update(g)
+	route.guards.Store(g)
+}
+
+// clone returns a copy of the guards.
+func (g *routeGuards) clone() *routeGuards {
+	c := &routeGuards{scopes: append([]string(nil), g.scopes...)}
+	if g.claims != nil {
+		c.claims = make(map[string][]string, len(g.claims))
+		for name, values := range g.claims {
+			c.claims[name] = append([]string(nil), values...)
+		}
+	}
+	return c
+}
+
+// loadGuards returns the guards of the route, nil if it has none.
+func (route *Route) loadGuards() *routeGuards {
+	g, _ := route.guards.Load().(*routeGuards)
+	return g
+}
+
+// guard enforces the scopes and the claims required by the route, then its rate limiter.
+// It runs just before the route handler, after the authentication middleware, so that the rejected requests aren't counted.
+func (route *Route) guard(ctx *Context) {
+	if g := route.loadGuards(); g != nil && (len(g.scopes) > 0 || len(g.claims) > 0) {
+		p := ctx.Principal()
+		if p == nil {
+			ctx.Fail((&UnauthorizedError{}).New("authentication required"))
+			return
+		}
+		for _, scope := range g.scopes {
+			if p.HasScope(scope) == false {
+				ctx.Fail((&ForbiddenError{}).New("missing scope " + scope))
+				return
+			}
+		}
+		for name, values := range g.claims {
+			if claimIn(p.Claims[name], values) == false {
+				ctx.Fail((&ForbiddenError{}).New("missing claim " + name))
+				return
+			}
+		}
+	}
+	if route.limiter != nil {
+		route.limiter(ctx)
+		return
+	}
+	ctx.Next()
+}
+
+// claimIn tells if the claim, a value or a list of values, has one of values.
+func claimIn(claim interface{}, values []string) bool {
+	switch c := claim.(type) {
+	case string:
+		return stringIn(c, values)
+	case []string:
+		for _, v := range c {
+			if stringIn(v, values) == true {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range c {
+			if s, ok := v.(string); ok == true && stringIn(s, values) == true {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Deprecate marks the route as deprecated: its responses get the Deprecation header, the Sunset header if sunset isn't zero,
+// and a Link header to the deprecation documentation if link isn't empty.
+// Its usage is counted in Metrics under "deprecated.<method> <path>", to track the clients before retiring it.
+//
+//	router.GET("/v1/users", listUsers).Deprecate(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), "https://api.example.com/docs/v2")
+func (route *Route) Deprecate(sunset time.Time, link string) *Route {
+	path := route.Path
+	route.sunset = &sunset
+	return route.Before(func(ctx *Context) {
+		h := ctx.ResponseWriter.Header()
+		h.Set("Deprecation", "true")
+		if sunset.IsZero() == false {
+			h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if link != "" {
+			h.Add("Link", "<"+link+`>; rel="deprecation"`)
+		}
+		Metrics.Add("deprecated."+ctx.Request.Method+" "+path, 1)
+	})
+}
+
+// info returns the description of the route for method.
+func (route *Route) info(method string, handlers int) RouteInfo {
+	info := RouteInfo{Method: method, Path: route.Path, Handlers: handlers, HandlerNames: route.names, Params: pathParams(route.Path), Options: route.options, Doc: route.docs}
+	if g := route.loadGuards(); g != nil {
+		info.Scopes = g.scopes
+		if len(g.claims) > 0 {
+			info.Claims = g.claims
+		}
+	}
+	if route.sunset != nil {
+		info.Deprecated = true
+		if route.sunset.IsZero() == false {
+			info.Sunset = route.sunset
+		}
+	}
+	return info
+}
+
+// pathParams returns the names of the params of path, like id of /users/:id or filepath of /files/*filepath.
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
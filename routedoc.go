@@ -0,0 +1,66 @@
+package core
+
+// Stability is the stability level of a route, for its clients.
+type Stability string
+
+// The stability levels of the routes.
+const (
+	Stable       Stability = "stable"
+	Beta         Stability = "beta"
+	Experimental Stability = "experimental"
+	Internal     Stability = "internal"
+)
+
+// RouteDoc is the documentation of a route, set with its fluent builder, exposed by Routes, the admin routes and OpenAPI:
+//
+//	router.GET("/users/:id", getUser).
+//		Summary("Get a user").
+//		Tags("users").
+//		Auth("bearer").
+//		Stability(core.Beta)
+type RouteDoc struct {
+	Summary     string    `json:"summary,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Auth        string    `json:"auth,omitempty"` // The authentication scheme required, empty if the route is public.
+	Stability   Stability `json:"stability,omitempty"`
+}
+
+// doc returns the documentation of the route, created on the first use.
+func (route *Route) doc() *RouteDoc {
+	if route.docs == nil {
+		route.docs = &RouteDoc{}
+	}
+	return route.docs
+}
+
+// Summary sets the one-line summary of the route.
+func (route *Route) Summary(summary string) *Route {
+	route.doc().Summary = summary
+	return route
+}
+
+// Description sets the long description of the route.
+func (route *Route) Description(description string) *Route {
+	route.doc().Description = description
+	return route
+}
+
+// Tags adds tags to the route, to group it with the routes of the same resource in the documentation.
+func (route *Route) Tags(tags ...string) *Route {
+	route.doc().Tags = append(route.doc().Tags, tags...)
+	return route
+}
+
+// Auth sets the authentication scheme required by the route, like "bearer" or "apiKey".
+// It documents the route only: the authentication is enforced by the middleware.
+func (route *Route) Auth(scheme string) *Route {
+	route.doc().Auth = scheme
+	return route
+}
+
+// Stability sets the stability level of the route. The routes without one are documented as Stable.
+func (route *Route) Stability(level Stability) *Route {
+	route.doc().Stability = level
+	return route
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RouteOptions are the operational policies of a route, declared next to its definition:
+//
+//	router.POST("/reports", createReport).Options(core.RouteOptions{
+//		Timeout:   10 * time.Second,
+//		MaxBody:   1 << 20,
+//		Scopes:    []string{"reports:write"},
+//		RateClass: "expensive",
+//	})
+type RouteOptions struct {
+	// Timeout is the deadline of the request context. If the handlers return without responding after it, the request fails with 503.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// MaxBody is the maximum size of the request body, the larger ones fail with 413 Request Entity Too Large.
+	MaxBody int64 `json:"maxBody,omitempty"`
+
+	// Scopes are the scopes the request principal must have, checked after the authentication middleware.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// RateClass is the name of the rate limiting middleware of the route in RateClasses, run after the authentication and the scopes.
+	RateClass string `json:"rateClass,omitempty"`
+
+	// StreamBody streams the request body to the handler, e.g. for the large uploads: BodyBytes doesn't read it.
+	StreamBody bool `json:"streamBody,omitempty"`
+
+	// CacheTTL is the max-age of the Cache-Control header of the success responses.
+	CacheTTL time.Duration `json:"cacheTTL,omitempty"`
+}
+
+// RateClasses are the rate limiting middleware of the rate classes of the routes, like the Handler of a Bulkhead, a Lanes or a Quota:
+//
+//	core.RateClasses["expensive"] = core.NewBulkhead("expensive", 10, 20).Handler
+var RateClasses = map[string]RouterHandler{}
+
+// Options sets the operational policies of the route.
+func (route *Route) Options(opts RouteOptions) *Route {
+	route.options = &opts
+	if len(opts.Scopes) > 0 {
+		route.RequireScopes(opts.Scopes...)
+	}
+	if opts.MaxBody > 0 {
+		route.Before(func(ctx *Context) {
+			if ctx.Request.ContentLength > opts.MaxBody {
+				ctx.FailWithStatus(http.StatusRequestEntityTooLarge, (&ValidationError{}).New("body too large"))
+				return
+			}
+			if ctx.Request.Body != nil {
+				ctx.Request.Body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, opts.MaxBody)
+			}
+		})
+	}
+	if opts.StreamBody == true {
+		route.Before(func(ctx *Context) {
+			ctx.Data["bodyStream"] = true
+		})
+	}
+	if opts.CacheTTL > 0 {
+		maxAge := "max-age=" + strconv.Itoa(int(opts.CacheTTL/time.Second))
+		intercept := func(ctx *Context, res *ResFormat) {
+			if res.Ok == true {
+				ctx.ResponseWriter.Header().Set("Cache-Control", maxAge)
+			}
+		}
+		route.Before(func(ctx *Context) {
+			ctx.addInterceptor(intercept)
+		})
+	}
+	if opts.Timeout > 0 {
+		route.around = append(route.around, func(ctx *Context, next func()) {
+			c, cancel := context.WithTimeout(ctx.Request.Context(), opts.Timeout)
+			defer cancel()
+			ctx.Request = ctx.Request.WithContext(c)
+			next()
+			if ctx.Written() == false && c.Err() == context.DeadlineExceeded {
+				ctx.FailWithStatus(http.StatusServiceUnavailable, (&ServerError{}).New("request timeout"))
+			}
+		})
+	}
+	if opts.RateClass != "" {
+		class := opts.RateClass
+		route.limiter = func(ctx *Context) {
+			limiter, ok := RateClasses[class]
+			if ok == false {
+				log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("RouteOptions: unknown rate class " + class)
+				ctx.Next()
+				return
+			}
+			limiter(ctx)
+		}
+	}
+	return route
+}
@@ -1,29 +1,161 @@
 package core
 
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 // Routers create router instance
 var Routers = create()
 
 // Engine each group has a router engine
 type Engine struct {
 	RouterGroup
+
+	// AutoHEAD serves the HEAD requests with the GET route when there is no HEAD route. Default is true.
+	// The response body is discarded, but the Content-Length is still reported.
+	AutoHEAD bool
+
+	// HandleMethodNotAllowed responds 405 Method Not Allowed, with the Allow header, when the path has routes for other methods only.
+	// Default is false, they are not found.
+	HandleMethodNotAllowed bool
+
+	allFallback RouterHandlerChain
 	allNoRoute  RouterHandlerChain
 	allNoMethod RouterHandlerChain
 	noRoute     RouterHandlerChain
 	noMethod    RouterHandlerChain
-	trees       methodTrees
+	trees       atomic.Value // methodTrees, swapped on the routes changes once serving.
+	routes      []registeredRoute
+	mu          sync.Mutex // Guards the routes changes.
+	serving     int32
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Handlers int    `json:"handlers"`
+	// HandlerNames are the names of the middleware and handlers of the route, without the ones of the handlers stack.
+	HandlerNames []string            `json:"handlerNames,omitempty"`
+	Params       []string            `json:"params,omitempty"`
+	Scopes       []string            `json:"scopes,omitempty"` // The scopes required by the route.
+	Claims       map[string][]string `json:"claims,omitempty"` // The accepted values of the claims required by the route.
+	Deprecated   bool                `json:"deprecated,omitempty"`
+	Sunset       *time.Time          `json:"sunset,omitempty"`
+	Options      *RouteOptions       `json:"options,omitempty"`
+	Doc          *RouteDoc           `json:"doc,omitempty"`
+}
+
+// registeredRoute is a route registered for a method.
+type registeredRoute struct {
+	method   string
+	route    *Route
+	handlers int
+	chain    RouterHandlerChain
 }
 
-func (engine *Engine) addRoute(method, path string, handlers RouterHandlerChain) {
+// addRoute adds the route to the trees.
+// Before serving, the trees are updated in place. Once serving, the routes can still be added from any goroutine:
+// the trees are rebuilt and swapped, so that the in-flight requests keep a consistent tree.
+func (engine *Engine) addRoute(method string, route *Route, handlers RouterHandlerChain) {
+	path := route.Path
 	assert1(path[0] == '/', "path must begin with '/'")
 	assert1(method != "", "HTTP method can not be empty")
 	assert1(len(handlers) > 0, "there must be at least one handler")
 
-	root := engine.trees.get(method)
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	r := registeredRoute{method: method, route: route, handlers: len(handlers), chain: handlers}
+	// Copied, as the route may be serving its other methods.
+	stats := map[string]*routeStats{method: statsFor(method, path)}
+	for m, s := range route.stats {
+		stats[m] = s
+	}
+	route.stats = stats
+	if atomic.LoadInt32(&engine.serving) == 1 {
+		engine.trees.Store(buildTrees(append(engine.routes[:len(engine.routes):len(engine.routes)], r)))
+	} else {
+		engine.trees.Store(insertRoute(engine.loadTrees(), r))
+	}
+	engine.routes = append(engine.routes, r)
+	route.Methods = append(route.Methods, method)
+}
+
+// RemoveRoute removes the route of method and path, e.g. a redirect managed from an admin page, and tells if it was registered.
+// It is safe to call while serving: the trees are rebuilt and swapped, the in-flight requests finish with the previous ones.
+func (engine *Engine) RemoveRoute(method, path string) bool {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	for i, r := range engine.routes {
+		if r.method != method || r.route.Path != path {
+			continue
+		}
+		routes := make([]registeredRoute, 0, len(engine.routes)-1)
+		routes = append(append(routes, engine.routes[:i]...), engine.routes[i+1:]...)
+		engine.trees.Store(buildTrees(routes))
+		engine.routes = routes
+		methods := make([]string, 0, len(r.route.Methods))
+		for _, m := range r.route.Methods {
+			if m != method {
+				methods = append(methods, m)
+			}
+		}
+		r.route.Methods = methods
+		return true
+	}
+	return false
+}
+
+// loadTrees returns the current trees.
+func (engine *Engine) loadTrees() methodTrees {
+	trees, _ := engine.trees.Load().(methodTrees)
+	return trees
+}
+
+// insertRoute adds the route r to trees in place, and returns them.
+func insertRoute(trees methodTrees, r registeredRoute) methodTrees {
+	root := trees.get(r.method)
 	if root == nil {
 		root = new(node)
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+		trees = append(trees, methodTree{method: r.method, root: root})
 	}
-	root.addRoute(path, handlers)
+	root.addRoute(r.route.Path, r.chain)
+	return trees
+}
+
+// buildTrees returns new trees of routes.
+func buildTrees(routes []registeredRoute) methodTrees {
+	trees := make(methodTrees, 0, 9)
+	for _, r := range routes {
+		trees = insertRoute(trees, r)
+	}
+	return trees
+}
+
+// Routes returns the registered routes, in registration order.
+func (engine *Engine) Routes() []RouteInfo {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	routes := make([]RouteInfo, len(engine.routes))
+	for i, r := range engine.routes {
+		routes[i] = r.route.info(r.method, r.handlers)
+	}
+	return routes
+}
+
+// New returns a new blank Engine instance without any middleware attached.
+func New() *Engine {
+	return create()
+}
+
+// Handler returns the router as a handler, to be used as the last handler of a handlers stack.
+func (engine *Engine) Handler() RouterHandler {
+	return engine.handlers
 }
 
 // create returns a new blank Engine instance without any middleware attached.
@@ -34,41 +166,176 @@ func create() *Engine {
 			basePath: "/",
 			root:     true,
 		},
-		trees: make(methodTrees, 0, 9),
+		AutoHEAD: true,
 	}
+	engine.trees.Store(make(methodTrees, 0, 9))
 	engine.RouterGroup.engine = engine
 	return engine
 }
 
 func (engine *Engine) handlers(ctx *Context) {
+	if atomic.LoadInt32(&engine.serving) == 0 {
+		// Wait for a route being added in place, the next ones swap the trees.
+		engine.mu.Lock()
+		atomic.StoreInt32(&engine.serving, 1)
+		engine.mu.Unlock()
+	}
 	httpMethod := ctx.Request.Method
-	path := ctx.Request.URL.Path
-	unescape := false
-	found := false
-	// Find root of the tree for the given HTTP method
-	t := engine.trees
-	for i, tl := 0, len(t); i < tl; i++ {
-		if t[i].method == httpMethod {
-			root := t[i].root
-			// Find route in tree
-			handlers, params, _ := root.getValue(path, ctx.Params, unescape)
-			if handlers != nil {
-				found = true
-				ctx.Params = params
-				engine.exeHandlers(ctx, handlers)
+	if handlers, params := engine.route(httpMethod, ctx.Request.URL.Path, ctx.Params); handlers != nil {
+		ctx.Params = params
+		engine.exeHandlers(ctx, handlers)
+		return
+	}
+	if httpMethod == "HEAD" && engine.AutoHEAD == true {
+		if handlers, params := engine.route("GET", ctx.Request.URL.Path, ctx.Params); handlers != nil {
+			ctx.Params = params
+			engine.exeHEAD(ctx, handlers)
+			return
+		}
+	}
+	if len(engine.allFallback) > 0 {
+		engine.exeHandlers(ctx, engine.allFallback)
+		return
+	}
+	engine.notFound(ctx)
+}
+
+// notFound responds the requests not matching any route, with the NoMethod or NoRoute handlers.
+func (engine *Engine) notFound(ctx *Context) {
+	if engine.HandleMethodNotAllowed == true {
+		if allowed := engine.allowed(ctx.Request.URL.Path); len(allowed) > 0 {
+			ctx.ResponseWriter.Header().Set("Allow", strings.Join(allowed, ", "))
+			if len(engine.allNoMethod) > 0 {
+				engine.exeHandlers(ctx, engine.allNoMethod)
 				return
 			}
-			break
+			ctx.FailWithStatus(http.StatusMethodNotAllowed, (&NotFoundError{}).New("Method Not Allowed"))
+			return
 		}
 	}
-	if found == false {
-		ctx.Fail((&NotFoundError{}).New("Url Not found"))
+	if len(engine.allNoRoute) > 0 {
+		engine.exeHandlers(ctx, engine.allNoRoute)
+		return
 	}
+	ctx.Fail((&NotFoundError{}).New("Url Not found"))
 }
 
-func (engine *Engine) exeHandlers(ctx *Context, handlers RouterHandlerChain) {
-	for _, h := range handlers {
-		ctx.handlersStack.Use(h)
+// Fallback sets the handlers of the requests not matching any route, run before the NoMethod and NoRoute handlers.
+// A fallback handler calls ctx.Next() to leave the request to them, e.g. to serve a SPA frontend:
+//
+//	core.Routers.Fallback(func(ctx *core.Context) {
+//		if ctx.Request.Method != "GET" || strings.HasPrefix(ctx.Request.URL.Path, "/api/") {
+//			ctx.Next()
+//			return
+//		}
+//		http.ServeFile(ctx.ResponseWriter, ctx.Request, "public/index.html")
+//	})
+func (engine *Engine) Fallback(handlers ...RouterHandler) {
+	engine.allFallback = append(engine.combineHandlers(handlers), engine.notFound)
+}
+
+// NoRoute sets the handlers of the requests not matching any route, instead of the default 404 fail response.
+// They get the full Context, and run after the middleware of the engine.
+//
+//	core.Routers.NoRoute(func(ctx *core.Context) {
+//		ctx.FailWithCode(http.StatusNotFound, "route_not_found", "no route "+ctx.Request.URL.Path)
+//	})
+func (engine *Engine) NoRoute(handlers ...RouterHandler) {
+	engine.noRoute = handlers
+	engine.allNoRoute = engine.combineHandlers(engine.noRoute)
+}
+
+// NoMethod sets the handlers of the requests whose path has routes for other methods only, instead of the default 405 fail response.
+// The Allow header is already set. It requires HandleMethodNotAllowed.
+func (engine *Engine) NoMethod(handlers ...RouterHandler) {
+	engine.noMethod = handlers
+	engine.allNoMethod = engine.combineHandlers(engine.noMethod)
+}
+
+// allowed returns the methods with a route matching path.
+func (engine *Engine) allowed(path string) []string {
+	var allowed []string
+	for _, t := range engine.loadTrees() {
+		if handlers, _, _ := t.root.getValue(path, nil, false); handlers != nil {
+			allowed = append(allowed, t.method)
+		}
+	}
+	return allowed
+}
+
+// route finds the handlers of the route matching method and path.
+func (engine *Engine) route(method, path string, po Params) (RouterHandlerChain, Params) {
+	// Find root of the tree for the given HTTP method
+	t := engine.loadTrees()
+	for i, tl := 0, len(t); i < tl; i++ {
+		if t[i].method == method {
+			// Find route in tree
+			handlers, params, _ := t[i].root.getValue(path, po, false)
+			return handlers, params
+		}
 	}
+	return nil, po
+}
+
+// exeHEAD executes the GET handlers for a HEAD request, discarding the body.
+func (engine *Engine) exeHEAD(ctx *Context, handlers RouterHandlerChain) {
+	hw := &headWriter{ResponseWriter: ctx.ResponseWriter, context: ctx}
+	ctx.ResponseWriter = hw
+	defer func() {
+		ctx.ResponseWriter = hw.ResponseWriter
+		if hw.flushed == false {
+			// Panic: let Recover write the response.
+			ctx.written = false
+		}
+	}()
+	engine.exeHandlers(ctx, handlers)
+	hw.flush()
+}
+
+func (engine *Engine) exeHandlers(ctx *Context, handlers RouterHandlerChain) {
+	// The stack is shared by all the requests, so limit its capacity to force append to copy it.
+	stack := ctx.handlersStack.Handlers
+	ctx.handlersStack.Handlers = append(stack[:len(stack):len(stack)], handlers...)
 	ctx.Next()
 }
+
+// headWriter discards the response body of a HEAD request served by a GET route, and counts its length.
+// The header is only written by flush, to report the Content-Length.
+type headWriter struct {
+	http.ResponseWriter
+	context *Context
+	status  int
+	length  int
+	flushed bool
+}
+
+// Write counts the discarded bytes.
+func (w *headWriter) Write(p []byte) (int, error) {
+	w.context.writer.record(http.StatusOK)
+	w.length += len(p)
+	return len(p), nil
+}
+
+// WriteHeader keeps the status for flush, and records it so that the middleware see it.
+func (w *headWriter) WriteHeader(code int) {
+	w.context.writer.record(code)
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// flush writes the header with the Content-Length of the discarded body.
+func (w *headWriter) flush() {
+	w.flushed = true
+	if w.status == 0 && w.context.written == false {
+		// Nothing has been written, let the server write the default response.
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.Header().Get("Content-Length") == "" && w.length > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
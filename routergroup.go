@@ -5,6 +5,7 @@
 package core
 
 import (
+	"net/http"
 	"regexp"
 )
 
@@ -16,15 +17,15 @@ type IRouter interface {
 
 // IRoutes routes interface
 type IRoutes interface {
-	Handle(string, string, ...RouterHandler) IRoutes
-	Any(string, ...RouterHandler) IRoutes
-	GET(string, ...RouterHandler) IRoutes
-	POST(string, ...RouterHandler) IRoutes
-	DELETE(string, ...RouterHandler) IRoutes
-	PATCH(string, ...RouterHandler) IRoutes
-	PUT(string, ...RouterHandler) IRoutes
-	OPTIONS(string, ...RouterHandler) IRoutes
-	HEAD(string, ...RouterHandler) IRoutes
+	Handle(string, string, ...RouterHandler) *Route
+	Any(string, ...RouterHandler) *Route
+	GET(string, ...RouterHandler) *Route
+	POST(string, ...RouterHandler) *Route
+	DELETE(string, ...RouterHandler) *Route
+	PATCH(string, ...RouterHandler) *Route
+	PUT(string, ...RouterHandler) *Route
+	OPTIONS(string, ...RouterHandler) *Route
+	HEAD(string, ...RouterHandler) *Route
 }
 
 // RouterHandler http handler
@@ -40,6 +41,8 @@ type RouterGroup struct {
 	basePath string
 	engine   *Engine
 	root     bool
+	headers  http.Header // Default response headers of the group routes.
+	guards   routeGuards // The scopes and the claims required by the group routes.
 }
 
 var _ IRouter = &RouterGroup{}
@@ -59,19 +62,64 @@ func (group *RouterGroup) Group(relativePath string, handlers ...RouterHandler)
 		Handlers: group.combineHandlers(handlers),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+		headers:  cloneHeader(group.headers),
+		guards:   *group.guards.clone(),
 	}
 }
 
+// Header sets a default response header of the group routes, applied before the handlers are called, so they can still change it.
+// Like Use, it only applies to the routes and groups registered afterwards.
+// Called on Routers, it sets a default header of all the routes.
+func (group *RouterGroup) Header(key, value string) *RouterGroup {
+	if group.headers == nil {
+		group.headers = make(http.Header)
+	}
+	group.headers.Set(key, value)
+	return group
+}
+
+// RequireScopes requires the scopes from the principal of the requests of the group routes, like Route.RequireScopes.
+// Like Use, it only applies to the routes and groups registered afterwards, but they are guarded as soon as they are added:
+// use it for the routes added while serving.
+func (group *RouterGroup) RequireScopes(scopes ...string) *RouterGroup {
+	group.guards.scopes = append(group.guards.scopes, scopes...)
+	return group
+}
+
+// RequireClaim requires the claim name of the principal of the requests of the group routes to be one of values,
+// like Route.RequireClaim. It applies like RequireScopes.
+func (group *RouterGroup) RequireClaim(name string, values ...string) *RouterGroup {
+	if group.guards.claims == nil {
+		group.guards.claims = map[string][]string{}
+	}
+	group.guards.claims[name] = append(group.guards.claims[name], values...)
+	return group
+}
+
 // BasePath set group base path
 func (group *RouterGroup) BasePath() string {
 	return group.basePath
 }
 
-func (group *RouterGroup) handle(httpMethod, relativePath string, handlers RouterHandlerChain) IRoutes {
-	absolutePath := group.calculateAbsolutePath(relativePath)
+func (group *RouterGroup) handle(httpMethod, relativePath string, handlers RouterHandlerChain) *Route {
+	route := group.newRoute(relativePath)
+	group.addRoute(route, httpMethod, handlers)
+	return route
+}
+
+func (group *RouterGroup) addRoute(route *Route, httpMethod string, handlers RouterHandlerChain) {
 	handlers = group.combineHandlers(handlers)
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
-	return group.returnObj()
+	assert1(len(handlers) > 0, "there must be at least one handler")
+	if route.names == nil {
+		route.names = handlerNames(handlers)
+	}
+	// The route options run first, and the route guard just before the route handler.
+	chain := append(RouterHandlerChain{route.serve}, handlers[:len(handlers)-1]...)
+	handlers = append(chain, route.guard, handlers[len(handlers)-1])
+	if len(group.headers) > 0 {
+		handlers = append(RouterHandlerChain{headersHandler(cloneHeader(group.headers))}, handlers...)
+	}
+	group.engine.addRoute(httpMethod, route, handlers)
 }
 
 // Handle registers a new request handle and middleware with the given path and method.
@@ -84,7 +132,7 @@ func (group *RouterGroup) handle(httpMethod, relativePath string, handlers Route
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...RouterHandler) *Route {
 	if matches, err := regexp.MatchString("^[A-Z]+$", httpMethod); !matches || err != nil {
 		panic("http method " + httpMethod + " is not valid")
 	}
@@ -92,53 +140,48 @@ func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...Ro
 }
 
 // POST is a shortcut for router.Handle("POST", path, handle).
-func (group *RouterGroup) POST(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) POST(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("POST", relativePath, handlers)
 }
 
 // GET is a shortcut for router.Handle("GET", path, handle).
-func (group *RouterGroup) GET(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) GET(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("GET", relativePath, handlers)
 }
 
 // DELETE is a shortcut for router.Handle("DELETE", path, handle).
-func (group *RouterGroup) DELETE(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) DELETE(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("DELETE", relativePath, handlers)
 }
 
 // PATCH is a shortcut for router.Handle("PATCH", path, handle).
-func (group *RouterGroup) PATCH(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) PATCH(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("PATCH", relativePath, handlers)
 }
 
 // PUT is a shortcut for router.Handle("PUT", path, handle).
-func (group *RouterGroup) PUT(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) PUT(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("PUT", relativePath, handlers)
 }
 
 // OPTIONS is a shortcut for router.Handle("OPTIONS", path, handle).
-func (group *RouterGroup) OPTIONS(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) OPTIONS(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("OPTIONS", relativePath, handlers)
 }
 
 // HEAD is a shortcut for router.Handle("HEAD", path, handle).
-func (group *RouterGroup) HEAD(relativePath string, handlers ...RouterHandler) IRoutes {
+func (group *RouterGroup) HEAD(relativePath string, handlers ...RouterHandler) *Route {
 	return group.handle("HEAD", relativePath, handlers)
 }
 
 // Any registers a route that matches all the HTTP methods.
 // GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
-func (group *RouterGroup) Any(relativePath string, handlers ...RouterHandler) IRoutes {
-	group.handle("GET", relativePath, handlers)
-	group.handle("POST", relativePath, handlers)
-	group.handle("PUT", relativePath, handlers)
-	group.handle("PATCH", relativePath, handlers)
-	group.handle("HEAD", relativePath, handlers)
-	group.handle("OPTIONS", relativePath, handlers)
-	group.handle("DELETE", relativePath, handlers)
-	group.handle("CONNECT", relativePath, handlers)
-	group.handle("TRACE", relativePath, handlers)
-	return group.returnObj()
+func (group *RouterGroup) Any(relativePath string, handlers ...RouterHandler) *Route {
+	route := group.newRoute(relativePath)
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "HEAD", "OPTIONS", "DELETE", "CONNECT", "TRACE"} {
+		group.addRoute(route, method, handlers)
+	}
+	return route
 }
 
 func (group *RouterGroup) combineHandlers(handlers RouterHandlerChain) RouterHandlerChain {
@@ -152,6 +195,28 @@ func (group *RouterGroup) combineHandlers(handlers RouterHandlerChain) RouterHan
 	return mergedHandlers
 }
 
+// headersHandler returns a handler setting the headers h on the response.
+func headersHandler(h http.Header) RouterHandler {
+	return func(ctx *Context) {
+		rh := ctx.ResponseWriter.Header()
+		for k, v := range h {
+			rh[k] = v[:len(v):len(v)]
+		}
+		ctx.Next()
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
 func (group *RouterGroup) calculateAbsolutePath(relativePath string) string {
 	return joinPaths(group.basePath, relativePath)
 }
@@ -0,0 +1,379 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveRouter serves the request method path with the router engine.
+func serveRouter(engine *Engine, method, path string) *httptest.ResponseRecorder {
+	hs := NewHandlersStack()
+	hs.Use(engine.Handler())
+	r, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	return w
+}
+
+func TestGroupHeader(t *testing.T) {
+	engine := New()
+	engine.Header("X-API-Version", "1")
+	v2 := engine.Group("/v2")
+	v2.Header("X-API-Version", "2").Header("Cache-Control", "max-age=60")
+	v2.GET("/users", func(c *Context) { c.Ok(nil) })
+	v2.GET("/live", func(c *Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Ok(nil)
+	})
+	engine.GET("/users", func(c *Context) { c.Ok(nil) })
+
+	tests := []struct {
+		path, version, cacheControl string
+	}{
+		{"/users", "1", "no-cache"},
+		{"/v2/users", "2", "max-age=60"},
+		{"/v2/live", "2", "no-store"},
+	}
+	for _, tt := range tests {
+		w := serveRouter(engine, "GET", tt.path)
+		if got := w.Header().Get("X-API-Version"); got != tt.version {
+			t.Errorf("%s: X-API-Version: want %q, got %q", tt.path, tt.version, got)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: Cache-Control: want %q, got %q", tt.path, tt.cacheControl, got)
+		}
+	}
+}
+
+func TestAutoHEAD(t *testing.T) {
+	engine := New()
+	status := 0
+	engine.GET("/users/:id", func(c *Context) {
+		c.Next()
+		status = c.Status()
+	}, func(c *Context) {
+		c.Header("X-User", c.Param("id"))
+		c.Ok("john")
+	})
+
+	w := serveRouter(engine, "HEAD", "/users/42")
+	if w.Code != http.StatusOK || status != http.StatusOK {
+		t.Errorf("status code: want %d, got %d, %d in the middleware", http.StatusOK, w.Code, status)
+	}
+	if got := w.Header().Get("X-User"); got != "42" {
+		t.Errorf("header: want %q, got %q", "42", got)
+	}
+	get := serveRouter(engine, "GET", "/users/42")
+	if got, want := w.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+		t.Errorf("Content-Length: want %q, got %q", want, got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body: want empty, got %q", w.Body.String())
+	}
+
+	engine.AutoHEAD = false
+	if w := serveRouter(engine, "HEAD", "/users/42"); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: status code: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	v1 := &Interceptor{
+		Params: map[string]string{"uid": "id"},
+		Response: func(c *Context, res *ResFormat) {
+			res.Data = map[string]interface{}{"user": res.Data}
+		},
+	}
+	engine := New()
+	engine.GET("/v1/users/:uid", v1.Handler, func(c *Context) { c.Ok(c.Param("id")) })
+
+	w := serveRouter(engine, "GET", "/v1/users/42")
+	if want := `{"ok":true,"data":{"user":"42"},"message":"","errno":0}`; w.Body.String() != want {
+		t.Errorf("body: want %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestRouteDeprecate(t *testing.T) {
+	engine := New()
+	sunset := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	engine.GET("/v1/users", func(c *Context) { c.Ok(nil) }).
+		Deprecate(sunset, "https://example.com/docs/v2").
+		GET("/v2/users", func(c *Context) { c.Ok(nil) })
+
+	w := serveRouter(engine, "GET", "/v1/users")
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation: want true, got %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Sat, 01 Jun 2030 00:00:00 GMT" {
+		t.Errorf("Sunset: got %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://example.com/docs/v2>; rel="deprecation"` {
+		t.Errorf("Link: got %q", got)
+	}
+	if got := Metrics.Get("deprecated.GET /v1/users"); got == nil || got.String() != "1" {
+		t.Errorf("metric: want 1, got %v", got)
+	}
+
+	w = serveRouter(engine, "GET", "/v2/users")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+		t.Errorf("/v2/users: want a non deprecated 200, got %d %q", w.Code, w.Header().Get("Deprecation"))
+	}
+}
+
+func TestMountDiscovery(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:id", func(c *Context) { c.Ok(nil) })
+	engine.DELETE("/users/:id", func(c *Context) { c.Ok(nil) }).Deprecate(time.Time{}, "")
+	MountDiscovery(engine, "/_routes", func(c *Context) { c.Next() })
+
+	w := serveRouter(engine, "GET", "/_routes")
+	want := `{"ok":true,"data":[{"path":"/users/:id","methods":["GET","DELETE"],"params":["id"],"deprecated":["DELETE"]},` +
+		`{"path":"/_routes","methods":["GET"]}],"message":"","errno":0}`
+	if w.Body.String() != want {
+		t.Errorf("body: want %s, got %s", want, w.Body.String())
+	}
+}
+
+func TestRouteOptions(t *testing.T) {
+	var limited int
+	RateClasses["test"] = func(c *Context) {
+		limited++
+		c.Next()
+	}
+	defer delete(RateClasses, "test")
+
+	engine := New()
+	auth := engine.Group("/", func(c *Context) {
+		if scopes := c.Request.URL.Query().Get("scopes"); scopes != "" {
+			c.SetPrincipal(&Principal{ID: "bob", Scopes: []string{scopes}})
+		}
+		c.Next()
+	})
+	auth.GET("/reports", func(c *Context) { c.Ok(nil) }).Options(RouteOptions{
+		Scopes:    []string{"reports:read"},
+		RateClass: "test",
+		CacheTTL:  time.Minute,
+	})
+	auth.GET("/slow", func(c *Context) { <-c.Request.Context().Done() }).Options(RouteOptions{Timeout: 10 * time.Millisecond})
+
+	tests := []struct {
+		path         string
+		code         int
+		cacheControl string
+	}{
+		{"/reports", http.StatusUnauthorized, "no-cache"},
+		{"/reports?scopes=orders:read", http.StatusForbidden, "no-cache"},
+		{"/reports?scopes=reports:read", http.StatusOK, "max-age=60"},
+		{"/slow", http.StatusServiceUnavailable, "no-cache"},
+	}
+	for _, tt := range tests {
+		w := serveRouter(engine, "GET", tt.path)
+		if w.Code != tt.code {
+			t.Errorf("%s: want %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: Cache-Control: want %q, got %q", tt.path, tt.cacheControl, got)
+		}
+	}
+	// The requests rejected by the authentication or the scopes aren't counted.
+	if limited != 1 {
+		t.Errorf("rate class: want 1 limited request, got %d", limited)
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	engine := New()
+	auth := engine.Group("/", func(c *Context) {
+		if role := c.Request.URL.Query().Get("role"); role != "" {
+			c.SetPrincipal(&Principal{ID: "bob", Scopes: []string{"admin:read"}, Claims: map[string]interface{}{"roles": []interface{}{role}}})
+		}
+		c.Next()
+	})
+	auth.GET("/admin", func(c *Context) { c.Ok(nil) }).RequireScopes("admin:read").RequireClaim("roles", "admin", "owner")
+
+	for path, code := range map[string]int{
+		"/admin":            http.StatusUnauthorized,
+		"/admin?role=guest": http.StatusForbidden,
+		"/admin?role=owner": http.StatusOK,
+	} {
+		if w := serveRouter(engine, "GET", path); w.Code != code {
+			t.Errorf("%s: want %d, got %d", path, code, w.Code)
+		}
+	}
+	info := engine.Routes()[0]
+	if len(info.Scopes) != 1 || info.Scopes[0] != "admin:read" || len(info.Claims["roles"]) != 2 {
+		t.Errorf("route info: want the scopes and the claims, got %+v", info)
+	}
+
+	// The guards of a serving route can be changed while it serves, and are enforced from then.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			serveRouter(engine, "GET", "/admin?role=owner")
+			engine.Routes()
+		}
+	}()
+	route := auth.GET("/runtime", func(c *Context) { c.Ok(nil) })
+	for i := 0; i < 50; i++ {
+		route.RequireScopes("admin:read")
+	}
+	route.RequireClaim("roles", "admin")
+	<-done
+	if w := serveRouter(engine, "GET", "/runtime?role=owner"); w.Code != http.StatusForbidden {
+		t.Errorf("runtime guard: want 403, got %d", w.Code)
+	}
+
+	// The routes of a guarded group are guarded as soon as they are added.
+	admin := auth.Group("/guarded").RequireScopes("admin:read").RequireClaim("roles", "admin")
+	admin.GET("/users", func(c *Context) { c.Ok(nil) })
+	for path, code := range map[string]int{
+		"/guarded/users":            http.StatusUnauthorized,
+		"/guarded/users?role=owner": http.StatusForbidden,
+		"/guarded/users?role=admin": http.StatusOK,
+	} {
+		if w := serveRouter(engine, "GET", path); w.Code != code {
+			t.Errorf("%s: want %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	engine := New()
+	engine.GET("/users", func(c *Context) { c.Ok(nil) })
+	engine.POST("/users", func(c *Context) { c.Ok(nil) })
+	engine.NoRoute(func(c *Context) { c.FailWithCode(http.StatusNotFound, "route_not_found", c.Request.URL.Path) })
+
+	w := serveRouter(engine, "GET", "/orders")
+	if want := `{"ok":false,"data":null,"message":"/orders","errno":0,"code":"route_not_found"}`; w.Code != http.StatusNotFound || w.Body.String() != want {
+		t.Errorf("no route: want 404 %s, got %d %s", want, w.Code, w.Body.String())
+	}
+	if w := serveRouter(engine, "DELETE", "/users"); w.Code != http.StatusNotFound {
+		t.Errorf("no method, disabled: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	engine.HandleMethodNotAllowed = true
+	w = serveRouter(engine, "DELETE", "/users")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Errorf("no method: want 405 GET, POST, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+	engine.NoMethod(func(c *Context) { c.FailWithCode(http.StatusMethodNotAllowed, "method_not_allowed", c.Request.Method) })
+	if w := serveRouter(engine, "DELETE", "/users"); w.Code != http.StatusMethodNotAllowed || w.Body.Len() == 0 {
+		t.Errorf("no method handler: got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFallback(t *testing.T) {
+	engine := New()
+	engine.GET("/api/users", func(c *Context) { c.Ok(nil) })
+	engine.Fallback(func(c *Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		c.HTML(http.StatusOK, "<html></html>")
+	})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/users", http.StatusOK},
+		{"/settings/profile", http.StatusOK},
+		{"/api/orders", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serveRouter(engine, "GET", tt.path); w.Code != tt.code {
+			t.Errorf("%s: want %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+func TestDynamicRoutes(t *testing.T) {
+	engine := New()
+	engine.GET("/users", func(c *Context) { c.Ok(nil) })
+	if w := serveRouter(engine, "GET", "/users"); w.Code != http.StatusOK {
+		t.Fatalf("/users: want %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Serving: the routes changes swap the trees.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			serveRouter(engine, "GET", "/users")
+		}
+		close(done)
+	}()
+	engine.GET("/old", func(c *Context) { c.Redirect("/users", http.StatusMovedPermanently) })
+	<-done
+	if w := serveRouter(engine, "GET", "/old"); w.Code != http.StatusMovedPermanently {
+		t.Errorf("added: want %d, got %d", http.StatusMovedPermanently, w.Code)
+	}
+	if engine.RemoveRoute("GET", "/old") == false {
+		t.Errorf("removed: want true")
+	}
+	if engine.RemoveRoute("GET", "/old") == true {
+		t.Errorf("removed twice: want false")
+	}
+	if w := serveRouter(engine, "GET", "/old"); w.Code != http.StatusNotFound {
+		t.Errorf("removed: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := serveRouter(engine, "GET", "/users"); w.Code != http.StatusOK {
+		t.Errorf("kept: want %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(engine.Routes()) != 1 {
+		t.Errorf("routes: want 1, got %d", len(engine.Routes()))
+	}
+}
+
+// listUsers is a named handler for TestHandlerNames.
+func listUsers(c *Context) { c.Ok(nil) }
+
+func TestHandlerNames(t *testing.T) {
+	engine := New()
+	var handler, responder string
+	engine.Use(func(c *Context) {
+		c.Next()
+		handler, responder = c.HandlerName(), c.ResponderName()
+	})
+	engine.GET("/users", listUsers).Options(RouteOptions{Scopes: []string{"users:read"}})
+
+	routes := engine.Routes()
+	if names := routes[0].HandlerNames; len(names) != 2 || names[1] != "github.com/HiLittleCat/core.listUsers" {
+		t.Errorf("route names: got %v", names)
+	}
+	serveRouter(engine, "GET", "/users")
+	if handler != "github.com/HiLittleCat/core.listUsers" || responder != "github.com/HiLittleCat/core.(*Route).guard" {
+		t.Errorf("handler %q, responder %q", handler, responder)
+	}
+}
+
+func TestRoutesStats(t *testing.T) {
+	engine := New()
+	var inflight int64
+	engine.GET("/stats/:id", func(c *Context) {
+		for _, s := range RoutesStats() {
+			if s.Route == "GET /stats/:id" {
+				inflight = s.Inflight
+			}
+		}
+		c.Ok(nil)
+	})
+	serveRouter(engine, "GET", "/stats/1")
+	serveRouter(engine, "HEAD", "/stats/2")
+
+	for _, s := range RoutesStats() {
+		if s.Route != "GET /stats/:id" {
+			continue
+		}
+		if inflight != 1 || s.Inflight != 0 || s.MaxInflight != 1 || s.Requests != 2 {
+			t.Errorf("want 1 in flight during the request, then 0 of max 1 after 2 requests, got %d then %+v", inflight, s)
+		}
+		return
+	}
+	t.Errorf("route stats not found")
+}
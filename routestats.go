@@ -0,0 +1,74 @@
+package core
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// routeStats are the concurrency counters of a route for a method.
+type routeStats struct {
+	inflight    int64
+	maxInflight int64
+	requests    int64
+	waitNanos   int64 // The total time from the request entering the handlers stack until its route is dispatched.
+}
+
+// RouteStats are the concurrency statistics of a route for a method, in Metrics under "routes" and in the admin endpoint.
+type RouteStats struct {
+	Route       string  `json:"route"` // "<METHOD> <path>".
+	Inflight    int64   `json:"inflight"`
+	MaxInflight int64   `json:"maxInflight"`
+	Requests    int64   `json:"requests"`
+	AvgWaitMs   float64 `json:"avgWaitMs"` // The average time in the middleware and queues before dispatch.
+}
+
+// allRouteStats are the *routeStats by "<METHOD> <path>".
+var allRouteStats sync.Map
+
+func init() {
+	Metrics.Set("routes", expvar.Func(func() interface{} { return RoutesStats() }))
+}
+
+// statsFor returns the stats of the route of method and path, shared by the engines.
+func statsFor(method, path string) *routeStats {
+	s, _ := allRouteStats.LoadOrStore(method+" "+path, new(routeStats))
+	return s.(*routeStats)
+}
+
+// dispatch counts the request of ctx until done is called.
+func (s *routeStats) dispatch(ctx *Context) (done func()) {
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.waitNanos, int64(time.Since(ctx.start)))
+	n := atomic.AddInt64(&s.inflight, 1)
+	for {
+		max := atomic.LoadInt64(&s.maxInflight)
+		if n <= max || atomic.CompareAndSwapInt64(&s.maxInflight, max, n) == true {
+			break
+		}
+	}
+	return func() { atomic.AddInt64(&s.inflight, -1) }
+}
+
+// RoutesStats returns the concurrency statistics of the routes, sorted by route.
+func RoutesStats() []RouteStats {
+	var stats []RouteStats
+	allRouteStats.Range(func(k, v interface{}) bool {
+		s := v.(*routeStats)
+		rs := RouteStats{
+			Route:       k.(string),
+			Inflight:    atomic.LoadInt64(&s.inflight),
+			MaxInflight: atomic.LoadInt64(&s.maxInflight),
+			Requests:    atomic.LoadInt64(&s.requests),
+		}
+		if rs.Requests > 0 {
+			rs.AvgWaitMs = float64(atomic.LoadInt64(&s.waitNanos)) / float64(rs.Requests) / float64(time.Millisecond)
+		}
+		stats = append(stats, rs)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
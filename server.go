@@ -1,6 +1,8 @@
 package core
 
 import (
+	"crypto/tls"
+	"expvar"
 	"flag"
 	"fmt"
 	"net"
@@ -9,6 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"os"
+	"sync"
 	"time"
 
 	"gopkg.in/tylerb/graceful.v1"
@@ -28,7 +31,7 @@ var (
 	beforeRun []func()
 
 	// Timeout is the duration to allow outstanding requests to survive
-	// before forcefully terminating them.
+	// before forcefully terminating them, on the graceful shutdown and the graceful restart.
 	Timeout = 30 * time.Second
 
 	// ListenLimit Limit the number of outstanding requests
@@ -51,9 +54,60 @@ var (
 
 	// MaxHeaderBytes Max HTTP Herder size, default is 0, no limit
 	MaxHeaderBytes = 1 << 20
+
+	// TrustProxy trusts the X-Forwarded-For and X-Real-Ip headers of the TrustedProxies to get the client IP.
+	// Only set it behind a proxy setting them.
+	TrustProxy bool
+
+	// TrustedProxies are the CIDRs of the proxies in front of the server when TrustProxy is set, default is the loopback and private networks.
+	// The client IP is the right-most X-Forwarded-For entry out of them: the entries before may be sent by the client.
+	TrustedProxies = []string{"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"}
+
+	// TLSCertFile and TLSKeyFile are the certificate and key files of the server. When set, the server serves HTTPS.
+	TLSCertFile, TLSKeyFile string
+
+	// TLSConfig is the base TLS configuration of the server, for the options not covered by the TLS variables. Setting it serves HTTPS.
+	TLSConfig *tls.Config
+
+	// ClientCAFile is the file of the CA certificates verifying the client certificates, for the mTLS authentication.
+	ClientCAFile string
+
+	// ClientAuth is the client certificates policy when ClientCAFile is set. Default is tls.RequireAndVerifyClientCert,
+	// tls.VerifyClientCertIfGiven lets the clients without certificate through, to authenticate them otherwise.
+	ClientAuth = tls.RequireAndVerifyClientCert
+
+	// GracefulRestart enables the zero-downtime restart: on SIGUSR2, a new process of the server is started with the listener,
+	// then the old process stops accepting connections and drains its requests for Timeout. Not supported on Windows.
+	GracefulRestart bool
 )
 
+// connStats are the connections counters, published in Metrics under "conn".
+var connStats = new(expvar.Map).Init()
+
+// connStates keeps the state of the open connections, to count the transitions.
+var connStates sync.Map
+
 func init() {
+	Metrics.Set("conn", connStats)
+}
+
+// trackConnState counts the connections by state.
+func trackConnState(conn net.Conn, state http.ConnState) {
+	if prev, ok := connStates.Load(conn); ok == true && prev.(http.ConnState) == http.StateActive {
+		connStats.Add("active", -1)
+	}
+	switch state {
+	case http.StateNew:
+		connStats.Add("total", 1)
+		connStats.Add("open", 1)
+	case http.StateActive:
+		connStats.Add("active", 1)
+	case http.StateHijacked, http.StateClosed:
+		connStats.Add("open", -1)
+		connStates.Delete(conn)
+		return
+	}
+	connStates.Store(conn, state)
 }
 
 // BeforeRun adds a function that will be triggered just before running the server.
@@ -71,19 +125,29 @@ func Run() {
 	if OpenCommandLine {
 		flag.StringVar(&Address, "address", ":8080", "-address=:8080")
 		flag.BoolVar(&Production, "production", false, "-production=false")
+		flag.BoolVar(&DisablePool, "disable-pool", DisablePool, "-disable-pool=false")
 		flag.Parse()
 	}
 
+	if err := runHooks("start", onStart, false); err != nil {
+		log.Fatalln(err)
+	}
+
 	log.Warnln(fmt.Sprintf("Serving %s with pid %d. Production is %t.", Address, os.Getpid(), Production))
+	if Production == false {
+		printBanner(BannerOutput, Banner, newBannerInfo(defaultHandlersStack, Routers))
+	}
 
 	// set default router.
 	Use(Routers.handlers)
 
 	// set graceful server.
 	srv := &graceful.Server{
-		ListenLimit: ListenLimit,
+		Timeout:        Timeout,
+		ListenLimit:    ListenLimit,
+		BeforeShutdown: beforeShutdown,
 		ConnState: func(conn net.Conn, state http.ConnState) {
-			// conn has a new state
+			trackConnState(conn, state)
 		},
 		Server: &http.Server{
 			Addr:           Address,
@@ -94,8 +158,33 @@ func Run() {
 			MaxHeaderBytes: MaxHeaderBytes,
 		},
 	}
-	err := srv.ListenAndServe()
-
+	l, inherited, err := listen(Address)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if GracefulRestart {
+		go watchRestart(srv, l)
+	}
+	if TLSCertFile != "" || TLSConfig != nil {
+		cfg, err := serverTLSConfig()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		l = tls.NewListener(l, cfg)
+	}
+	if inherited {
+		// The previous process is stopped once this one is serving, the connections meanwhile wait in the listener backlog.
+		l = &servingListener{Listener: l, serving: notifyParent}
+	}
+	go func() {
+		logHooks(runHooks("ready", onReady, false))
+		logHooks(runHooks("warm-up", warmUps, false))
+		setReady(true)
+	}()
+	err = srv.Serve(l)
+
+	logHooks(runHooks("stop", onStop, true))
+	defaultHandlersStack.ShutdownPlugins()
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -47,7 +47,7 @@ func session(ctx *Context) {
 	}
 	if len(store.Values) > 0 {
 		//err := provider.refresh(store)
-		err := provider.UpExpire(sid)
+		err := provider.UpExpire(sid, store.Values["User"])
 		if err != nil {
 			log.WithFields(log.Fields{"sid": sid, "err": err}).Warnln("刷新session失败")
 			ctx.Fail(err)
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"expvar"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Priority is the priority class of a request, the lowest ones are shed first.
+type Priority int
+
+// The priority classes.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical // Never shed, for health checks and admin endpoints.
+)
+
+// String returns the name of the priority class.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "normal"
+}
+
+// PriorityHeader is the request header giving the priority class: low, normal, high or critical.
+// The clients can lower their priority with it, a higher one is only taken on the routes using PriorityFromHeader.
+var PriorityHeader = "X-Priority"
+
+// WithPriority returns a handler annotating the next handlers of the route or group with the priority class p.
+// It must be used before the Shedder handler.
+func WithPriority(p Priority) RouterHandler {
+	return func(ctx *Context) {
+		ctx.Data["priority"] = p
+		ctx.Next()
+	}
+}
+
+// PriorityFromHeader returns a handler taking the priority class of the next handlers from the PriorityHeader, up to max.
+// The header is sent by the clients: use it after the authentication of the trusted callers, like the internal services.
+//
+//	internal := core.Routers.Group("/internal", certs.Handler, core.PriorityFromHeader(core.PriorityCritical), shedder.Handler)
+func PriorityFromHeader(max Priority) RouterHandler {
+	return func(ctx *Context) {
+		if p, ok := parsePriority(ctx.Request.Header.Get(PriorityHeader)); ok == true {
+			if p > max {
+				p = max
+			}
+			ctx.Data["priority"] = p
+		}
+		ctx.Next()
+	}
+}
+
+// Priority returns the priority class of the request, from WithPriority or PriorityFromHeader, PriorityLow if the PriorityHeader
+// asks for it, or PriorityNormal.
+func (ctx *Context) Priority() Priority {
+	if p, ok := ctx.Data["priority"].(Priority); ok == true {
+		return p
+	}
+	if p, _ := parsePriority(ctx.Request.Header.Get(PriorityHeader)); p == PriorityLow {
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+// parsePriority returns the priority class named s, and false if it is unknown.
+func parsePriority(s string) (Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	case "critical":
+		return PriorityCritical, true
+	}
+	return PriorityNormal, false
+}
+
+// Shedder rejects requests when the server approaches overload, the lowest priorities first.
+//
+// The load is the highest of the in-flight requests against MaxInFlight, and the average latency against TargetLatency.
+// The average decays with the time, by half each LatencyHalfLife, so that the load falls back while the requests are shed.
+// A request is rejected with a 503 Service Unavailable when the load reaches the threshold of its priority.
+// The load is published in Metrics under "shedder.<name>.load", the rejected requests under "shedder.<name>.rejected".
+type Shedder struct {
+	Name            string
+	MaxInFlight     int           // The in-flight requests making a load of 1.
+	TargetLatency   time.Duration // The average latency making a load of 1.
+	LatencyHalfLife time.Duration // Default is 1 second.
+
+	// Thresholds are the loads from which the requests of each priority are shed.
+	// Default sheds low at 1, normal at 1.25 and high at 1.5, critical is never shed.
+	Thresholds map[Priority]float64
+
+	inflight int32
+	mu       sync.Mutex
+	latency  float64   // The exponentially weighted moving average of the latency, in seconds.
+	observed time.Time // The time of the last latency.
+}
+
+// NewShedder returns a new Shedder with the default thresholds.
+func NewShedder(name string, maxInFlight int, targetLatency time.Duration) *Shedder {
+	s := &Shedder{
+		Name:            name,
+		MaxInFlight:     maxInFlight,
+		TargetLatency:   targetLatency,
+		LatencyHalfLife: time.Second,
+		Thresholds: map[Priority]float64{
+			PriorityLow:    1,
+			PriorityNormal: 1.25,
+			PriorityHigh:   1.5,
+		},
+	}
+	Metrics.Set("shedder."+name+".load", expvar.Func(func() interface{} { return s.Load() }))
+	return s
+}
+
+// Load returns the current load, 1 being the target.
+func (s *Shedder) Load() float64 {
+	load := 0.0
+	if s.MaxInFlight > 0 {
+		load = float64(atomic.LoadInt32(&s.inflight)) / float64(s.MaxInFlight)
+	}
+	if s.TargetLatency > 0 {
+		s.mu.Lock()
+		latency := s.decayed(time.Now())
+		s.mu.Unlock()
+		load = math.Max(load, latency/s.TargetLatency.Seconds())
+	}
+	return load
+}
+
+// Handler is the middleware shedding the next handlers.
+func (s *Shedder) Handler(ctx *Context) {
+	if threshold, ok := s.Thresholds[ctx.Priority()]; ok == true && s.Load() >= threshold {
+		Metrics.Add("shedder."+s.Name+".rejected", 1)
+		err := (&ServerError{}).New(http.StatusText(http.StatusServiceUnavailable))
+		err.Details = &ErrorDetails{RetryAfter: 1}
+		ctx.FailWithStatus(http.StatusServiceUnavailable, err)
+		return
+	}
+
+	atomic.AddInt32(&s.inflight, 1)
+	start := time.Now()
+	defer func() {
+		atomic.AddInt32(&s.inflight, -1)
+		s.observe(time.Since(start))
+	}()
+	ctx.Next()
+}
+
+// observe adds a latency to the moving average.
+func (s *Shedder) observe(d time.Duration) {
+	const alpha = 0.1
+	now := time.Now()
+	s.mu.Lock()
+	s.latency = alpha*d.Seconds() + (1-alpha)*s.decayed(now)
+	s.observed = now
+	s.mu.Unlock()
+}
+
+// decayed returns the average latency decayed from the last latency to now. s.mu must be held.
+func (s *Shedder) decayed(now time.Time) float64 {
+	if s.LatencyHalfLife <= 0 || s.observed.IsZero() == true {
+		return s.latency
+	}
+	return s.latency * math.Exp2(-float64(now.Sub(s.observed))/float64(s.LatencyHalfLife))
+}
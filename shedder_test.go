@@ -0,0 +1,96 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShedderDecay(t *testing.T) {
+	s := NewShedder("decay", 0, 100*time.Millisecond)
+	hs := NewHandlersStack()
+	hs.Use(s.Handler)
+	hs.Use(func(c *Context) { c.Ok(nil) })
+	serve := func() int {
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Code
+	}
+
+	// An overload sheds the requests, none is observed then.
+	s.latency, s.observed = 0.2, time.Now()
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Fatalf("overloaded: want 503, got %d", code)
+	}
+	// Three half-lives later, the average is 25ms: the load is back under the thresholds.
+	s.observed = time.Now().Add(-3 * s.LatencyHalfLife)
+	if load := s.Load(); load >= 1 {
+		t.Errorf("decayed load: want < 1, got %f", load)
+	}
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("after the decay: want 200, got %d", code)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	priority := func(header string, handlers ...RouterHandler) Priority {
+		var got Priority
+		hs := NewHandlersStack()
+		for _, h := range handlers {
+			hs.Use(h)
+		}
+		hs.Use(func(c *Context) { got = c.Priority() })
+		r := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			r.Header.Set(PriorityHeader, header)
+		}
+		hs.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+	tests := []struct {
+		name     string
+		header   string
+		handlers []RouterHandler
+		want     Priority
+	}{
+		{"default", "", nil, PriorityNormal},
+		{"lowered by the client", "low", nil, PriorityLow},
+		{"raised by the client", "critical", nil, PriorityNormal},
+		{"route", "critical", []RouterHandler{WithPriority(PriorityLow)}, PriorityLow},
+		{"trusted header", "high", []RouterHandler{PriorityFromHeader(PriorityCritical)}, PriorityHigh},
+		{"capped header", "critical", []RouterHandler{PriorityFromHeader(PriorityHigh)}, PriorityHigh},
+		{"unknown header", "urgent", []RouterHandler{PriorityFromHeader(PriorityHigh)}, PriorityNormal},
+	}
+	for _, tt := range tests {
+		if got := priority(tt.header, tt.handlers...); got != tt.want {
+			t.Errorf("%s: want %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestShedderPriorities(t *testing.T) {
+	s := NewShedder("priorities", 0, 100*time.Millisecond)
+	s.latency, s.observed = 0.11, time.Now()
+	serve := func(header string) int {
+		hs := NewHandlersStack()
+		hs.Use(s.Handler)
+		hs.Use(func(c *Context) { c.Ok(nil) })
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(PriorityHeader, header)
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w.Code
+	}
+	// At a load of 1.1, the low requests are shed, and a client can't escape with the header.
+	if code := serve("low"); code != http.StatusServiceUnavailable {
+		t.Errorf("low: want 503, got %d", code)
+	}
+	if code := serve("critical"); code != http.StatusOK {
+		t.Errorf("critical header: want 200 as normal, got %d", code)
+	}
+	s.latency, s.observed = 1, time.Now()
+	if code := serve("critical"); code != http.StatusServiceUnavailable {
+		t.Errorf("critical header under overload: want 503, got %d", code)
+	}
+}
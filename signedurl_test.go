@@ -0,0 +1,57 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	defer func(key []byte) { SignedURLKey = key }(SignedURLKey)
+	SignedURLKey = []byte("0123456789abcdef0123456789abcdef")
+	engine := New()
+	engine.GET("/unsubscribe", VerifySignedURL, func(ctx *Context) {
+		ctx.Text(http.StatusOK, ctx.Request.URL.Query().Get("user"))
+	})
+
+	link := SignURL("/unsubscribe", url.Values{"user": {"42"}}, time.Hour)
+	if strings.Contains(link, "expires=") == false || strings.Contains(link, "signature=") == false {
+		t.Fatalf("SignURL: got %s", link)
+	}
+	if w := serveRouter(engine, "GET", link); w.Code != http.StatusOK || w.Body.String() != "42" {
+		t.Errorf("signed: got %d %q", w.Code, w.Body.String())
+	}
+	if w := serveRouter(engine, "GET", strings.Replace(link, "user=42", "user=43", 1)); w.Code != http.StatusForbidden {
+		t.Errorf("tampered: want 403, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", link+"&admin=1"); w.Code != http.StatusForbidden {
+		t.Errorf("added param: want 403, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", "/unsubscribe?user=42"); w.Code != http.StatusForbidden {
+		t.Errorf("unsigned: want 403, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", SignURL("/unsubscribe", url.Values{"user": {"42"}}, -time.Minute)); w.Code != http.StatusForbidden {
+		t.Errorf("expired: want 403, got %d", w.Code)
+	}
+	if w := serveRouter(engine, "GET", SignURL("/unsubscribe", url.Values{"user": {"7"}}, 0)); w.Code != http.StatusOK || w.Body.String() != "7" {
+		t.Errorf("never expires: got %d %q", w.Code, w.Body.String())
+	}
+
+	SignedURLKey = []byte("secret")
+	if w := serveRouter(engine, "GET", link); w.Code != http.StatusInternalServerError {
+		t.Errorf("short key: want 500, got %d", w.Code)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("SignURL with a short key: want a panic")
+			}
+		}()
+		SignURL("/unsubscribe", nil, time.Hour)
+	}()
+	if _, err := NewFSBlobStore(t.TempDir(), "http://example.com/blobs", []byte("secret")); err == nil {
+		t.Error("NewFSBlobStore with a short key: want an error")
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Singleflight deduplicates the concurrent calls of a same key: the first caller executes the function,
+// the others wait for its result instead of hitting the upstream again.
+//
+// Each caller can give up with its own context, the shared call is only canceled when all its callers have given up.
+type Singleflight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed Singleflight call.
+type flightCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	val     interface{}
+	err     error
+}
+
+// NewSingleflight returns a new Singleflight.
+func NewSingleflight() *Singleflight {
+	return &Singleflight{calls: make(map[string]*flightCall)}
+}
+
+// Do executes fn for key, unless a call for key is already in flight: its result is then shared.
+// shared tells if the result was given to several callers.
+// If ctx is done before the result, ctx.Err() is returned to this caller only.
+func (g *Singleflight) Do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if ok == true {
+		c.waiters++
+	} else {
+		callCtx, cancel := context.WithCancel(context.Background())
+		c = &flightCall{done: make(chan struct{}), cancel: cancel, waiters: 1}
+		g.calls[key] = c
+		go g.call(callCtx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = c.waiters > 1
+		g.mu.Unlock()
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, false, ctx.Err()
+	}
+}
+
+// Forget forgets the in-flight call of key: the next Do executes the function again.
+func (g *Singleflight) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
+
+// call executes fn and shares its result.
+func (g *Singleflight) call(ctx context.Context, key string, c *flightCall, fn func(context.Context) (interface{}, error)) {
+	defer func() {
+		if err := recover(); err != nil {
+			c.err = fmt.Errorf("singleflight: panic: %v", err)
+		}
+		c.cancel()
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = fn(ctx)
+}
+
+// Singleflight executes fn for key through the Singleflight g, canceled for this caller when the request is.
+func (ctx *Context) Singleflight(g *Singleflight, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	v, _, err := g.Do(ctx.Request.Context(), key, fn)
+	return v, err
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleflight(t *testing.T) {
+	g := NewSingleflight()
+	var calls int32
+	release := make(chan struct{})
+	fn := func(context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "result", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = g.Do(context.Background(), "key", fn)
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("calls: want 1, got %d", calls)
+	}
+	for i, r := range results {
+		if r != "result" {
+			t.Errorf("result %d: want %q, got %v", i, "result", r)
+		}
+	}
+}
+
+func TestSingleflightCancel(t *testing.T) {
+	g := NewSingleflight()
+	canceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := g.Do(ctx, "key", fn); err != context.DeadlineExceeded {
+		t.Errorf("error: want %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the shared call is not canceled when all the callers gave up")
+	}
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SitemapContentType is the content type of the sitemaps and the sitemap indexes.
+const SitemapContentType = "application/xml; charset=utf-8"
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+var (
+	// SitemapMaxURLs is the max number of URLs of a sitemap, 50000 by the protocol.
+	// Beyond it, Sitemap.Handler serves a sitemap index of several sitemaps.
+	SitemapMaxURLs = 50000
+
+	// SitemapMaxAge is the max-age of the Cache-Control header of the sitemaps, in place of the default no-cache.
+	SitemapMaxAge = time.Hour
+)
+
+// SitemapURL is a URL of a sitemap.
+type SitemapURL struct {
+	Loc        string    // The absolute URL of the page.
+	LastMod    time.Time // The zero time omits it.
+	ChangeFreq string    // Like "daily" or "weekly", "" omits it.
+	Priority   float64   // Between 0 and 1, 0 omits it.
+}
+
+// SitemapIterator returns the next URL of a sitemap, or the io.EOF error after the last one.
+type SitemapIterator func() (*SitemapURL, error)
+
+// SitemapEntry is a sitemap of a sitemap index.
+type SitemapEntry struct {
+	Loc     string    // The absolute URL of the sitemap.
+	LastMod time.Time // The zero time omits it.
+}
+
+// Sitemap serves the sitemap of Count URLs listed by URLs.
+// When there are more than SitemapMaxURLs, it serves a sitemap index of the sitemap pages, at the same URL with the page query parameter.
+//
+//	sitemap := core.NewSitemap(countProducts, func(ctx *core.Context, offset, limit int) core.SitemapIterator {
+//		rows := queryProducts(ctx, offset, limit)
+//		return func() (*core.SitemapURL, error) {
+//			if !rows.Next() {
+//				return nil, io.EOF
+//			}
+//			var p Product
+//			err := rows.Scan(&p.Slug, &p.Updated)
+//			return &core.SitemapURL{Loc: "https://example.com/p/" + p.Slug, LastMod: p.Updated}, err
+//		}
+//	})
+//	core.Routers.GET("/sitemap.xml", sitemap.Handler)
+type Sitemap struct {
+	Count func(ctx *Context) (int, error)
+	URLs  func(ctx *Context, offset, limit int) SitemapIterator
+}
+
+// NewSitemap returns a new Sitemap of the count URLs listed by urls.
+func NewSitemap(count func(ctx *Context) (int, error), urls func(ctx *Context, offset, limit int) SitemapIterator) *Sitemap {
+	return &Sitemap{Count: count, URLs: urls}
+}
+
+// Handler serves the sitemap, the sitemap index, or the sitemap page of the request.
+func (s *Sitemap) Handler(ctx *Context) {
+	n, err := s.Count(ctx)
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	max := SitemapMaxURLs
+	pages := (n + max - 1) / max
+	page := ctx.Request.URL.Query().Get("page")
+	if page == "" {
+		if pages <= 1 {
+			ctx.SitemapURLs(s.URLs(ctx, 0, max))
+			return
+		}
+		sitemaps := make([]SitemapEntry, pages)
+		u, _ := url.Parse(requestURL(ctx))
+		for i := range sitemaps {
+			q := u.Query()
+			q.Set("page", strconv.Itoa(i+1))
+			u.RawQuery = q.Encode()
+			sitemaps[i].Loc = u.String()
+		}
+		ctx.SitemapIndex(sitemaps)
+		return
+	}
+	p, err := strconv.Atoi(page)
+	if err != nil || p < 1 || p > pages {
+		ctx.Fail((&NotFoundError{}).New("Sitemap page not found"))
+		return
+	}
+	ctx.SitemapURLs(s.URLs(ctx, (p-1)*max, max))
+}
+
+type sitemapURL struct {
+	XMLName    xml.Name `xml:"url"`
+	Loc        string   `xml:"loc"`
+	LastMod    string   `xml:"lastmod,omitempty"`
+	ChangeFreq string   `xml:"changefreq,omitempty"`
+	Priority   string   `xml:"priority,omitempty"`
+}
+
+type sitemapEntry struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+// SitemapURLs streams the sitemap of the URLs of next. It stops at SitemapMaxURLs, the extra URLs are logged.
+func (ctx *Context) SitemapURLs(next SitemapIterator) {
+	ctx.streamXML("Context.SitemapURLs", SitemapContentType, SitemapMaxAge, time.Time{}, func(enc *xml.Encoder, flush func()) error {
+		start := xml.StartElement{Name: xml.Name{Local: "urlset"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNS}}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for n := 1; ; n++ {
+			u, err := next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if n > SitemapMaxURLs {
+				log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context.SitemapURLs: more than", SitemapMaxURLs, "URLs, the sitemap is truncated")
+				break
+			}
+			e := sitemapURL{Loc: u.Loc, LastMod: w3cTime(u.LastMod), ChangeFreq: u.ChangeFreq}
+			if u.Priority > 0 {
+				e.Priority = strconv.FormatFloat(u.Priority, 'f', 1, 64)
+			}
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+			if ExportFlushRows > 0 && n%ExportFlushRows == 0 {
+				if ctx.ClientGone() == true {
+					return nil
+				}
+				flush()
+			}
+		}
+		return enc.EncodeToken(start.End())
+	})
+}
+
+// SitemapIndex responds with the sitemap index of sitemaps.
+func (ctx *Context) SitemapIndex(sitemaps []SitemapEntry) {
+	ctx.streamXML("Context.SitemapIndex", SitemapContentType, SitemapMaxAge, time.Time{}, func(enc *xml.Encoder, flush func()) error {
+		start := xml.StartElement{Name: xml.Name{Local: "sitemapindex"}, Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNS}}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, s := range sitemaps {
+			if err := enc.Encode(sitemapEntry{Loc: s.Loc, LastMod: w3cTime(s.LastMod)}); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	})
+}
+
+// w3cTime formats t in the W3C datetime format of the sitemaps, or "" for the zero time.
+func w3cTime(t time.Time) string {
+	if t.IsZero() == true {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// streamXML streams the XML document of the type contentType written by write, cached for maxAge unless the handler replaced
+// the default Cache-Control header. The nonzero modtime is the Last-Modified of the document, answering If-Modified-Since with 304.
+// As the status is already sent, an error of write ends the document early, and is logged.
+func (ctx *Context) streamXML(method, contentType string, maxAge time.Duration, modtime time.Time, write func(enc *xml.Encoder, flush func()) error) {
+	if ctx.written == true {
+		ctx.doubleWrite(method)
+		return
+	}
+	ctx.written = true
+	if ctx.ClientGone() == true {
+		ctx.logGone()
+		return
+	}
+
+	h := ctx.ResponseWriter.Header()
+	if cc := h.Get("Cache-Control"); cc == "" || cc == "no-cache" {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge/time.Second)))
+	}
+	if modtime.IsZero() == false {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+		if since, err := http.ParseTime(ctx.Request.Header.Get("If-Modified-Since")); err == nil && modtime.Truncate(time.Second).After(since) == false {
+			ctx.finalizeHeader(http.StatusNotModified, -1)
+			ctx.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	h.Set("Content-Type", contentType)
+	ctx.finalizeHeader(http.StatusOK, -1)
+	ctx.ResponseWriter.WriteHeader(http.StatusOK)
+
+	enc := xml.NewEncoder(ctx.ResponseWriter)
+	flush := func() {
+		enc.Flush()
+		ctx.writer.Flush()
+	}
+	_, err := io.WriteString(ctx.ResponseWriter, xml.Header)
+	if err == nil {
+		err = write(enc, flush)
+	}
+	if flushErr := enc.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil && ctx.ClientGone() == false {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln(method + ": " + err.Error())
+	}
+}
+
+// requestURL returns the absolute URL of the request, with the scheme of X-Forwarded-Proto when TrustProxy is set.
+func requestURL(ctx *Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.Request.Header.Get("X-Forwarded-Proto"); TrustProxy == true && proto != "" {
+		scheme = proto
+	}
+	u := url.URL{Scheme: scheme, Host: ctx.Request.Host, Path: ctx.Request.URL.Path, RawQuery: ctx.Request.URL.RawQuery}
+	return u.String()
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveXML(h RouterHandler, target string, header http.Header) *httptest.ResponseRecorder {
+	hs := NewHandlersStack()
+	hs.Use(h)
+	r, _ := http.NewRequest("GET", target, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	hs.ServeHTTP(w, r)
+	return w
+}
+
+func TestSitemap(t *testing.T) {
+	defer func(max int) { SitemapMaxURLs = max }(SitemapMaxURLs)
+	SitemapMaxURLs = 2
+	count := 3
+	sitemap := NewSitemap(func(ctx *Context) (int, error) { return count, nil }, func(ctx *Context, offset, limit int) SitemapIterator {
+		i := offset
+		return func() (*SitemapURL, error) {
+			if i == count || i == offset+limit {
+				return nil, io.EOF
+			}
+			i++
+			return &SitemapURL{Loc: "http://example.com/" + string(rune('a'+i-1)), LastMod: time.Date(2020, 1, i, 0, 0, 0, 0, time.UTC), Priority: 0.5}, nil
+		}
+	})
+
+	w := serveXML(sitemap.Handler, "http://example.com/sitemap.xml", nil)
+	if got := w.Header().Get("Content-Type"); got != SitemapContentType {
+		t.Errorf("Content-Type: got %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Cache-Control: got %q", got)
+	}
+	var index struct {
+		XMLName  xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &index); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	if len(index.Sitemaps) != 2 || index.Sitemaps[1].Loc != "http://example.com/sitemap.xml?page=2" {
+		t.Fatalf("index: got %+v", index.Sitemaps)
+	}
+
+	w = serveXML(sitemap.Handler, index.Sitemaps[1].Loc, nil)
+	want := xml.Header + `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>http://example.com/c</loc><lastmod>2020-01-03T00:00:00Z</lastmod><priority>0.5</priority></url></urlset>`
+	if w.Body.String() != want {
+		t.Errorf("page 2: want %q, got %q", want, w.Body.String())
+	}
+	if w = serveXML(sitemap.Handler, "http://example.com/sitemap.xml?page=3", nil); w.Code != http.StatusNotFound {
+		t.Errorf("page 3: want 404, got %d", w.Code)
+	}
+
+	count = 2
+	w = serveXML(sitemap.Handler, "http://example.com/sitemap.xml", nil)
+	if strings.Count(w.Body.String(), "<url>") != 2 {
+		t.Errorf("single sitemap: got %q", w.Body.String())
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	updated := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	feed := &Feed{Title: "Blog", Link: "http://example.com/", Description: "News & notes", Updated: updated}
+	items := []FeedItem{{Title: "Hello <world>", Link: "http://example.com/hello", Description: "First", Published: updated}}
+	next := func() FeedIterator {
+		i := 0
+		return func() (*FeedItem, error) {
+			if i == len(items) {
+				return nil, io.EOF
+			}
+			i++
+			return &items[i-1], nil
+		}
+	}
+
+	w := serveXML(func(ctx *Context) { ctx.RSS(feed, next()) }, "/feed.rss", nil)
+	if got := w.Header().Get("Content-Type"); got != RSSContentType {
+		t.Errorf("RSS Content-Type: got %q", got)
+	}
+	if got := w.Header().Get("Last-Modified"); got != "Sun, 01 Mar 2020 12:00:00 GMT" {
+		t.Errorf("RSS Last-Modified: got %q", got)
+	}
+	want := xml.Header + `<rss version="2.0"><channel><title>Blog</title><link>http://example.com/</link><description>News &amp; notes</description>` +
+		`<lastBuildDate>Sun, 01 Mar 2020 12:00:00 +0000</lastBuildDate><item><title>Hello &lt;world&gt;</title><link>http://example.com/hello</link>` +
+		`<description>First</description><guid isPermaLink="true">http://example.com/hello</guid><pubDate>Sun, 01 Mar 2020 12:00:00 +0000</pubDate></item></channel></rss>`
+	if w.Body.String() != want {
+		t.Errorf("RSS: want %q, got %q", want, w.Body.String())
+	}
+
+	w = serveXML(func(ctx *Context) { ctx.Atom(feed, next()) }, "/feed.atom", nil)
+	if got := w.Header().Get("Content-Type"); got != AtomContentType {
+		t.Errorf("Atom Content-Type: got %q", got)
+	}
+	var atom struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Entries []struct {
+			Title   string `xml:"title"`
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &atom); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	if atom.ID != feed.Link || len(atom.Entries) != 1 || atom.Entries[0].Title != "Hello <world>" || atom.Entries[0].Updated != "2020-03-01T12:00:00Z" {
+		t.Errorf("Atom: got %+v", atom)
+	}
+
+	w = serveXML(func(ctx *Context) { ctx.RSS(feed, next()) }, "/feed.rss", http.Header{"If-Modified-Since": {"Sun, 01 Mar 2020 12:00:00 GMT"}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-Modified-Since: want 304, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -81,7 +81,7 @@ func (rp *redisProvider) refresh(rs *redisStore) error {
 
 // Get read redis session by sid
 func (rp *redisProvider) Get(sid string) (*redisStore, error) {
-	var rs = &redisStore{}
+	var rs = &redisStore{SID: sid}
 	var val map[string]string
 	var err error
 	redisPool.Exec(func(c *redis.Client) {
@@ -100,11 +100,76 @@ func (rp *redisProvider) Destroy(sid string) error {
 	return err
 }
 
-// UpExpire refresh session expire
-func (rp *redisProvider) UpExpire(sid string) error {
+// UpExpire refresh session expire, and the one of the sessions of user if it isn't empty, in one round trip
+func (rp *redisProvider) UpExpire(sid, user string) error {
 	var err error
 	redisPool.Exec(func(c *redis.Client) {
-		err = c.Expire(sid, sessExpire).Err()
+		if user == "" {
+			err = c.Expire(sid, sessExpire).Err()
+			return
+		}
+		_, err = c.Pipelined(func(pipe *redis.Pipeline) error {
+			pipe.Expire(sid, sessExpire)
+			pipe.Expire(userSessionsKey(user), sessExpire)
+			return nil
+		})
+	})
+	return err
+}
+
+// userSessionsKey returns the key of the set of the session ids of user
+func userSessionsKey(user string) string {
+	return "sessions:" + user
+}
+
+// index adds sid to the sessions of user
+func (rp *redisProvider) index(user, sid string) error {
+	var err error
+	redisPool.Exec(func(c *redis.Client) {
+		err = c.SAdd(userSessionsKey(user), sid).Err()
+		if err != nil {
+			return
+		}
+		err = c.Expire(userSessionsKey(user), sessExpire).Err()
+	})
+	return err
+}
+
+// List read the sessions of user, forgetting the expired ones
+func (rp *redisProvider) List(user string) ([]*redisStore, error) {
+	var stores []*redisStore
+	var err error
+	redisPool.Exec(func(c *redis.Client) {
+		var sids []string
+		sids, err = c.SMembers(userSessionsKey(user)).Result()
+		if err != nil {
+			return
+		}
+		for _, sid := range sids {
+			var val map[string]string
+			val, err = c.HGetAll(sid).Result()
+			if err != nil {
+				return
+			}
+			if len(val) == 0 {
+				c.SRem(userSessionsKey(user), sid)
+				continue
+			}
+			stores = append(stores, &redisStore{SID: sid, Values: val})
+		}
+	})
+	return stores, err
+}
+
+// Revoke delete the session sid of user
+func (rp *redisProvider) Revoke(user, sid string) error {
+	var err error
+	redisPool.Exec(func(c *redis.Client) {
+		err = c.SRem(userSessionsKey(user), sid).Err()
+		if err != nil {
+			return
+		}
+		err = c.Del(sid).Err()
 	})
 	return err
 }
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrTasksFull is the error of Tasks.Go when the queue of the pool is full.
+	ErrTasksFull = errors.New("core: task queue full")
+	// ErrTasksStopped is the error of Tasks.Go once the pool is stopped.
+	ErrTasksStopped = errors.New("core: tasks stopped")
+)
+
+// permanentError is an error of a task not to retry.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps the error of a task not to retry, like an invalid recipient.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Task is a background task. Its context is canceled when its pool stops without draining in time.
+type Task func(ctx context.Context) error
+
+// DefaultTasks is the pool of Context.Defer, stopped with the server.
+var DefaultTasks = NewTasks("default", 4, 1000)
+
+func init() {
+	OnStop("tasks", 30*time.Second, DefaultTasks.Stop)
+}
+
+// Tasks is a pool of background workers running the tasks outside of the requests, like sending the emails.
+// The failed tasks are retried Retries times with an exponential backoff, unless their error is Permanent,
+// then logged and counted in Metrics under "tasks.<name>.failed". The pool should be stopped with the server, with OnStop:
+//
+//	images := core.NewTasks("images", 2, 100)
+//	core.OnStop("images", time.Minute, images.Stop)
+type Tasks struct {
+	Name    string
+	Retries int           // The retries of the failed tasks, default is 3.
+	Backoff time.Duration // The delay before the first retry, doubled on each next one, default is 1 second.
+
+	workers int
+	queue   chan queuedTask
+	start   sync.Once
+	mu      sync.RWMutex // Guards stopped, so that the queue isn't closed while sending to it.
+	stopped bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+type queuedTask struct {
+	name string
+	fn   Task
+}
+
+// NewTasks returns a new pool of workers, with a queue of maxQueue tasks. The workers start with the first task.
+func NewTasks(name string, workers, maxQueue int) *Tasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tasks{
+		Name:    name,
+		Retries: 3,
+		Backoff: time.Second,
+		workers: workers,
+		queue:   make(chan queuedTask, maxQueue),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Go queues the task fn named name. It doesn't block: it returns ErrTasksFull if the queue is full.
+func (t *Tasks) Go(name string, fn Task) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.stopped == true {
+		return ErrTasksStopped
+	}
+	t.start.Do(func() {
+		for i := 0; i < t.workers; i++ {
+			t.wg.Add(1)
+			go t.work()
+		}
+	})
+	select {
+	case t.queue <- queuedTask{name: name, fn: fn}:
+		Metrics.Add("tasks."+t.Name+".queued", 1)
+		return nil
+	default:
+		Metrics.Add("tasks."+t.Name+".rejected", 1)
+		return ErrTasksFull
+	}
+}
+
+// Stop stops accepting tasks, and waits for the queued and running ones until ctx is done.
+// Then the contexts of the tasks are canceled, and it returns the error of ctx.
+func (t *Tasks) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	if t.stopped == false {
+		t.stopped = true
+		close(t.queue)
+	}
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.cancel()
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		return ctx.Err()
+	}
+}
+
+// work runs the queued tasks until the queue is closed.
+func (t *Tasks) work() {
+	defer t.wg.Done()
+	for task := range t.queue {
+		t.run(task)
+	}
+}
+
+// run runs task, with its retries.
+func (t *Tasks) run(task queuedTask) {
+	backoff := t.Backoff
+	for attempt := 0; ; attempt++ {
+		err := t.call(task)
+		if err == nil {
+			return
+		}
+		var permanent *permanentError
+		if attempt >= t.Retries || errors.As(err, &permanent) == true || t.ctx.Err() != nil {
+			Metrics.Add("tasks."+t.Name+".failed", 1)
+			log.WithFields(log.Fields{"task": task.name, "attempts": attempt + 1}).Errorln("Tasks " + t.Name + ": " + err.Error())
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-t.ctx.Done():
+		}
+		backoff *= 2
+	}
+}
+
+// call calls task, recovering its panic as an error.
+func (t *Tasks) call(task queuedTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return task.fn(t.ctx)
+}
+
+// Defer runs fn named name in the background with DefaultTasks once the request is finished, so that it doesn't delay
+// the response, e.g. to send an email. The context of fn isn't the request one: fn outlives the request.
+// A full queue is logged.
+func (ctx *Context) Defer(name string, fn Task) {
+	ctx.deferTask(DefaultTasks, name, fn)
+}
+
+// deferTask queues fn named name to tasks once the request is finished.
+func (ctx *Context) deferTask(tasks *Tasks, name string, fn Task) {
+	path := ctx.Request.URL.Path
+	ctx.OnFinish(func() {
+		if err := tasks.Go(name, fn); err != nil {
+			log.WithFields(log.Fields{"path": path, "task": name}).Warnln("Context.Defer: " + err.Error())
+		}
+	})
+}
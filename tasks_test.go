@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTasks(t *testing.T) {
+	tasks := NewTasks("test", 2, 10)
+	tasks.Backoff = time.Millisecond
+	var calls, done int32
+	tasks.Go("flaky", func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("unavailable")
+		}
+		atomic.AddInt32(&done, 1)
+		return nil
+	})
+	tasks.Go("panic", func(ctx context.Context) error {
+		panic("boom")
+	})
+	for i := 0; i < 5; i++ {
+		tasks.Go("ok", func(ctx context.Context) error {
+			atomic.AddInt32(&done, 1)
+			return nil
+		})
+	}
+	if err := tasks.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || done != 6 {
+		t.Errorf("want 3 calls of the flaky task and 6 done, got %d and %d", calls, done)
+	}
+	if err := tasks.Go("late", func(ctx context.Context) error { return nil }); err != ErrTasksStopped {
+		t.Errorf("stopped: want ErrTasksStopped, got %v", err)
+	}
+
+	full := NewTasks("full", 1, 1)
+	block := make(chan struct{})
+	full.Go("block", func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	full.Go("queued", func(ctx context.Context) error { return nil })
+	if err := full.Go("rejected", func(ctx context.Context) error { return nil }); err != ErrTasksFull {
+		t.Errorf("full: want ErrTasksFull, got %v", err)
+	}
+	c, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := full.Stop(c); err != context.DeadlineExceeded {
+		t.Errorf("stop timeout: want DeadlineExceeded, got %v", err)
+	}
+	close(block)
+}
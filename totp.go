@@ -0,0 +1,215 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// totpEncoding is the base32 encoding of the TOTP secrets, without padding like the authenticator apps.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random TOTP secret, base32 encoded.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTP verifies the time-based one-time passwords of RFC 6238, the codes of the authenticator apps,
+// with the HMAC-SHA1 algorithm they all support. The failed attempts of an account are limited by Attempts,
+// and a code can't be replayed. A verification is kept in the session, see Require.
+// The zero Digits, Period and Attempts are their defaults, so a TOTP literal works too:
+//
+//	totp := core.NewTOTP("Example")
+//	core.Use(totp.Handler)
+//	core.Routers.POST("/2fa", func(ctx *core.Context) {
+//		if err := ctx.VerifyTOTP(user.ID, user.TOTPSecret, code); err != nil {
+//			ctx.Fail(err)
+//			return
+//		}
+//		ctx.Ok(nil)
+//	})
+//	core.Routers.GET("/account", totp.Require, account)
+type TOTP struct {
+	Issuer   string        // The name of the service in the authenticator apps.
+	Digits   int           // Default is 6.
+	Period   time.Duration // Default is 30 seconds.
+	Skew     int           // The accepted periods before and after the current one, for the clock drifts, NewTOTP sets 1.
+	Attempts *BruteForce   // The lockout of the accounts after failed codes, default locks out after 5 failures.
+
+	once sync.Once
+	mu   sync.Mutex
+	used *Cache // The last verified period of the accounts, so that a code is used once.
+}
+
+// NewTOTP returns a new TOTP of issuer, with the default parameters of the authenticator apps.
+func NewTOTP(issuer string) *TOTP {
+	return &TOTP{
+		Issuer:   issuer,
+		Digits:   6,
+		Period:   30 * time.Second,
+		Skew:     1,
+		Attempts: NewBruteForce("totp"),
+	}
+}
+
+// init sets the default Attempts of a TOTP literal, and allocates the used periods.
+func (t *TOTP) init() {
+	t.once.Do(func() {
+		if t.Attempts == nil {
+			t.Attempts = NewBruteForce("totp")
+		}
+		t.used = NewCache("", 0, 0)
+	})
+}
+
+// digits returns the number of digits of the codes.
+func (t *TOTP) digits() int {
+	if t.Digits <= 0 {
+		return 6
+	}
+	return t.Digits
+}
+
+// period returns the period of the codes, at least a second.
+func (t *TOTP) period() time.Duration {
+	if t.Period < time.Second {
+		return 30 * time.Second
+	}
+	return t.Period
+}
+
+// URI returns the otpauth URI of the secret of account, the content of the QR code scanned by the authenticator apps.
+func (t *TOTP) URI(account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", t.Issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(t.digits()))
+	q.Set("period", strconv.Itoa(int(t.period()/time.Second)))
+	label := url.PathEscape(t.Issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Code returns the code of secret at the time now.
+func (t *TOTP) Code(secret string, now time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	return t.code(key, now.Unix()/int64(t.period()/time.Second)), nil
+}
+
+// code returns the code of key for the period counter, see RFC 4226.
+func (t *TOTP) code(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	digits := t.digits()
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	s := strconv.FormatUint(uint64(value%mod), 10)
+	return strings.Repeat("0", digits-len(s)) + s
+}
+
+// Validate returns the period counter matching code for secret within Skew of now, or -1.
+// It doesn't limit the attempts nor prevent the replays, see Verify.
+func (t *TOTP) Validate(secret, code string, now time.Time) int64 {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(code) != t.digits() {
+		return -1
+	}
+	counter := now.Unix() / int64(t.period()/time.Second)
+	match := int64(-1)
+	for i := -t.Skew; i <= t.Skew; i++ {
+		if hmac.Equal([]byte(t.code(key, counter+int64(i))), []byte(code)) == true && match < 0 {
+			match = counter + int64(i)
+		}
+	}
+	return match
+}
+
+// Verify verifies code for the secret of account. The locked out accounts fail with 429 Too Many Requests
+// and a Retry-After header, the invalid or replayed codes with 401 Unauthorized.
+func (t *TOTP) Verify(ctx *Context, account, secret, code string) error {
+	t.init()
+	if wait, err := t.Attempts.Locked(account); err != nil {
+		return err
+	} else if wait > 0 {
+		err := (&BusinessError{}).New(0, "too many failed attempts")
+		err.HTTPCode = http.StatusTooManyRequests
+		err.Details = &ErrorDetails{RetryAfter: int(wait/time.Second) + 1}
+		return err
+	}
+	counter := t.Validate(secret, code, time.Now())
+	if counter >= 0 {
+		t.mu.Lock()
+		last, ok := t.used.Get(account)
+		if ok == true && last.(int64) >= counter {
+			counter = -1
+		} else {
+			t.used.SetTTL(account, counter, time.Duration(2*t.Skew+1)*t.period())
+		}
+		t.mu.Unlock()
+	}
+	if counter < 0 {
+		Metrics.Add("totp.rejected", 1)
+		if _, err := t.Attempts.Fail(ctx, account); err != nil {
+			log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("TOTP: " + err.Error())
+		}
+		return (&UnauthorizedError{}).New("invalid code")
+	}
+	return t.Attempts.Reset(ctx, account)
+}
+
+// Handler is the middleware enabling the ctx helper VerifyTOTP.
+func (t *TOTP) Handler(ctx *Context) {
+	ctx.Data["totp"] = t
+	ctx.Next()
+}
+
+// Require is the middleware of the routes requiring a session verified by VerifyTOTP.
+// The other requests fail with 401 Unauthorized.
+func (t *TOTP) Require(ctx *Context) {
+	if session := ctx.GetSession(); session == nil || session.Get("totpVerifiedAt") == "" {
+		ctx.Fail((&UnauthorizedError{}).New("two-factor authentication required"))
+		return
+	}
+	ctx.Next()
+}
+
+// VerifyTOTP verifies code for the secret of account with the TOTP of the request, see TOTP.Verify.
+// When verified, the session of the request, if any, is marked as verified for TOTP.Require.
+func (ctx *Context) VerifyTOTP(account, secret, code string) error {
+	t, _ := ctx.Data["totp"].(*TOTP)
+	if t == nil {
+		log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Warnln("Context: TOTP.Handler isn't used")
+		return (&ServerError{}).New("TOTP isn't enabled")
+	}
+	if err := t.Verify(ctx, account, secret, code); err != nil {
+		return err
+	}
+	if session := ctx.GetSession(); session != nil {
+		return session.Set("totpVerifiedAt", strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// The SHA1 secret of the test vectors of RFC 6238.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	totp := NewTOTP("Example")
+	totp.Digits = 8
+	if code, _ := totp.Code(secret, time.Unix(59, 0)); code != "94287082" {
+		t.Errorf("code at 59: want 94287082, got %s", code)
+	}
+	if code, _ := totp.Code(secret, time.Unix(1111111109, 0)); code != "07081804" {
+		t.Errorf("code at 1111111109: want 07081804, got %s", code)
+	}
+	totp.Digits = 6
+	if totp.Validate(secret, "287082", time.Unix(59+30, 0)) < 0 {
+		t.Error("drift: the previous code must validate")
+	}
+	if totp.Validate(secret, "287082", time.Unix(59+90, 0)) >= 0 {
+		t.Error("drift: a code beyond Skew must not validate")
+	}
+
+	uri := totp.URI("bob@example.com", secret)
+	if strings.HasPrefix(uri, "otpauth://totp/Example:bob@example.com?") == false || strings.Contains(uri, "secret="+secret) == false {
+		t.Errorf("URI: got %s", uri)
+	}
+	if generated, err := GenerateTOTPSecret(); err != nil || len(generated) != 32 {
+		t.Errorf("GenerateTOTPSecret: got %q, %v", generated, err)
+	}
+
+	totp.Attempts.Threshold = 2
+	engine := New()
+	engine.Use(totp.Handler)
+	engine.POST("/2fa/:code", func(ctx *Context) {
+		if err := ctx.VerifyTOTP("bob", secret, ctx.Param("code")); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Ok(nil)
+	})
+	code, _ := totp.Code(secret, time.Now())
+	if w := serveRouter(engine, "POST", "/2fa/"+code); w.Code != http.StatusOK {
+		t.Fatalf("valid code: want 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serveRouter(engine, "POST", "/2fa/"+code); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed code: want 401, got %d", w.Code)
+	}
+	serveRouter(engine, "POST", "/2fa/000000")
+	if w := serveRouter(engine, "POST", "/2fa/"+code); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("locked out: want 429 with Retry-After, got %d", w.Code)
+	}
+}
+
+func TestTOTPLiteral(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	totp := &TOTP{Issuer: "Example", Skew: 1}
+	if code, _ := totp.Code(secret, time.Unix(59, 0)); code != "287082" {
+		t.Errorf("code at 59: want the 6 digits of 30 seconds 287082, got %s", code)
+	}
+	engine := New()
+	engine.Use(totp.Handler)
+	engine.POST("/2fa/:code", func(ctx *Context) {
+		if err := ctx.VerifyTOTP("bob", secret, ctx.Param("code")); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Ok(nil)
+	})
+	code, _ := totp.Code(secret, time.Now())
+	if w := serveRouter(engine, "POST", "/2fa/"+code); w.Code != http.StatusOK {
+		t.Errorf("valid code: want 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := serveRouter(engine, "POST", "/2fa/"+code); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed code: want 401, got %d", w.Code)
+	}
+}
@@ -0,0 +1,180 @@
+package tus
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Info is the state of an upload.
+type Info struct {
+	ID       string            `json:"id"`
+	Size     int64             `json:"size"`   // The total size of the file, -1 until it is known with a deferred length.
+	Offset   int64             `json:"offset"` // The number of bytes received.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Expires  time.Time         `json:"expires"` // The zero time never expires.
+}
+
+// Complete tells if all the bytes of the upload were received.
+func (info *Info) Complete() bool {
+	return info.Size >= 0 && info.Offset == info.Size
+}
+
+// Store stores the uploads, e.g. on disk with DiskStore, or in an object storage with a multipart upload per file.
+// The methods return an error matching fs.ErrNotExist for the unknown uploads.
+type Store interface {
+	// Create creates the empty upload info.
+	Create(info *Info) error
+	// Info returns the current state of the upload id.
+	Info(id string) (*Info, error)
+	// Update saves the changes of the size and the expiration of an upload. The offset is only changed by Write.
+	Update(info *Info) error
+	// Write appends the content of r to the upload id, at offset, then saves the new offset.
+	// It returns the number of bytes written, saved even if it fails after writing some of them.
+	Write(id string, offset int64, r io.Reader) (int64, error)
+	// Open reads the content of the upload id.
+	Open(id string) (io.ReadCloser, error)
+	// Delete deletes the upload id and its content.
+	Delete(id string) error
+	// Expired returns the IDs of the uploads expired at now.
+	Expired(now time.Time) ([]string, error)
+}
+
+// DiskStore is the Store of the uploads in a directory: the content of an upload <id> is <id>.bin, and its info <id>.info.
+type DiskStore struct {
+	Dir string
+
+	mu sync.Mutex // Guards the info files.
+}
+
+// NewDiskStore returns a new DiskStore in dir, created if it doesn't exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{Dir: dir}, nil
+}
+
+func (s *DiskStore) path(id, ext string) string {
+	return filepath.Join(s.Dir, filepath.Base(id)+ext)
+}
+
+// Create creates the files of the upload.
+func (s *DiskStore) Create(info *Info) error {
+	f, err := os.OpenFile(s.path(info.ID, ".bin"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return s.Update(info)
+}
+
+// Info reads the info file of the upload.
+func (s *DiskStore) Info(id string) (*Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readInfo(id)
+}
+
+func (s *DiskStore) readInfo(id string) (*Info, error) {
+	b, err := ioutil.ReadFile(s.path(id, ".info"))
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{}
+	return info, json.Unmarshal(b, info)
+}
+
+// Update writes the info file of the upload.
+func (s *DiskStore) Update(info *Info) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeInfo(info)
+}
+
+func (s *DiskStore) writeInfo(info *Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	// The info is replaced atomically, not to be read half written.
+	tmp := s.path(info.ID, ".info.tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(info.ID, ".info"))
+}
+
+// Write appends r to the content file of the upload.
+func (s *DiskStore) Write(id string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.path(id, ".bin"), os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if n > 0 {
+		s.mu.Lock()
+		info, infoErr := s.readInfo(id)
+		if infoErr == nil {
+			info.Offset = offset + n
+			infoErr = s.writeInfo(info)
+		}
+		s.mu.Unlock()
+		if err == nil {
+			err = infoErr
+		}
+	}
+	return n, err
+}
+
+// Open opens the content file of the upload.
+func (s *DiskStore) Open(id string) (io.ReadCloser, error) {
+	if _, err := os.Stat(s.path(id, ".info")); err != nil {
+		return nil, err
+	}
+	return os.Open(s.path(id, ".bin"))
+}
+
+// Delete removes the files of the upload.
+func (s *DiskStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(id, ".info"))
+	if binErr := os.Remove(s.path(id, ".bin")); err == nil {
+		err = binErr
+	}
+	return err
+}
+
+// Expired lists the info files of the directory.
+func (s *DiskStore) Expired(now time.Time) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*.info"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, name := range names {
+		id := strings.TrimSuffix(filepath.Base(name), ".info")
+		info, err := s.Info(id)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return ids, err
+		}
+		if info.Expires.IsZero() == false && info.Expires.Before(now) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,416 @@
+// Package tus implements the tus resumable upload protocol 1.0.0 (https://tus.io) for package core, with the creation,
+// creation-defer-length, expiration and termination extensions: the clients upload large files in several PATCH requests,
+// and resume them after a network failure from the offset received by the server.
+//
+//	store, err := tus.NewDiskStore("/var/lib/myapp/uploads")
+//	uploads := tus.New(store)
+//	uploads.Completed = func(ctx *core.Context, upload *tus.Info) error {
+//		f, err := store.Open(upload.ID)
+//		...
+//	}
+//	uploads.Mount(core.Routers.Group("/files", auth), "/")
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HiLittleCat/core"
+)
+
+// Version is the version of the tus protocol.
+const Version = "1.0.0"
+
+const offsetContentType = "application/offset+octet-stream"
+
+// Uploads serves the resumable uploads of Store.
+// The uploads expire after Expiration without a PATCH request, Sweep deletes them.
+type Uploads struct {
+	Store      Store
+	MaxSize    int64         // The max size of the uploaded files, default is 1 GB.
+	Expiration time.Duration // The expiration of the uploads after their last request, default is a day. 0 never expires.
+
+	// Creating validates the upload info of a creation request, e.g. its metadata or the quota of the user.
+	// An error fails the creation.
+	Creating func(ctx *core.Context, upload *Info) error
+
+	// Blobs receives the completed uploads under their ID, with the media type of their filetype metadata, if it is valid.
+	// They are copied before Completed is called, and stay in Store until they expire.
+	Blobs core.BlobStore
+
+	// Completed processes the uploaded file, in the PATCH request completing the upload.
+	// An error fails the request, the upload stays complete.
+	Completed func(ctx *core.Context, upload *Info) error
+
+	prefix string
+	mu     sync.Mutex
+	locked map[string]bool // The uploads receiving a PATCH request.
+}
+
+// New returns new Uploads in store.
+func New(store Store) *Uploads {
+	return &Uploads{Store: store, MaxSize: 1 << 30, Expiration: 24 * time.Hour, locked: make(map[string]bool)}
+}
+
+// Mount serves the uploads under prefix in group: the creation at prefix, and the uploads at prefix/<id>.
+func (u *Uploads) Mount(group *core.RouterGroup, prefix string) {
+	prefix = strings.TrimSuffix("/"+strings.Trim(prefix, "/"), "/")
+	u.prefix = path.Join(group.BasePath(), prefix) + "/"
+	group.OPTIONS(prefix+"/", u.header, u.options)
+	group.POST(prefix+"/", u.header, u.create)
+	group.OPTIONS(prefix+"/:id", u.header, u.options)
+	group.HEAD(prefix+"/:id", u.header, u.head)
+	group.PATCH(prefix+"/:id", u.header, u.patch)
+	group.DELETE(prefix+"/:id", u.header, u.delete)
+}
+
+// header checks the protocol version of the request, then sets the one of the response.
+func (u *Uploads) header(ctx *core.Context) {
+	h := ctx.ResponseWriter.Header()
+	h.Set("Tus-Resumable", Version)
+	if ctx.Request.Method != "OPTIONS" && ctx.Request.Header.Get("Tus-Resumable") != Version {
+		h.Set("Tus-Version", Version)
+		ctx.FailWithStatus(http.StatusPreconditionFailed, (&core.ValidationError{}).New("unsupported tus version"))
+		return
+	}
+	ctx.Next()
+}
+
+// options describes the server capabilities.
+func (u *Uploads) options(ctx *core.Context) {
+	h := ctx.ResponseWriter.Header()
+	h.Set("Tus-Version", Version)
+	h.Set("Tus-Extension", "creation,creation-defer-length,expiration,termination")
+	if u.MaxSize > 0 {
+		h.Set("Tus-Max-Size", strconv.FormatInt(u.MaxSize, 10))
+	}
+	ctx.Empty(http.StatusNoContent)
+}
+
+// create creates an upload.
+func (u *Uploads) create(ctx *core.Context) {
+	info := &Info{Size: -1}
+	if ctx.Request.Header.Get("Upload-Defer-Length") != "1" {
+		size, err := u.size(ctx)
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		if size < 0 {
+			ctx.Fail((&core.ValidationError{}).New("Upload-Length or Upload-Defer-Length is required"))
+			return
+		}
+		info.Size = size
+	}
+	metadata, err := parseMetadata(ctx.Request.Header.Get("Upload-Metadata"))
+	if err != nil {
+		ctx.Fail(err)
+		return
+	}
+	info.Metadata = metadata
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		ctx.Fail(err)
+		return
+	}
+	info.ID = hex.EncodeToString(id)
+	u.touch(info)
+	if u.Creating != nil {
+		if err := u.Creating(ctx, info); err != nil {
+			ctx.Fail(err)
+			return
+		}
+	}
+	if err := u.Store.Create(info); err != nil {
+		ctx.Fail(err)
+		return
+	}
+	h := ctx.ResponseWriter.Header()
+	h.Set("Location", u.prefix+info.ID)
+	u.setExpires(ctx, info)
+	if info.Size == 0 {
+		if err := u.complete(ctx, info); err != nil {
+			ctx.Fail(err)
+			return
+		}
+	}
+	ctx.Empty(http.StatusCreated)
+}
+
+// size returns the Upload-Length of the request, or -1.
+func (u *Uploads) size(ctx *core.Context) (int64, error) {
+	s := ctx.Request.Header.Get("Upload-Length")
+	if s == "" {
+		return -1, nil
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || size < 0 {
+		return 0, (&core.ValidationError{}).New("invalid Upload-Length")
+	}
+	if u.MaxSize > 0 && size > u.MaxSize {
+		e := (&core.ValidationError{}).New("upload too large")
+		e.HTTPCode = http.StatusRequestEntityTooLarge
+		return 0, e
+	}
+	return size, nil
+}
+
+// head responds with the state of the upload.
+func (u *Uploads) head(ctx *core.Context) {
+	info, ok := u.info(ctx)
+	if ok == false {
+		return
+	}
+	h := ctx.ResponseWriter.Header()
+	h.Set("Cache-Control", "no-store")
+	h.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	if info.Size < 0 {
+		h.Set("Upload-Defer-Length", "1")
+	} else {
+		h.Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if len(info.Metadata) > 0 {
+		h.Set("Upload-Metadata", formatMetadata(info.Metadata))
+	}
+	u.setExpires(ctx, info)
+	ctx.Empty(http.StatusOK)
+}
+
+// patch appends the request body to the upload.
+func (u *Uploads) patch(ctx *core.Context) {
+	if ctx.Request.Header.Get("Content-Type") != offsetContentType {
+		ctx.FailWithStatus(http.StatusUnsupportedMediaType, (&core.ValidationError{}).New("Content-Type must be "+offsetContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.Request.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.Fail((&core.ValidationError{}).New("invalid Upload-Offset"))
+		return
+	}
+	id := ctx.Param("id")
+	if u.lock(id) == false {
+		ctx.FailWithStatus(http.StatusConflict, (&core.ValidationError{}).New("upload in progress"))
+		return
+	}
+	defer u.unlock(id)
+	info, ok := u.info(ctx)
+	if ok == false {
+		return
+	}
+	if offset != info.Offset {
+		ctx.FailWithStatus(http.StatusConflict, (&core.ValidationError{}).New("Upload-Offset mismatch, the upload is at "+strconv.FormatInt(info.Offset, 10)))
+		return
+	}
+	if info.Size < 0 {
+		size, err := u.size(ctx)
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		if size >= 0 && size < info.Offset {
+			ctx.Fail((&core.ValidationError{}).New("Upload-Length is less than Upload-Offset"))
+			return
+		}
+		info.Size = size
+	}
+	u.touch(info)
+	if err := u.Store.Update(info); err != nil {
+		ctx.Fail(err)
+		return
+	}
+
+	// The body is cut at the size of the upload, or at MaxSize while it is deferred.
+	var body io.Reader = ctx.Request.Body
+	if info.Size >= 0 {
+		body = io.LimitReader(body, info.Size-info.Offset)
+	} else if u.MaxSize > 0 {
+		body = io.LimitReader(body, u.MaxSize-info.Offset)
+	}
+	n, err := u.Store.Write(id, info.Offset, body)
+	info.Offset += n
+	if err != nil {
+		if ctx.ClientGone() == true {
+			return
+		}
+		ctx.Fail(err)
+		return
+	}
+	ctx.ResponseWriter.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	u.setExpires(ctx, info)
+	if info.Complete() == true && n > 0 {
+		if err := u.complete(ctx, info); err != nil {
+			ctx.Fail(err)
+			return
+		}
+	}
+	ctx.Empty(http.StatusNoContent)
+}
+
+// complete copies the completed upload to Blobs, then calls Completed.
+func (u *Uploads) complete(ctx *core.Context, info *Info) error {
+	if u.Blobs != nil {
+		r, err := u.Store.Open(info.ID)
+		if err != nil {
+			return err
+		}
+		err = u.Blobs.Put(ctx.Request.Context(), info.ID, r, fileType(info.Metadata["filetype"]))
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if u.Completed != nil {
+		return u.Completed(ctx, info)
+	}
+	return nil
+}
+
+// delete terminates the upload.
+func (u *Uploads) delete(ctx *core.Context) {
+	id := ctx.Param("id")
+	if u.lock(id) == false {
+		ctx.FailWithStatus(http.StatusConflict, (&core.ValidationError{}).New("upload in progress"))
+		return
+	}
+	defer u.unlock(id)
+	if _, ok := u.info(ctx); ok == false {
+		return
+	}
+	if err := u.Store.Delete(id); err != nil {
+		ctx.Fail(err)
+		return
+	}
+	ctx.Empty(http.StatusNoContent)
+}
+
+// info returns the upload of the request, or responds with 404 Not Found or 410 Gone.
+func (u *Uploads) info(ctx *core.Context) (*Info, bool) {
+	info, err := u.Store.Info(ctx.Param("id"))
+	if errors.Is(err, fs.ErrNotExist) {
+		ctx.Fail((&core.NotFoundError{}).New("upload not found"))
+		return nil, false
+	}
+	if err != nil {
+		ctx.Fail(err)
+		return nil, false
+	}
+	if info.Expires.IsZero() == false && info.Expires.Before(time.Now()) {
+		ctx.FailWithStatus(http.StatusGone, (&core.NotFoundError{}).New("upload expired"))
+		return nil, false
+	}
+	return info, true
+}
+
+// touch extends the expiration of the upload.
+func (u *Uploads) touch(info *Info) {
+	if u.Expiration > 0 {
+		info.Expires = time.Now().Add(u.Expiration)
+	}
+}
+
+func (u *Uploads) setExpires(ctx *core.Context, info *Info) {
+	if info.Expires.IsZero() == false && info.Complete() == false {
+		ctx.ResponseWriter.Header().Set("Upload-Expires", info.Expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// lock locks the upload id for a request, and tells if it wasn't already.
+func (u *Uploads) lock(id string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.locked == nil {
+		u.locked = make(map[string]bool)
+	}
+	if u.locked[id] == true {
+		return false
+	}
+	u.locked[id] = true
+	return true
+}
+
+func (u *Uploads) unlock(id string) {
+	u.mu.Lock()
+	delete(u.locked, id)
+	u.mu.Unlock()
+}
+
+// Sweep deletes the expired uploads, and returns their number. It should be called periodically.
+func (u *Uploads) Sweep() (int, error) {
+	ids, err := u.Store.Expired(time.Now())
+	n := 0
+	for _, id := range ids {
+		if u.lock(id) == false {
+			continue
+		}
+		if delErr := u.Store.Delete(id); delErr == nil {
+			n++
+		} else if err == nil {
+			err = delErr
+		}
+		u.unlock(id)
+	}
+	return n, err
+}
+
+// fileType returns the media type of the filetype metadata, without its params, or "" if it is invalid.
+// The type is sent by the client: core.ServeBlob serves the stored blobs as attachments unless their type is in core.InlineBlobTypes.
+func fileType(s string) string {
+	t, _, err := mime.ParseMediaType(s)
+	if err != nil || strings.Contains(t, "/") == false {
+		return ""
+	}
+	return t
+}
+
+// parseMetadata parses the Upload-Metadata header: comma-separated keys with base64 values.
+func parseMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, (&core.ValidationError{}).New("invalid Upload-Metadata")
+		}
+		value := ""
+		if len(fields) == 2 {
+			b, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, (&core.ValidationError{}).New("invalid Upload-Metadata")
+			}
+			value = string(b)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata, nil
+}
+
+// formatMetadata formats metadata as an Upload-Metadata header.
+func formatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k
+		if v := metadata[k]; v != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(v))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,123 @@
+package tus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HiLittleCat/core"
+)
+
+func TestUploads(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads := New(store)
+	uploads.Blobs, err = core.NewFSBlobStore(t.TempDir(), "/blobs/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var completed string
+	uploads.Completed = func(ctx *core.Context, upload *Info) error {
+		r, info, err := uploads.Blobs.Get(ctx.Request.Context(), upload.ID)
+		if err != nil {
+			return err
+		}
+		if info.ContentType != "text/plain" {
+			t.Errorf("blob: got content type %q", info.ContentType)
+		}
+		defer r.Close()
+		b, _ := ioutil.ReadAll(r)
+		completed = upload.Metadata["filename"] + ":" + string(b)
+		return nil
+	}
+	engine := core.New()
+	uploads.Mount(engine.Group("/api"), "files")
+	hs := core.NewHandlersStack()
+	hs.Use(engine.Handler())
+	serve := func(method, target string, header map[string]string, body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Tus-Resumable", Version)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+	patch := func(location, offset, body string) *httptest.ResponseRecorder {
+		return serve("PATCH", location, map[string]string{"Content-Type": offsetContentType, "Upload-Offset": offset}, body)
+	}
+
+	w := serve("POST", "/api/files/", map[string]string{"Upload-Length": "11", "Upload-Metadata": "filename aGVsbG8udHh0,filetype dGV4dC9wbGFpbg==,private"}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: want 201, got %d %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if strings.HasPrefix(location, "/api/files/") == false || w.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("create: got Location %q, Upload-Expires %q", location, w.Header().Get("Upload-Expires"))
+	}
+
+	if w = patch(location, "0", "hello "); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "6" {
+		t.Fatalf("patch: got %d, offset %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if w = patch(location, "3", "world"); w.Code != http.StatusConflict {
+		t.Errorf("wrong offset: want 409, got %d", w.Code)
+	}
+	w = serve("HEAD", location, nil, "")
+	if w.Header().Get("Upload-Offset") != "6" || w.Header().Get("Upload-Length") != "11" || w.Header().Get("Upload-Metadata") != "filename aGVsbG8udHh0,filetype dGV4dC9wbGFpbg==,private" {
+		t.Errorf("head: got %v", w.Header())
+	}
+	if w = patch(location, "6", "world!!"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+		t.Fatalf("patch: got %d, offset %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if completed != "hello.txt:hello world" {
+		t.Errorf("completed: got %q", completed)
+	}
+
+	if w = serve("PATCH", location, map[string]string{"Content-Type": offsetContentType, "Upload-Offset": "0", "Tus-Resumable": "0.2.0"}, ""); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("version: want 412, got %d", w.Code)
+	}
+	if w = serve("POST", "/api/files/", map[string]string{"Upload-Length": "2000000000"}, ""); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large: want 413, got %d", w.Code)
+	}
+	if w = serve("DELETE", location, nil, ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete: want 204, got %d", w.Code)
+	}
+	if w = serve("HEAD", location, nil, ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleted: want 404, got %d", w.Code)
+	}
+
+	uploads.Expiration = time.Millisecond
+	w = serve("POST", "/api/files/", map[string]string{"Upload-Defer-Length": "1"}, "")
+	location = w.Header().Get("Location")
+	time.Sleep(5 * time.Millisecond)
+	if w = patch(location, "0", "late"); w.Code != http.StatusGone {
+		t.Errorf("expired: want 410, got %d", w.Code)
+	}
+	if n, err := uploads.Sweep(); n != 1 || err != nil {
+		t.Errorf("sweep: want 1, got %d %v", n, err)
+	}
+	if _, err := store.Open(location[strings.LastIndex(location, "/")+1:]); err == nil {
+		t.Error("sweep: the upload is still there")
+	}
+}
+
+func TestFileType(t *testing.T) {
+	tests := map[string]string{
+		"text/plain":                 "text/plain",
+		"Text/HTML; charset=utf-8":   "text/html",
+		"text/html\r\nX-Injected: 1": "",
+		"image":                      "",
+		"":                           "",
+	}
+	for s, want := range tests {
+		if got := fileType(s); got != want {
+			t.Errorf("%q: want %q, got %q", s, want, got)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tx is a database transaction. *sql.Tx implements it.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// TxManager begins the transactions of a database driver.
+type TxManager interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// SQLTxManager is the TxManager of a database/sql database.
+type SQLTxManager struct {
+	DB   *sql.DB
+	Opts *sql.TxOptions
+}
+
+// Begin begins a transaction of the database.
+func (m *SQLTxManager) Begin(ctx context.Context) (Tx, error) {
+	return m.DB.BeginTx(ctx, m.Opts)
+}
+
+// Transaction returns the middleware running the next handlers in one transaction of m, returned by ctx.Tx.
+// The transaction is committed just before a 2xx response is written, so that a commit error still responds 500.
+// It is rolled back on the other responses, on panic, or when the next handlers don't respond.
+// The AfterCommit callbacks run once the transaction is committed and the response written.
+//
+//	core.Use(core.Transaction(&core.SQLTxManager{DB: db}))
+func Transaction(m TxManager) RouterHandler {
+	return func(ctx *Context) {
+		tx, err := m.Begin(ctx.Request.Context())
+		if err != nil {
+			ctx.Fail((&DBError{}).New("", err.Error()))
+			return
+		}
+		ctx.Data["tx"] = tx
+		w := &txWriter{ResponseWriter: ctx.ResponseWriter, ctx: ctx, tx: tx}
+		ctx.ResponseWriter = w
+		defer func() {
+			ctx.ResponseWriter = w.ResponseWriter
+			if err := recover(); err != nil {
+				w.end(false)
+				panic(err)
+			}
+			w.end(false)
+			if w.committed == true {
+				ctx.runAfterCommit()
+			}
+		}()
+		ctx.Next()
+	}
+}
+
+// Tx returns the transaction of the request, or nil outside of the Transaction middleware.
+func (ctx *Context) Tx() Tx {
+	tx, _ := ctx.Data["tx"].(Tx)
+	return tx
+}
+
+// AfterCommit registers fn to run once the transaction of the request is committed, e.g. to invalidate a cache or publish an event.
+// The callbacks are dropped if the transaction is rolled back. Outside of the Transaction middleware, fn runs at once.
+func (ctx *Context) AfterCommit(fn func()) {
+	if ctx.Tx() == nil {
+		fn()
+		return
+	}
+	callbacks, _ := ctx.Data["afterCommit"].([]func())
+	ctx.Data["afterCommit"] = append(callbacks, fn)
+}
+
+// runAfterCommit runs the AfterCommit callbacks in order. A panicking callback is logged and doesn't stop the next ones.
+func (ctx *Context) runAfterCommit() {
+	callbacks, _ := ctx.Data["afterCommit"].([]func())
+	delete(ctx.Data, "afterCommit")
+	for _, fn := range callbacks {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.WithFields(log.Fields{"path": ctx.Request.URL.Path}).Errorln("Context.AfterCommit:", err)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+// txWriter ends the transaction when the response header is written.
+type txWriter struct {
+	http.ResponseWriter
+	ctx       *Context
+	tx        Tx
+	ended     bool
+	committed bool
+	failed    bool // The commit failed, the response is replaced by a 500.
+}
+
+func (w *txWriter) WriteHeader(code int) {
+	if w.ended == true {
+		if w.failed == false {
+			w.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	if w.end(code >= 200 && code < 300) == false {
+		w.failed = true
+		w.writeCommitFailed()
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *txWriter) Write(p []byte) (int, error) {
+	if w.ended == false {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed == true {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered response to the client, if the underlying ResponseWriter is an http.Flusher.
+func (w *txWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok == true && w.ended == true && w.failed == false {
+		f.Flush()
+	}
+}
+
+// end commits or rolls back the transaction, once, and tells if the commit succeeded.
+func (w *txWriter) end(commit bool) bool {
+	if w.ended == true {
+		return true
+	}
+	w.ended = true
+	if commit == false {
+		if err := w.tx.Rollback(); err != nil {
+			log.WithFields(log.Fields{"path": w.ctx.Request.URL.Path}).Warnln(err.Error())
+		}
+		return true
+	}
+	if err := w.tx.Commit(); err != nil {
+		Metrics.Add("tx.commit_failed", 1)
+		log.WithFields(log.Fields{"path": w.ctx.Request.URL.Path}).Errorln(err.Error())
+		return false
+	}
+	w.committed = true
+	return true
+}
+
+// writeCommitFailed writes a 500 fail response instead of the response of the handlers.
+func (w *txWriter) writeCommitFailed() {
+	res := &ResFormat{Ok: false, Message: http.StatusText(http.StatusInternalServerError)}
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, _ := json.Marshal(w.ctx.envelope(res))
+	h := w.Header()
+	h.Del("Content-Disposition")
+	h.Del("Content-Encoding")
+	h.Set("Content-Type", "application/json")
+	w.ctx.finalizeHeader(http.StatusInternalServerError, int64(len(b)))
+	w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+	w.ResponseWriter.Write(b)
+}
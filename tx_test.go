@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testTx records how it ended.
+type testTx struct {
+	commitErr error
+	ended     string
+}
+
+func (tx *testTx) Commit() error {
+	tx.ended = "commit"
+	return tx.commitErr
+}
+
+func (tx *testTx) Rollback() error {
+	tx.ended = "rollback"
+	return nil
+}
+
+type testTxManager struct {
+	tx *testTx
+}
+
+func (m *testTxManager) Begin(ctx context.Context) (Tx, error) {
+	return m.tx, nil
+}
+
+func TestTransaction(t *testing.T) {
+	errCommit := errors.New("commit failed")
+	tests := []struct {
+		name      string
+		handler   RouterHandler
+		commitErr error
+		code      int
+		ended     string
+	}{
+		{"ok", func(c *Context) { c.Ok(c.Tx() != nil) }, nil, http.StatusOK, "commit"},
+		{"fail", func(c *Context) { c.Fail((&BusinessError{}).New(1, "no stock")) }, nil, http.StatusBadRequest, "rollback"},
+		{"panic", func(c *Context) { panic("boom") }, nil, http.StatusInternalServerError, "rollback"},
+		{"commit failed", func(c *Context) { c.Ok(nil) }, errCommit, http.StatusInternalServerError, "commit"},
+	}
+	for _, tt := range tests {
+		m := &testTxManager{tx: &testTx{commitErr: tt.commitErr}}
+		engine := New()
+		engine.POST("/orders", Transaction(m), tt.handler)
+		w := serveRouter(engine, "POST", "/orders")
+		if w.Code != tt.code {
+			t.Errorf("%s: want %d, got %d", tt.name, tt.code, w.Code)
+		}
+		if m.tx.ended != tt.ended {
+			t.Errorf("%s: want %s, got %q", tt.name, tt.ended, m.tx.ended)
+		}
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond RouterHandler
+		fired   []string
+	}{
+		{"committed", func(c *Context) { c.Ok(nil) }, []string{"cache", "event"}},
+		{"rolled back", func(c *Context) { c.Fail((&BusinessError{}).New(1, "no stock")) }, nil},
+	}
+	for _, tt := range tests {
+		var fired []string
+		engine := New()
+		engine.POST("/orders", Transaction(&testTxManager{tx: &testTx{}}), func(c *Context) {
+			c.AfterCommit(func() { fired = append(fired, "cache") })
+			c.AfterCommit(func() { panic("publish failed") })
+			c.AfterCommit(func() { fired = append(fired, "event") })
+			if len(fired) > 0 {
+				t.Errorf("%s: fired before the commit", tt.name)
+			}
+			tt.respond(c)
+		})
+		serveRouter(engine, "POST", "/orders")
+		if strings.Join(fired, ",") != strings.Join(tt.fired, ",") {
+			t.Errorf("%s: want %v, got %v", tt.name, tt.fired, fired)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// SessionInfo is a session of a user, listed by UserSessions for the "log out other devices" features.
+type SessionInfo struct {
+	ID        string    `json:"id"` // The handle of the session, not its session id which is a credential.
+	Current   bool      `json:"current"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// sessionHandle returns the public handle of the session sid.
+func sessionHandle(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
+
+// StartSession starts a new session of user, like SetSession, but each sign in of the user gets its own session,
+// with its device: the sessions of the user are listed by UserSessions and revoked by RevokeSession.
+//
+//	err := ctx.StartSession(user.ID, map[string]string{"Name": user.Name})
+func (ctx *Context) StartSession(user string, values map[string]string) error {
+	key, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	if values == nil {
+		values = map[string]string{}
+	}
+	values["User"] = user
+	values["UserAgent"] = ctx.Request.UserAgent()
+	values["IP"] = ctx.ClientIP()
+	values["CreatedAt"] = strconv.FormatInt(time.Now().Unix(), 10)
+	if err := ctx.SetSession(user+":"+key, values); err != nil {
+		return err
+	}
+	ctx.Data["Sid"] = values["Sid"]
+	return provider.index(user, values["Sid"])
+}
+
+// UserSessions returns the sessions of user started by StartSession, with the one of the request as Current.
+func (ctx *Context) UserSessions(user string) ([]SessionInfo, error) {
+	stores, err := provider.List(user)
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if session := ctx.GetSession(); session != nil {
+		current = session.SessionID()
+	}
+	sessions := make([]SessionInfo, 0, len(stores))
+	for _, store := range stores {
+		created, _ := strconv.ParseInt(store.Values["CreatedAt"], 10, 64)
+		sessions = append(sessions, SessionInfo{
+			ID:        sessionHandle(store.SID),
+			Current:   store.SID == current,
+			UserAgent: store.Values["UserAgent"],
+			IP:        store.Values["IP"],
+			CreatedAt: time.Unix(created, 0),
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs out the session id of user, the ID of its SessionInfo, like a lost device.
+// An unknown session fails with 404 Not Found.
+func (ctx *Context) RevokeSession(user, id string) error {
+	stores, err := provider.List(user)
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if sessionHandle(store.SID) == id {
+			return provider.Revoke(user, store.SID)
+		}
+	}
+	return (&NotFoundError{}).New("session not found")
+}
+
+// RevokeOtherSessions logs out the sessions of user but the one of the request.
+func (ctx *Context) RevokeOtherSessions(user string) error {
+	current := ""
+	if session := ctx.GetSession(); session != nil {
+		current = session.SessionID()
+	}
+	return revokeSessions(user, current)
+}
+
+// RevokeSessions logs out all the sessions of user, e.g. after a password reset.
+func RevokeSessions(user string) error {
+	return revokeSessions(user, "")
+}
+
+// revokeSessions revokes the sessions of user but the session keep.
+func revokeSessions(user, keep string) error {
+	stores, err := provider.List(user)
+	if err != nil {
+		return err
+	}
+	for _, store := range stores {
+		if store.SID == keep {
+			continue
+		}
+		if err := provider.Revoke(user, store.SID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HiLittleCat/conn"
+	redis "gopkg.in/redis.v5"
+)
+
+// TestUserSessions runs against the Redis server at REDIS_ADDR.
+func TestUserSessions(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR isn't set")
+	}
+	pool, err := conn.NewRedisPool(conn.RedisPoolOption{Size: 2, Host: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	redisPool, sessExpire, httpCookie = pool, time.Hour, http.Cookie{Name: "sid", Path: "/"}
+	user := "usersessions_test:" + time.Now().Format("150405.000")
+	defer RevokeSessions(user)
+
+	var sessions []SessionInfo
+	var revoked error
+	hs := NewHandlersStack()
+	hs.Use(session)
+	hs.Use(func(c *Context) {
+		switch c.Request.URL.Path {
+		case "/signin":
+			if err := c.StartSession(user, map[string]string{"Name": "Bob"}); err != nil {
+				t.Fatal(err)
+			}
+		case "/sessions":
+			sessions, err = c.UserSessions(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+		case "/revoke":
+			revoked = c.RevokeSession(user, c.Request.URL.Query().Get("id"))
+		case "/others":
+			revoked = c.RevokeOtherSessions(user)
+		}
+		c.ResponseWriter.WriteHeader(http.StatusNoContent)
+	})
+	serve := func(target, userAgent string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", target, nil)
+		r.Header.Set("User-Agent", userAgent)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		hs.ServeHTTP(w, r)
+		return w
+	}
+	signin := func(userAgent string) *http.Cookie {
+		cookies := serve("/signin", userAgent, nil).Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "sid" {
+			t.Fatalf("signin %s: want the session cookie, got %v", userAgent, cookies)
+		}
+		return cookies[0]
+	}
+
+	laptop := signin("laptop")
+	signin("phone")
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	client.Expire(userSessionsKey(user), time.Minute)
+	serve("/sessions", "laptop", laptop)
+	if ttl := client.TTL(userSessionsKey(user)).Val(); ttl <= time.Minute {
+		t.Errorf("the sessions of the user: want their expire refreshed, got %s", ttl)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("UserSessions: want 2 sessions, got %+v", sessions)
+	}
+	var phone string
+	for _, s := range sessions {
+		if s.Current != (s.UserAgent == "laptop") || s.CreatedAt.IsZero() == true {
+			t.Errorf("UserSessions: got %+v", s)
+		}
+		if s.UserAgent == "phone" {
+			phone = s.ID
+		}
+		if s.ID == laptop.Value {
+			t.Error("UserSessions: the ID must not be the session id")
+		}
+	}
+
+	serve("/revoke?id="+phone, "laptop", laptop)
+	if revoked != nil {
+		t.Errorf("RevokeSession: got %v", revoked)
+	}
+	serve("/revoke?id="+phone, "laptop", laptop)
+	if _, ok := revoked.(*NotFoundError); ok == false {
+		t.Errorf("RevokeSession unknown: want a NotFoundError, got %v", revoked)
+	}
+	serve("/sessions", "laptop", laptop)
+	if len(sessions) != 1 || sessions[0].UserAgent != "laptop" {
+		t.Errorf("UserSessions after revoke: got %+v", sessions)
+	}
+
+	signin("tablet")
+	serve("/others", "laptop", laptop)
+	serve("/sessions", "laptop", laptop)
+	if revoked != nil || len(sessions) != 1 || sessions[0].Current == false {
+		t.Errorf("RevokeOtherSessions: want the current session, got %+v %v", sessions, revoked)
+	}
+}
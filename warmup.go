@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// warmUps are the warm-up calls, run after the ready hooks and before the readiness flips to ready.
+var warmUps []lifecycleHook
+
+// WarmUp registers fn to run once the server is listening, before it is ready, e.g. to fill a cache or parse the templates,
+// so that the first requests behind the load balancer don't pay the cold start. The errors are logged and don't block the readiness.
+func WarmUp(name string, timeout time.Duration, fn LifecycleHook) {
+	warmUps = append(warmUps, lifecycleHook{name: name, timeout: timeout, fn: fn})
+}
+
+// WarmUpRoute registers a warm-up request of method and path served by the default handlers stack, with the X-Warm-Up header.
+// A response status of 500 or more is an error.
+//
+//	core.WarmUpRoute("GET", "/products?page=1", 5*time.Second)
+func WarmUpRoute(method, path string, timeout time.Duration) {
+	WarmUp(method+" "+path, timeout, func(ctx context.Context) error {
+		return warmUpRequest(ctx, defaultHandlersStack, method, path)
+	})
+}
+
+// warmUpRequest serves the request method path with hs, discarding the response.
+func warmUpRequest(ctx context.Context, hs *HandlersStack, method, path string) error {
+	r, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set("X-Warm-Up", "1")
+	r.RemoteAddr = "127.0.0.1:0"
+	w := &discardWriter{header: make(http.Header)}
+	hs.ServeHTTP(w, r)
+	if w.status >= http.StatusInternalServerError {
+		return fmt.Errorf("status %d", w.status)
+	}
+	return nil
+}
+
+// discardWriter is a ResponseWriter discarding the response, but its status.
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(p), nil
+}
+
+func (w *discardWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWarmUpRequest(t *testing.T) {
+	engine := New()
+	var warmed bool
+	engine.GET("/products", func(c *Context) {
+		warmed = c.Request.Header.Get("X-Warm-Up") == "1"
+		c.Ok(nil)
+	})
+	engine.GET("/broken", func(c *Context) { c.Fail((&ServerError{}).New("no cache")) })
+	hs := NewHandlersStack()
+	hs.Use(engine.Handler())
+
+	if err := warmUpRequest(context.Background(), hs, "GET", "/products"); err != nil || warmed == false {
+		t.Errorf("products: want warmed, got %t %v", warmed, err)
+	}
+	if err := warmUpRequest(context.Background(), hs, "GET", "/broken"); err == nil || err.Error() != "status 500" {
+		t.Errorf("broken: want status 500, got %v", err)
+	}
+	if err := warmUpRequest(context.Background(), hs, "GET", "/missing"); err != nil {
+		t.Errorf("missing: want no error for %d, got %v", http.StatusNotFound, err)
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeScheme is the SignatureScheme of the Stripe webhooks: the Stripe-Signature header "t=<timestamp>,v1=<signature>",
+// the signatures covering "<timestamp>.<body>".
+type StripeScheme struct{}
+
+// GitHubScheme is the SignatureScheme of the GitHub webhooks: the X-Hub-Signature-256 header "sha256=<signature>",
+// the signature covering the body. The replays are detected by their signature, the X-GitHub-Delivery header isn't signed.
+type GitHubScheme struct{}
+
+// SlackScheme is the SignatureScheme of the Slack requests: the X-Slack-Signature header "v0=<signature>"
+// and the X-Slack-Request-Timestamp header, the signature covering "v0:<timestamp>:<body>".
+type SlackScheme struct{}
+
+// NewStripeWebhook returns a new HMAC verifying the Stripe webhooks signed with the endpoint secret.
+//
+//	core.Routers.POST("/hooks/stripe", core.NewStripeWebhook(secret).Handler, onStripeEvent)
+func NewStripeWebhook(secret string) *HMAC {
+	m := NewHMAC([]byte(secret))
+	m.Scheme = StripeScheme{}
+	return m
+}
+
+// NewGitHubWebhook returns a new HMAC verifying the GitHub webhooks signed with secret.
+func NewGitHubWebhook(secret string) *HMAC {
+	m := NewHMAC([]byte(secret))
+	m.Scheme = GitHubScheme{}
+	return m
+}
+
+// NewSlackWebhook returns a new HMAC verifying the Slack requests signed with the signing secret.
+func NewSlackWebhook(secret string) *HMAC {
+	m := NewHMAC([]byte(secret))
+	m.Scheme = SlackScheme{}
+	return m
+}
+
+// Parse returns the signed payload and the v1 signatures of the Stripe-Signature header.
+func (StripeScheme) Parse(r *http.Request, body []byte) ([]byte, [][]byte, time.Time, error) {
+	var ts string
+	var signatures [][]byte
+	for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			if sig, err := hex.DecodeString(kv[1]); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	timestamp, err := parseUnix(ts)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if len(signatures) == 0 {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	return append([]byte(ts+"."), body...), signatures, timestamp, nil
+}
+
+// Parse returns the body and the signature of the X-Hub-Signature-256 header.
+func (GitHubScheme) Parse(r *http.Request, body []byte) ([]byte, [][]byte, time.Time, error) {
+	header := r.Header.Get("X-Hub-Signature-256")
+	if strings.HasPrefix(header, "sha256=") == false {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	sig, err := hex.DecodeString(header[len("sha256="):])
+	if err != nil {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	return body, [][]byte{sig}, time.Time{}, nil
+}
+
+// Parse returns the signed payload and the signature of the X-Slack-Signature header.
+func (SlackScheme) Parse(r *http.Request, body []byte) ([]byte, [][]byte, time.Time, error) {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	timestamp, err := parseUnix(ts)
+	if err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	header := r.Header.Get("X-Slack-Signature")
+	if strings.HasPrefix(header, "v0=") == false {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	sig, err := hex.DecodeString(header[len("v0="):])
+	if err != nil {
+		return nil, nil, time.Time{}, ErrSignature
+	}
+	return append([]byte("v0:"+ts+":"), body...), [][]byte{sig}, timestamp, nil
+}
+
+// parseUnix parses the unix time of a signature timestamp.
+func parseUnix(ts string) (time.Time, error) {
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("invalid signature timestamp")
+	}
+	return time.Unix(unix, 0), nil
+}